@@ -0,0 +1,254 @@
+package yangtree
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// binaryMagic is the header of the binary encoded data nodes.
+// The last byte is the version of the binary encoding.
+var binaryMagic = []byte{'y', 't', 'b', 1}
+
+// The binary encoding of a data node is composed of the header and the node encoding.
+//
+//   header   = magic name
+//   name     = uvarint(len) bytes      ; the schema name of the encoded node
+//   node     = branch | leaf | leaflist
+//   branch   = uvarint(count) *(uvarint(index) node)
+//   leaf     = 0x00 | 0x01 string      ; without or with a value
+//   leaflist = uvarint(count) *string
+//   string   = uvarint(len) bytes
+//
+// The index is the position of the child schema in the parent schema's Children.
+// So the binary encoded data must be decoded with the same schema tree.
+
+type binaryEncoder struct {
+	buf   []byte
+	index map[*SchemaNode]uint64
+}
+
+func (e *binaryEncoder) putUvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	e.buf = append(e.buf, b[:n]...)
+}
+
+func (e *binaryEncoder) putString(s string) {
+	e.putUvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// childIndex() returns the index of the child schema in the parent schema.
+func (e *binaryEncoder) childIndex(parent, child *SchemaNode) (uint64, error) {
+	if i, ok := e.index[child]; ok {
+		return i, nil
+	}
+	for i := range parent.Children {
+		e.index[parent.Children[i]] = uint64(i)
+	}
+	if i, ok := e.index[child]; ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("schema %s not found in %s", child.Name, parent.Name)
+}
+
+func (e *binaryEncoder) encode(node DataNode) error {
+	switch n := node.(type) {
+	case *DataBranch:
+		e.putUvarint(uint64(len(n.children)))
+		for _, child := range n.children {
+			i, err := e.childIndex(n.schema, child.Schema())
+			if err != nil {
+				return err
+			}
+			e.putUvarint(i)
+			if err := e.encode(child); err != nil {
+				return err
+			}
+		}
+	case *DataLeaf:
+		if n.Value() == nil {
+			e.buf = append(e.buf, 0)
+			return nil
+		}
+		e.buf = append(e.buf, 1)
+		e.putString(n.ValueString())
+	case *DataLeafList:
+		values := n.Values()
+		e.putUvarint(uint64(len(values)))
+		for i := range values {
+			e.putString(ValueToValueString(values[i]))
+		}
+	default:
+		return fmt.Errorf("binary encoding not supported for %s", node)
+	}
+	return nil
+}
+
+// MarshalBinary() returns the compact binary encoding of the data node.
+// The data node is encoded with the schema indices and the values of its descendants,
+// so it is much faster to save and restore than JSON. The metadata is not encoded.
+// The encoded data is only decodable by UnmarshalBinary() with the same schema tree.
+func MarshalBinary(node DataNode) ([]byte, error) {
+	if !IsValid(node) {
+		return nil, Errorf(EAppTagInvalidArg, "invalid data node")
+	}
+	e := &binaryEncoder{
+		buf:   make([]byte, 0, 1024),
+		index: map[*SchemaNode]uint64{},
+	}
+	e.buf = append(e.buf, binaryMagic...)
+	e.putString(node.Schema().Name)
+	if err := e.encode(node); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type binaryDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *binaryDecoder) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid binary data at %d", d.pos)
+	}
+	d.pos += n
+	return v, nil
+}
+
+// readCount() reads the number of the encoded items each of which takes at least size bytes.
+// It fails if the remaining binary data is too short for the number of the items.
+func (d *binaryDecoder) readCount(size uint64) (uint64, error) {
+	count, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if count > uint64(len(d.buf)-d.pos)/size {
+		return 0, fmt.Errorf("invalid count %d at %d", count, d.pos)
+	}
+	return count, nil
+}
+
+func (d *binaryDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, fmt.Errorf("unexpected end of binary data")
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *binaryDecoder) readString() (string, error) {
+	l, err := d.readUvarint()
+	if err != nil {
+		return "", err
+	}
+	if uint64(len(d.buf)-d.pos) < l {
+		return "", fmt.Errorf("unexpected end of binary data")
+	}
+	s := string(d.buf[d.pos : d.pos+int(l)])
+	d.pos += int(l)
+	return s, nil
+}
+
+func (d *binaryDecoder) decode(schema *SchemaNode) (DataNode, error) {
+	switch {
+	case schema.IsLeaf() || schema.IsLeafList() || schema.IsAnyXML():
+		if schema.Option.SingleLeafList && schema.ListAttr != nil {
+			count, err := d.readCount(1) // the length of each value string
+			if err != nil {
+				return nil, err
+			}
+			leaflist := &DataLeafList{schema: schema}
+			for ; count > 0; count-- {
+				s, err := d.readString()
+				if err != nil {
+					return nil, err
+				}
+				v, err := ValueStringToValue(schema, schema.Type, s)
+				if err != nil {
+					return nil, err
+				}
+				leaflist.value = append(leaflist.value, v)
+			}
+			return leaflist, nil
+		}
+		leaf := &DataLeaf{schema: schema}
+		hasValue, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if hasValue == 0 {
+			return leaf, nil
+		}
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if leaf.value, err = ValueStringToValue(schema, schema.Type, s); err != nil {
+			return nil, err
+		}
+		return leaf, nil
+	default:
+		count, err := d.readCount(2) // the schema index and the child node
+		if err != nil {
+			return nil, err
+		}
+		branch := &DataBranch{
+			schema:   schema,
+			children: make([]DataNode, 0, count),
+		}
+		for ; count > 0; count-- {
+			i, err := d.readUvarint()
+			if err != nil {
+				return nil, err
+			}
+			if i >= uint64(len(schema.Children)) {
+				return nil, fmt.Errorf("invalid schema index %d for %s", i, schema.Name)
+			}
+			cschema := schema.Children[i]
+			child, err := d.decode(cschema)
+			if err != nil {
+				return nil, err
+			}
+			var iopt InsertOption
+			if cschema.IsOrderedByUser() {
+				iopt = InsertToLast{}
+			}
+			if _, err := branch.insert(child, iopt); err != nil {
+				return nil, err
+			}
+		}
+		return branch, nil
+	}
+}
+
+// UnmarshalBinary() restores the data node from the binary encoding of MarshalBinary().
+// The schema must be the schema of the encoded data node.
+func UnmarshalBinary(schema *SchemaNode, b []byte) (DataNode, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("schema is nil")
+	}
+	if len(b) < len(binaryMagic) || string(b[:len(binaryMagic)]) != string(binaryMagic) {
+		return nil, fmt.Errorf("invalid binary data header")
+	}
+	d := &binaryDecoder{buf: b, pos: len(binaryMagic)}
+	name, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	if name != schema.Name {
+		return nil, fmt.Errorf("binary data of %s cannot be restored to %s", name, schema.Name)
+	}
+	node, err := d.decode(schema)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.buf) {
+		return nil, fmt.Errorf("unexpected trailing binary data at %d", d.pos)
+	}
+	return node, nil
+}
@@ -0,0 +1,112 @@
+package yangtree
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	jbytes, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, single := range []bool{false, true} {
+		schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{SingleLeafList: single})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := NewWithValueString(schema, string(jbytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"C", "A", "B"} {
+			err := SetValueString(root, "/sample/ordered-by-user-list[name="+name+"]", &EditOption{InsertOption: InsertToLast{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := MarshalBinary(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := UnmarshalBinary(schema, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(root, restored) {
+			j1, _ := MarshalJSON(root)
+			j2, _ := MarshalJSON(restored)
+			t.Errorf("restored tree (SingleLeafList=%v) differs:\n%s\n%s", single, j1, j2)
+		}
+		var names []string
+		for _, n := range restored.Get("sample").GetAll("ordered-by-user-list") {
+			names = append(names, n.GetValueString("name"))
+		}
+		if !reflect.DeepEqual(names, []string{"C", "A", "B"}) {
+			t.Errorf("ordered-by user list not restored in order: %v", names)
+		}
+
+		sample := root.Get("sample")
+		b, err = MarshalBinary(sample)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored, err = UnmarshalBinary(sample.Schema(), b); err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(sample, restored) {
+			t.Errorf("restored subtree (SingleLeafList=%v) differs", single)
+		}
+		if _, err := UnmarshalBinary(schema, b); err == nil {
+			t.Errorf("binary data of the subtree must not be restored to the root")
+		}
+		if _, err := UnmarshalBinary(sample.Schema(), b[:len(b)-1]); err == nil {
+			t.Errorf("truncated binary data must be failed")
+		}
+		huge := append(append([]byte{}, binaryMagic...), byte(len("sample")))
+		huge = append(huge, "sample"...)
+		huge = append(huge, 0xff, 0xff, 0xff, 0xff, 0x0f) // the number of the children: 2^32-1
+		if _, err := UnmarshalBinary(sample.Schema(), huge); err == nil {
+			t.Errorf("binary data having the number of the children over the data length must be failed")
+		}
+	}
+}
+
+func benchmarkSnapshot(b *testing.B, binary bool) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	jbytes, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, string(jbytes))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if binary {
+			data, err := MarshalBinary(root)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := UnmarshalBinary(schema, data); err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+		data, err := MarshalJSON(root)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := NewWithValueString(schema, string(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSnapshotJSON(b *testing.B)   { benchmarkSnapshot(b, false) }
+func BenchmarkSnapshotBinary(b *testing.B) { benchmarkSnapshot(b, true) }
@@ -382,18 +382,24 @@ var builtInYanglib2019 = []byte{
 0xdd, 0xde, 0xff, 0x6, 0x0, 0x0, 0xff, 0xff, 0x25, 0x47, 0xd8, 0x2c, 0x35, 0x40, 0x0, 0x0}
 
 var builtInYangtreeRoot = []byte{
-0x1f, 0x8b, 0x8, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xff, 0x94, 0x90, 0x31, 0x4f, 0xc3, 0x30, 0x10, 0x85, 0x77, 0xff, 
-0x8a, 0xa7, 0xec, 0x6d, 0x42, 0xa7, 0xa, 0x16, 0x98, 0x10, 0xb, 0x13, 0xb, 0xe3, 0x61, 0x5f, 0x12, 0xab, 0x8e, 0x2f, 
-0xb2, 0x2f, 0x88, 0x80, 0xfa, 0xdf, 0x51, 0xc, 0x14, 0x28, 0x1d, 0xe8, 0x7a, 0xa7, 0xef, 0xde, 0xf7, 0x6e, 0x10, 0x37, 
-0x5, 0xc6, 0x4c, 0xb1, 0xd3, 0xc4, 0x8c, 0x37, 0x3, 0x8c, 0x89, 0x5b, 0xff, 0x82, 0xea, 0x6b, 0x58, 0x5d, 0x19, 0x20, 
-0xd2, 0xc0, 0x79, 0x24, 0xcb, 0xa8, 0x7a, 0xd5, 0x31, 0x5f, 0xd6, 0x75, 0xe7, 0xb5, 0x9f, 0x9e, 0xd6, 0x56, 0x86, 0x3a, 
-0xb2, 0x4c, 0xa1, 0xfe, 0x5, 0x48, 0xea, 0x28, 0xfa, 0x57, 0x52, 0x2f, 0xf1, 0xe8, 0x96, 0x95, 0xa8, 0x64, 0x15, 0x55, 
-0xc1, 0xae, 0xe7, 0x81, 0x7c, 0x58, 0xce, 0x94, 0xa5, 0xe3, 0x6c, 0x93, 0x1f, 0x3f, 0xb0, 0x87, 0xde, 0x67, 0x3c, 0xde, 
-0xdc, 0xdf, 0xe2, 0x53, 0xd4, 0x71, 0xeb, 0x23, 0x67, 0x10, 0x5a, 0xda, 0x31, 0xb2, 0xed, 0x79, 0x20, 0x44, 0x71, 0xc, 
-0x3, 0x0, 0x2a, 0xd8, 0x31, 0x8f, 0xd0, 0xfe, 0xb0, 0x2c, 0xbd, 0xa4, 0x2d, 0xa3, 0x20, 0xe4, 0xd8, 0x1d, 0xea, 0xae, 
-0x4b, 0x62, 0xe2, 0x67, 0x9f, 0x4b, 0xdc, 0xa6, 0xd9, 0x34, 0xab, 0x66, 0xbb, 0xba, 0xd8, 0x56, 0xe5, 0x13, 0x47, 0x36, 
-0x77, 0xd1, 0x2b, 0x85, 0x45, 0x85, 0x43, 0x21, 0xf7, 0xc6, 0x0, 0x14, 0x67, 0x47, 0x4a, 0x48, 0x22, 0x7a, 0x8a, 0x2a, 
-0x1d, 0x8a, 0xa1, 0xcf, 0x98, 0x32, 0xbb, 0x45, 0x72, 0x91, 0x29, 0xc0, 0xcf, 0x6, 0xa7, 0x25, 0xff, 0x26, 0x59, 0x9, 
-0x81, 0xad, 0x4a, 0x3a, 0x2b, 0xee, 0x9b, 0xfa, 0x77, 0xe6, 0xde, 0xbc, 0x7, 0x0, 0x0, 0xff, 0xff, 0x36, 0xc1, 0x43, 
-0x6f, 0x20, 0x2, 0x0, 0x0}
+0x1f, 0x8b, 0x8, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xff, 0x95, 0x92, 0x3b, 0x53, 0xec, 0x30, 0xc, 0x85, 0x7b, 0x7e, 
+0x85, 0x26, 0xfd, 0x3e, 0xa0, 0x62, 0xa0, 0x81, 0xea, 0xe, 0x5, 0x54, 0xdb, 0x50, 0xa, 0x5b, 0xd9, 0x78, 0xd6, 0x96, 
+0x32, 0xb6, 0x2, 0x4, 0x86, 0xff, 0x8e, 0xa3, 0x2c, 0xbb, 0xbc, 0xa, 0x6e, 0x91, 0x64, 0xc6, 0xf2, 0xd1, 0x77, 0x74, 
+0x94, 0x24, 0x7e, 0x88, 0x4, 0x23, 0xf2, 0x56, 0x33, 0x11, 0xbc, 0x9e, 0x0, 0xf4, 0x99, 0xda, 0xf0, 0xc, 0xcd, 0xc7, 
+0x61, 0x73, 0x59, 0xf, 0x19, 0x13, 0x95, 0x1e, 0x1d, 0x41, 0xd3, 0xa9, 0xf6, 0xe5, 0x62, 0xb5, 0xda, 0x6, 0xed, 0x86, 
+0x87, 0xa5, 0x93, 0xb4, 0x62, 0x92, 0x21, 0xae, 0xbe, 0x8, 0x42, 0xea, 0x25, 0x2b, 0x4, 0xd2, 0x76, 0x31, 0x15, 0x16, 
+0x89, 0x14, 0x3d, 0x2a, 0x1a, 0xe3, 0x48, 0x49, 0xde, 0xae, 0xbf, 0xd5, 0x47, 0xf2, 0x16, 0x39, 0xbc, 0xa0, 0x6, 0xe1, 
+0x6f, 0x78, 0x27, 0xac, 0xe8, 0x14, 0x1a, 0x23, 0x5d, 0x8d, 0x9, 0x43, 0x9c, 0xc8, 0x56, 0xf4, 0x54, 0x5c, 0xe, 0xfd, 
+0x2c, 0xdb, 0x74, 0xa1, 0xc0, 0xfd, 0xf5, 0xdd, 0x3f, 0x48, 0xf3, 0x6c, 0xbe, 0x72, 0x98, 0xa, 0x20, 0xb4, 0xb8, 0x23, 
+0x28, 0xae, 0xa3, 0x84, 0xc0, 0xe2, 0x9, 0xcc, 0x88, 0xa, 0xec, 0x88, 0x7a, 0xd0, 0xee, 0x50, 0xb4, 0x28, 0xa4, 0xb5, 
+0xa3, 0x28, 0xe8, 0xc9, 0x1f, 0x12, 0x5a, 0x1a, 0x31, 0xd3, 0x63, 0x28, 0x86, 0x3b, 0x5b, 0x9f, 0xad, 0x17, 0xeb, 0xf3, 
+0xc5, 0xe9, 0x79, 0xb3, 0x1f, 0xec, 0x8b, 0x9b, 0x1b, 0xe, 0x8a, 0x71, 0xb2, 0x42, 0x71, 0x3f, 0x67, 0x7d, 0x25, 0x7f, 
+0x81, 0xcc, 0xa2, 0xf3, 0xa4, 0xb5, 0x18, 0xda, 0x50, 0x19, 0xb3, 0x5e, 0xc7, 0xbe, 0x1a, 0xd1, 0x1c, 0x78, 0x7b, 0xf9, 
+0xb3, 0xa1, 0x8d, 0xf7, 0x49, 0x6c, 0xc1, 0x4, 0x2e, 0xe6, 0xb5, 0xc6, 0x4b, 0xb5, 0xe8, 0x41, 0x43, 0x22, 0x78, 0xea, 
+0x88, 0xa7, 0x63, 0x6b, 0x2, 0x1f, 0xa2, 0xca, 0xb1, 0x2d, 0x58, 0x0, 0x4f, 0x58, 0x20, 0x62, 0xd1, 0x83, 0x87, 0x25, 
+0xdc, 0xd4, 0x9d, 0x15, 0x10, 0x8e, 0x23, 0xc, 0xfd, 0xd4, 0xd0, 0xef, 0xf5, 0x61, 0xce, 0x63, 0x93, 0xd1, 0xed, 0x6e, 
+0xa7, 0xeb, 0xe3, 0x66, 0xa2, 0xc8, 0x6c, 0x6c, 0x9f, 0xd6, 0xe1, 0x47, 0xaa, 0x3d, 0x88, 0xf1, 0x21, 0xd6, 0x96, 0xc7, 
+0xb9, 0x91, 0x47, 0x63, 0x67, 0x11, 0xfd, 0x2d, 0x2d, 0x1b, 0xce, 0x8c, 0xd5, 0xef, 0x50, 0xaa, 0xd5, 0xba, 0x9c, 0xa9, 
+0xad, 0x9, 0x3e, 0x6f, 0xee, 0xf7, 0xe5, 0xfc, 0x24, 0x39, 0x89, 0x91, 0x9c, 0x4a, 0xfe, 0x2f, 0xdc, 0x51, 0xf5, 0x67, 
+0xe6, 0xdb, 0xc9, 0x3b, 0xab, 0xdf, 0xf6, 0xcc, 0x4b, 0x3, 0x0, 0x0}
 
@@ -2,8 +2,11 @@ package yangtree
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/openconfig/goyang/pkg/yang"
@@ -26,7 +29,7 @@ func (f ConfigOnly) String() string { return "config-only" }
 func (f StateOnly) String() string  { return "state-only" }
 func (f HasState) String() string   { return "has-state" }
 
-// EditOperation [EditMerge, EditCreate, EditReplace, EditDelete, EditRemove] for yangtree
+// EditOperation [EditMerge, EditCreate, EditReplace, EditDelete, EditRemove, EditNone, EditUpdate] for yangtree
 type EditOp int
 
 const (
@@ -35,6 +38,8 @@ const (
 	EditReplace               // similar to NETCONF edit-config: replace operation
 	EditDelete                // similar to NETCONF edit-config: delete operation
 	EditRemove                // similar to NETCONF edit-config: remove operation
+	EditNone                  // similar to NETCONF edit-config: none default-operation. It returns data-missing error if the node doesn't exist.
+	EditUpdate                // merge operation only for the existent node. It returns data-missing error if it doesn't exist.
 )
 
 func (op EditOp) String() string {
@@ -49,6 +54,10 @@ func (op EditOp) String() string {
 		return "delete"
 	case EditRemove:
 		return "remove"
+	case EditNone:
+		return "none"
+	case EditUpdate:
+		return "update"
 	default:
 		return "unknown"
 	}
@@ -62,6 +71,7 @@ type EditOption struct {
 	InsertOption                                               // Insert option for ordered-by yang option
 	Callback        func(op EditOp, old, new []DataNode) error // Callback is invoked upon the node changes.
 	FailureRecovery bool
+	RejectStateEdit bool // The edits targeting the state (config false) nodes are rejected if it is set.
 }
 
 func (edit *EditOption) String() string {
@@ -95,6 +105,13 @@ func (edit *EditOption) GetFailureRecovery() bool {
 	return edit.FailureRecovery
 }
 
+func (edit *EditOption) GetRejectStateEdit() bool {
+	if edit == nil {
+		return false
+	}
+	return edit.RejectStateEdit
+}
+
 func (edit *EditOption) GetCallback() func(EditOp, []DataNode, []DataNode) error {
 	if edit == nil {
 		return nil
@@ -186,6 +203,22 @@ func indexFirst(parent *DataBranch, id *string) int {
 	return i
 }
 
+// indexOrderedByUser() returns the index of the ordered-by user child having the id.
+// It returns the first index of the children having the same schema if not found
+// because the ordered-by user nodes are not sorted by their ids.
+func indexOrderedByUser(parent *DataBranch, schema *SchemaNode, id *string) int {
+	i, max := indexRangeBySchema(parent, schema)
+	if i >= max {
+		return indexFirst(parent, id)
+	}
+	for j := i; j < max; j++ {
+		if parent.children[j].ID() == *id {
+			return j
+		}
+	}
+	return i
+}
+
 // indexRangeBySchema() returns the index of a child related to the node id
 func indexRangeBySchema(parent *DataBranch, target *SchemaNode) (i, max int) {
 	i = sort.Search(len(parent.children),
@@ -205,6 +238,19 @@ func indexRangeBySchema(parent *DataBranch, target *SchemaNode) (i, max int) {
 	return
 }
 
+// nextInsertOption() returns the insert option for the next ordered-by user node
+// inserted together with the inserted node so that the nodes are kept in the insertion order.
+func nextInsertOption(iopt InsertOption, inserted DataNode) InsertOption {
+	if !inserted.Schema().IsOrderedByUser() {
+		return iopt
+	}
+	switch iopt.(type) {
+	case nil, InsertToLast:
+		return iopt
+	}
+	return InsertToAfter{Key: strings.TrimPrefix(inserted.ID(), inserted.Name())}
+}
+
 // insert() insert a child node to the branch node according to the operation and insert option.
 // It returns a data node that becomes replaced.
 func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, error) {
@@ -214,7 +260,7 @@ func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, e
 		}
 		// allow to move the child to another node.
 		// return fmt.Errorf("child node %s is already inserted to %s", child, child.Parent())
-		child.Remove()
+		detach(child)
 	}
 	schema := child.Schema()
 	if !branch.schema.IsAnyData() && !branch.schema.ContainAny {
@@ -229,6 +275,9 @@ func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, e
 
 	id := child.ID()
 	i := indexFirst(branch, &id)
+	if orderedByUser {
+		i = indexOrderedByUser(branch, schema, &id)
+	}
 	if !duplicatable {
 		// find and replace the node if it is not a duplicatable node.
 		if i < len(branch.children) && id == branch.children[i].ID() {
@@ -236,6 +285,8 @@ func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, e
 			resetParent(branch.children[i])
 			branch.children[i] = child
 			setParent(child, branch, &id)
+			updateModifyTime(child)
+			markDirty(child, true)
 			return old, nil
 		}
 	}
@@ -270,6 +321,9 @@ func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, e
 		target := child.Name() + o.Key
 		i = sort.Search(len(branch.children),
 			func(j int) bool { return target <= branch.children[j].ID() })
+		if orderedByUser {
+			i = indexOrderedByUser(branch, schema, &target)
+		}
 	case InsertToAfter:
 		if schema.IsDuplicatableList() {
 			return nil, Errorf(ETagOperationNotSupported,
@@ -278,6 +332,9 @@ func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, e
 		target := child.Name() + o.Key
 		i = sort.Search(len(branch.children),
 			func(j int) bool { return target <= branch.children[j].ID() })
+		if orderedByUser {
+			i = indexOrderedByUser(branch, schema, &target)
+		}
 		if i < len(branch.children) {
 			i++
 		}
@@ -286,9 +343,176 @@ func (branch *DataBranch) insert(child DataNode, iopt InsertOption) (DataNode, e
 	copy(branch.children[i+1:], branch.children[i:])
 	branch.children[i] = child
 	setParent(child, branch, &id)
+	updateModifyTime(child)
+	markDirty(child, true)
 	return nil, nil
 }
 
+// updateModifyTime() stamps the modified metadata (yangtree:modified) to the changed data node
+// and its ancestors if YANGTreeOption.TrackModifyTime is set.
+func updateModifyTime(node DataNode) {
+	schema := node.Schema()
+	if schema == nil || schema.Option == nil || !schema.Option.TrackModifyTime {
+		return
+	}
+	if schema.Extension == nil {
+		return
+	}
+	if _, ok := schema.Node.(*yang.Statement); ok {
+		return // metadata and extension nodes are not tracked.
+	}
+	mschema := schema.MetadataSchema["yangtree:modified"]
+	if mschema == nil {
+		return
+	}
+	ts := time.Now().UTC().Format(time.RFC3339Nano)
+	for n := node; n != nil; n = n.Parent() {
+		modified, err := NewWithValueString(mschema, ts)
+		if err != nil {
+			return
+		}
+		switch node := n.(type) {
+		case *DataBranch:
+			if node.metadata == nil {
+				node.metadata = map[string]DataNode{}
+			}
+			node.metadata["yangtree:modified"] = modified
+		case *DataLeaf:
+			if node.metadata == nil {
+				node.metadata = map[string]DataNode{}
+			}
+			node.metadata["yangtree:modified"] = modified
+		case *DataLeafList:
+			if node.metadata == nil {
+				node.metadata = map[string]DataNode{}
+			}
+			node.metadata["yangtree:modified"] = modified
+		}
+	}
+}
+
+// markDirty() sets the dirty flags of the changed data node and its ancestors
+// if YANGTreeOption.TrackDirty is set. The descendants of the changed data node
+// are also marked if subtree is set (e.g. a newly inserted data node).
+func markDirty(node DataNode, subtree bool) {
+	schema := node.Schema()
+	if schema == nil || schema.Option == nil || !schema.Option.TrackDirty {
+		return
+	}
+	if _, ok := schema.Node.(*yang.Statement); ok {
+		return // metadata and extension nodes are not tracked.
+	}
+	if subtree {
+		setDirty(node, true, true)
+	}
+	for n := node; n != nil; n = n.Parent() {
+		setDirty(n, true, false)
+	}
+}
+
+// markRemoved() records the child of the schema and the id removed from the branch and marks
+// the branch dirty if YANGTreeOption.TrackDirty is set. The removed children are encoded
+// to null by MarshalJSON() with DirtyOnly until MarkClean() is called.
+func markRemoved(branch *DataBranch, schema *SchemaNode, id string) {
+	if schema == nil || schema.Option == nil || !schema.Option.TrackDirty {
+		return
+	}
+	if _, ok := schema.Node.(*yang.Statement); ok {
+		return // metadata and extension nodes are not tracked.
+	}
+	markDirty(branch, false)
+	for i := range branch.removed {
+		if branch.removed[i].ID() == id {
+			return
+		}
+	}
+	removed, err := NewWithID(schema, id)
+	if err != nil {
+		return
+	}
+	setDirty(removed, true, false)
+	branch.removed = append(branch.removed, removed)
+}
+
+// isRemoved() returns true if the node is the removed child recorded in the branch.
+func (branch *DataBranch) isRemoved(node DataNode) bool {
+	for i := range branch.removed {
+		if branch.removed[i] == node {
+			return true
+		}
+	}
+	return false
+}
+
+// childrenWithRemoved() returns the children of the branch including the removed children
+// not re-inserted to the branch.
+func (branch *DataBranch) childrenWithRemoved(children []DataNode) []DataNode {
+	var removed []DataNode
+	for i := range branch.removed {
+		if branch.Get(branch.removed[i].ID()) == nil {
+			removed = append(removed, branch.removed[i])
+		}
+	}
+	if len(removed) == 0 {
+		return children
+	}
+	sortByID(removed)
+	return mergeByID(children, removed)
+}
+
+// setDirty() sets or clears the dirty flag of the data node (and its descendants if recursive is set).
+// The removed children recorded in the branch are cleared together with the dirty flag.
+func setDirty(node DataNode, dirty, recursive bool) {
+	switch n := node.(type) {
+	case *DataBranch:
+		n.dirty = dirty
+		if !dirty {
+			n.removed = nil
+		}
+		if recursive {
+			for i := range n.children {
+				setDirty(n.children[i], dirty, recursive)
+			}
+		}
+	case *DataLeaf:
+		n.dirty = dirty
+	case *DataLeafList:
+		n.dirty = dirty
+	case *DataNodeGroup:
+		for i := range n.Nodes {
+			setDirty(n.Nodes[i], dirty, recursive)
+		}
+	}
+}
+
+// IsDirty() returns true if the data node or its descendants have been changed
+// since the last MarkClean(). It is only available if YANGTreeOption.TrackDirty is set.
+func IsDirty(node DataNode) bool {
+	switch n := node.(type) {
+	case *DataBranch:
+		return n.dirty
+	case *DataLeaf:
+		return n.dirty
+	case *DataLeafList:
+		return n.dirty
+	case *DataNodeGroup:
+		for i := range n.Nodes {
+			if IsDirty(n.Nodes[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarkClean() clears the dirty flags of the data node and its descendants.
+func MarkClean(root DataNode) {
+	if !IsValid(root) {
+		return
+	}
+	setDirty(root, false, true)
+}
+
 // NewCollector() creates a fake node that can be used to collect all kindes of data nodes.
 // Any of data nodes can be contained to the collector data node.
 func NewCollector() DataNode {
@@ -340,6 +564,35 @@ func NewWithValueString(schema *SchemaNode, value ...string) (DataNode, error) {
 	return node, err
 }
 
+// NewValidated() creates a new DataNode like NewWithValueString() and then validates it.
+// The new DataNode is returned only if the when, must, mandatory, leafref and
+// instance-identifier statements are satisfied within the new DataNode.
+func NewValidated(schema *SchemaNode, value ...string) (DataNode, error) {
+	node, err := NewWithValueString(schema, value...)
+	if err != nil {
+		return nil, err
+	}
+	if err := validationError(Validate(node)); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// validationError() combines the errors returned by Validate() to an error.
+func validationError(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return Error(ETagInvalidValue, errs[0])
+	}
+	msg := make([]string, 0, len(errs))
+	for i := range errs {
+		msg = append(msg, errs[i].Error())
+	}
+	return Errorf(ETagInvalidValue, "%s", strings.Join(msg, "; "))
+}
+
 // NewWithID() creates a new DataNode using id (NODE_NAME or NODE_NAME[KEY=VALUE])
 func NewWithID(schema *SchemaNode, id string) (DataNode, error) {
 	if schema == nil {
@@ -371,11 +624,9 @@ func newDataNode(schema *SchemaNode) (DataNode, error) {
 	var newdata DataNode
 	soption := schema.Option
 	switch {
-	case schema.IsLeaf() || schema.IsLeafList(): // leaf, leaf-list
+	case schema.IsLeaf() || schema.IsLeafList() || schema.IsAnyXML(): // leaf, leaf-list, anyxml
 		if soption.SingleLeafList && schema.ListAttr != nil {
-			leaflist := &DataLeafList{
-				schema: schema,
-			}
+			leaflist := allocLeafList(schema)
 			if schema.Default != "" && soption.CreatedWithDefault {
 				if err := leaflist.SetValueString(schema.Default); err != nil {
 					return nil, err
@@ -383,9 +634,7 @@ func newDataNode(schema *SchemaNode) (DataNode, error) {
 			}
 			newdata = leaflist
 		} else {
-			leaf := &DataLeaf{
-				schema: schema,
-			}
+			leaf := allocLeaf(schema)
 			if schema.Default != "" && soption.CreatedWithDefault {
 				if err := leaf.SetValueString(schema.Default); err != nil {
 					return nil, err
@@ -394,22 +643,10 @@ func newDataNode(schema *SchemaNode) (DataNode, error) {
 			newdata = leaf
 		}
 	default: // list, container, anydata
-		branch := &DataBranch{
-			schema:   schema,
-			children: []DataNode{},
-		}
+		branch := allocBranch(schema)
 		if soption.CreatedWithDefault {
-			for _, s := range schema.Children {
-				if !s.IsDir() && s.Default != "" {
-					c, err := NewWithValueString(s)
-					if err != nil {
-						return nil, err
-					}
-					_, err = branch.insert(c, nil)
-					if err != nil {
-						return nil, err
-					}
-				}
+			if err := branch.setDefaults(); err != nil {
+				return nil, err
 			}
 		}
 		newdata = branch
@@ -424,14 +661,25 @@ func mergeChildren(dest DataNode, mergedChildren []DataNode, edit *EditOption) (
 	var before []DataNode
 	var after []DataNode
 	d := dest.(*DataBranch)
+	var iopt InsertOption
+	var last *SchemaNode
 	for i := range mergedChildren {
 		schema := mergedChildren[i].Schema()
+		if schema != last {
+			// the insert option is only applied to the children of the same schema.
+			last = schema
+			iopt = edit.GetInsertOption()
+		}
+		if iopt == nil && schema.IsOrderedByUser() {
+			iopt = InsertToLast{}
+		}
 		if schema.IsDuplicatable() {
 			n = Clone(mergedChildren[i])
-			_, err = d.insert(n, edit.GetInsertOption())
+			_, err = d.insert(n, iopt)
 			if err != nil {
 				break
 			}
+			iopt = nextInsertOption(iopt, n)
 			after = append(after, n)
 		} else {
 			dchild := d.Get(mergedChildren[i].ID())
@@ -443,10 +691,11 @@ func mergeChildren(dest DataNode, mergedChildren []DataNode, edit *EditOption) (
 				after = append(after, dchild)
 			} else {
 				n = Clone(mergedChildren[i])
-				_, err = d.insert(n, edit.GetInsertOption())
+				_, err = d.insert(n, iopt)
 				if err != nil {
 					break
 				}
+				iopt = nextInsertOption(iopt, n)
 				after = append(after, n)
 			}
 		}
@@ -459,14 +708,17 @@ func setGroupValue(branch *DataBranch, cschema *SchemaNode, oldnodes []DataNode,
 	var newnodes []DataNode
 	op := edit.GetOperation()
 	iop := edit.GetInsertOption()
+	if iop == nil && cschema.IsOrderedByUser() {
+		iop = InsertToLast{}
+	}
 	switch op {
 	case EditDelete, EditRemove:
-		if callback := edit.GetCallback(); callback != nil {
-			if err = callback(op, oldnodes, nil); err != nil {
-				return err
-			}
-		}
 		for i := range oldnodes {
+			if callback := edit.GetCallback(); callback != nil {
+				if err = callback(op, []DataNode{oldnodes[i]}, nil); err != nil {
+					return err
+				}
+			}
 			branch.Delete(oldnodes[i])
 		}
 		return nil
@@ -491,6 +743,7 @@ func setGroupValue(branch *DataBranch, cschema *SchemaNode, oldnodes []DataNode,
 			if err != nil {
 				break
 			}
+			iop = nextInsertOption(iop, newnodes[i])
 		}
 		if err == nil {
 			if callback := edit.GetCallback(); callback != nil {
@@ -499,7 +752,7 @@ func setGroupValue(branch *DataBranch, cschema *SchemaNode, oldnodes []DataNode,
 				}
 			}
 		}
-	case EditMerge:
+	case EditMerge, EditUpdate:
 		var new *DataNodeGroup
 		switch v := value.(type) {
 		case []string:
@@ -535,8 +788,10 @@ func setGroupValue(branch *DataBranch, cschema *SchemaNode, oldnodes []DataNode,
 //  // - EditOption (create): create a node. It returns data-exists error if it exists.
 //  // - EditOption (replace): replace the node to the new node.
 //  // - EditOption (merge): update the node. (default)
+//  // - EditOption (update): update the node. It returns data-missing error if it doesn't exist.
 //  // - EditOption (delete): delete the node. It returns data-missing error if it doesn't exist.
 //  // - EditOption (remove): delete the node. It doesn't return data-missing error.
+//  // - EditOption (delete, remove) with values for leaf-list: delete only the leaf-list values.
 func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value interface{}) error {
 	var valnum int
 	isValueString := false
@@ -549,22 +804,37 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 	}
 	op := eopt.GetOperation()
 	if len(pathnode) == 0 {
+		if op == EditNone {
+			return nil // the existent node is not changed.
+		}
+		if root.IsStateNode() && eopt.GetRejectStateEdit() {
+			return Errorf(ETagOperationNotSupported,
+				"unable to %s the state (config false) node %s", op, root.Schema().Path())
+		}
 		switch op {
 		case EditCreate:
 			return Errorf(ETagDataExists, "data node %s already exists", root.ID())
 		case EditDelete, EditRemove:
+			if leaflist, ok := root.(*DataLeafList); ok && valnum > 0 {
+				return deleteLeafListValue(leaflist, eopt, value)
+			}
 			if cb := eopt.GetCallback(); cb != nil {
 				if err := cb(op, []DataNode{root}, nil); err != nil {
 					return err
 				}
 			}
-			if err := root.Remove(); err != nil {
+			if err := removeNode(root); err != nil {
 				return err
 			}
 		default: // replace, merge
 			if value == nil {
 				return nil
 			}
+			if eopt.GetRejectStateEdit() {
+				if err := rejectStateValue(root.Schema(), op, isValueString, value); err != nil {
+					return err
+				}
+			}
 			if cb := eopt.GetCallback(); cb != nil {
 				var err error
 				backup := Clone(root)
@@ -616,8 +886,10 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 		if !ok {
 			return fmt.Errorf("select children from non-branch node %s", root)
 		}
-		for i := 0; i < len(branch.children); i++ {
-			err := setValue(branch.Child(i), pathnode[1:], eopt, value)
+		// copy the children because they can be removed while iterating.
+		children := copyDataNodeList(branch.children)
+		for i := range children {
+			err := setValue(children[i], pathnode[1:], eopt, value)
 			if err != nil {
 				return err
 			}
@@ -632,8 +904,12 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 		if !ok {
 			return fmt.Errorf("select children from non-branch node %s", root)
 		}
-		for i := 0; i < len(branch.children); i++ {
-			err := setValue(root.Child(i), pathnode, eopt, value)
+		children := copyDataNodeList(branch.children)
+		for i := range children {
+			if children[i].Parent() == nil { // already removed
+				continue
+			}
+			err := setValue(children[i], pathnode, eopt, value)
 			if err != nil {
 				return err
 			}
@@ -648,6 +924,8 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 			return fmt.Errorf("longtail path for metadata %s", pathnode[0].Name)
 		}
 		switch op {
+		case EditNone:
+			return nil
 		case EditDelete, EditRemove:
 			return root.UnsetMetadata(pathnode[0].Name)
 		default:
@@ -666,6 +944,10 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 	if cschema == nil {
 		return fmt.Errorf("schema %s not found from %s", pathnode[0].Name, branch.schema.Name)
 	}
+	if cschema.IsState && eopt.GetRejectStateEdit() {
+		return Errorf(ETagOperationNotSupported,
+			"unable to %s the state (config false) node %s", op, cschema.Path())
+	}
 	pmap, err := pathnode[0].ToMap()
 	if err != nil {
 		return err
@@ -682,6 +964,21 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 		if cschema.IsSingleLeafList() {
 			delete(pmap, ".")
 		} else { // multiple leaf-list
+			if _, ok := pmap["."]; !ok && reachToEnd && valnum > 0 &&
+				(op == EditDelete || op == EditRemove) {
+				// delete the leaf-list nodes indicated by the values
+				if valnum > 1 {
+					for _, v := range valueStrings(value) {
+						if err := setValue(root, pathnode, eopt, []string{v}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+				pmap["."] = valueStrings(value)[0]
+				value = nil
+				valnum = 0
+			}
 			if _, ok := pmap["."]; ok {
 				if valnum > 1 {
 					return Errorf(EAppTagInvalidArg,
@@ -700,7 +997,7 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 	children := branch.find(cschema, &id, nodeGroup, valueSearch, pmap)
 	if len(children) == 0 {
 		switch op {
-		case EditDelete:
+		case EditDelete, EditUpdate, EditNone:
 			return Errorf(ETagDataMissing, "data node %s not found", id)
 		case EditRemove:
 			return nil
@@ -711,6 +1008,9 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 		}
 	}
 
+	if reachToEnd && op == EditNone {
+		return nil // the existent nodes are not changed.
+	}
 	if reachToEnd && nodeGroup {
 		return setGroupValue(branch, cschema, copyDataNodeList(children), eopt, value)
 	}
@@ -733,6 +1033,11 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 			if err != nil {
 				return err
 			}
+			if eopt.GetRejectStateEdit() {
+				if err := rejectStateNode(child, op); err != nil {
+					return err
+				}
+			}
 		}
 		if _, err = branch.insert(child, eopt.GetInsertOption()); err != nil {
 			return err
@@ -747,7 +1052,7 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 		}
 
 		if err := setValue(child, pathnode[1:], eopt, value); err != nil {
-			child.Remove()
+			detach(child)
 			return err
 		}
 		return nil
@@ -762,6 +1067,93 @@ func setValue(root DataNode, pathnode []*PathNode, eopt *EditOption, value inter
 	}
 }
 
+// rejectStateNode() returns an error if the data node or its descendants are state (config false) nodes.
+func rejectStateNode(node DataNode, op EditOp) error {
+	if node.IsStateNode() {
+		return Errorf(ETagOperationNotSupported,
+			"unable to %s the state (config false) node %s", op, node.Schema().Path())
+	}
+	if branch, ok := node.(*DataBranch); ok && branch.schema.HasState {
+		for i := range branch.children {
+			if err := rejectStateNode(branch.children[i], op); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rejectStateValue() returns an error if the value (e.g. JSON or YAML encoded bytes) to be set
+// to the data node of the schema has state (config false) nodes.
+func rejectStateValue(schema *SchemaNode, op EditOp, isValueString bool, value interface{}) error {
+	if !schema.IsDir() || !schema.HasState {
+		return nil
+	}
+	node, err := New(schema)
+	if err != nil {
+		return err
+	}
+	if isValueString {
+		err = node.SetValueString(value.([]string)...)
+	} else {
+		err = node.SetValue(value.([]interface{})...)
+	}
+	if err != nil {
+		return err
+	}
+	return rejectStateNode(node, op)
+}
+
+// valueStrings() returns the value strings of the setting values.
+func valueStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		s := make([]string, 0, len(v))
+		for i := range v {
+			s = append(s, ValueToValueString(v[i]))
+		}
+		return s
+	}
+	return nil
+}
+
+// deleteLeafListValue() deletes the values from the single leaf-list node.
+// The leaf-list node is removed if all values are deleted.
+func deleteLeafListValue(leaflist *DataLeafList, eopt *EditOption, value interface{}) error {
+	op := eopt.GetOperation()
+	values := valueStrings(value)
+	if op == EditDelete {
+		for i := range values {
+			found := false
+			for j := range leaflist.value {
+				if ValueToValueString(leaflist.value[j]) == values[i] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return Errorf(ETagDataMissing, "data node %s[.=%s] not found", leaflist.ID(), values[i])
+			}
+		}
+	}
+	backup := Clone(leaflist)
+	if err := leaflist.UnsetValueString(values...); err != nil {
+		return err
+	}
+	if cb := eopt.GetCallback(); cb != nil {
+		if err := cb(op, []DataNode{backup}, []DataNode{leaflist}); err != nil {
+			recover(leaflist, backup)
+			return err
+		}
+	}
+	if len(leaflist.value) == 0 {
+		return removeNode(leaflist)
+	}
+	return nil
+}
+
 // SetValueString sets a value to the target DataNode in the path.
 // If the target DataNode is a branch node, the value must be json or json_ietf bytes.
 // If the target data node is a leaf or a leaf-list node, the value should be string.
@@ -776,9 +1168,79 @@ func SetValueString(root DataNode, path string, opt *EditOption, value ...string
 	return setValue(root, pathnode, opt, value)
 }
 
+// CanSet() checks the edit of SetValueString() without side effects.
+// The edit is applied to the clone of the subtree selected by the first element
+// of the path and then the edited subtree is validated. The clone is discarded
+// and only the error of the edit or the validation is returned.
+// The callback of the edit option is not invoked for the check.
+func CanSet(root DataNode, path string, opt *EditOption, value ...string) error {
+	if !IsValid(root) {
+		return fmt.Errorf("invalid root data node")
+	}
+	pathnode, err := ParsePath(&path)
+	if err != nil {
+		return err
+	}
+	if opt != nil && opt.Callback != nil {
+		o := *opt
+		o.Callback = nil
+		opt = &o
+	}
+	if len(pathnode) > 0 && pathnode[0].Select == NodeSelectFromRoot {
+		for root.Parent() != nil {
+			root = root.Parent()
+		}
+		pathnode[0].Select = NodeSelectChild
+	}
+
+	var scope *SchemaNode
+	var dryrun DataNode
+	branch, ok := root.(*DataBranch)
+	if ok && len(pathnode) > 0 && pathnode[0].Select == NodeSelectChild &&
+		!strings.HasPrefix(pathnode[0].Name, "@") {
+		scope = branch.schema.GetSchema(pathnode[0].Name)
+		if scope == nil {
+			return fmt.Errorf("schema %s not found from %s", pathnode[0].Name, branch.schema.Name)
+		}
+		// clone the data node (with the key nodes) and its ancestors.
+		if dryrun, err = cloneUp(nil, root); err != nil {
+			return err
+		}
+		i, max := indexRangeBySchema(branch, scope)
+		for ; i < max; i++ {
+			if _, err := clone(dryrun.(*DataBranch), branch.children[i]); err != nil {
+				return err
+			}
+		}
+	} else {
+		dryrun = Clone(root)
+		if root.Parent() != nil {
+			if _, err := cloneUp(dryrun, root.Parent()); err != nil {
+				return err
+			}
+		}
+	}
+	if dryrun == nil {
+		return fmt.Errorf("unable to clone %s", root)
+	}
+	if err := setValue(dryrun, pathnode, opt, value); err != nil {
+		return err
+	}
+	if scope == nil {
+		return validationError(Validate(dryrun))
+	}
+	var errs []error
+	i, max := indexRangeBySchema(dryrun.(*DataBranch), scope)
+	for ; i < max; i++ {
+		errs = append(errs, Validate(dryrun.(*DataBranch).children[i])...)
+	}
+	return validationError(errs)
+}
+
 // SetValue sets a value to the target DataNode in the path.
 // If the target DataNode is a branch node, the value must be map[interface{}]interface{} or map[string]interface{}.
 // If the target data node is a leaf or a leaf-list node, the value should be the value.
+// If the value is a DataNode, the DataNode is inserted or merged to the path according to the EditOp.
 func SetValue(root DataNode, path string, opt *EditOption, value ...interface{}) error {
 	if !IsValid(root) {
 		return fmt.Errorf("invalid root data node")
@@ -787,58 +1249,183 @@ func SetValue(root DataNode, path string, opt *EditOption, value ...interface{})
 	if err != nil {
 		return err
 	}
+	if len(value) == 1 {
+		if node, ok := value[0].(DataNode); ok {
+			return setDataNode(root, pathnode, opt, node)
+		}
+	}
 	return setValue(root, pathnode, opt, value)
 }
 
-func replaceNode(root DataNode, pathnode []*PathNode, node DataNode) error {
-	branch, ok := root.(*DataBranch)
-	if !ok {
-		return fmt.Errorf("%s is not a branch", root)
+// setDataNode() inserts, merges or replaces the data node to the path according to the EditOp.
+func setDataNode(root DataNode, pathnode []*PathNode, eopt *EditOption, node DataNode) error {
+	if !IsValid(node) {
+		return fmt.Errorf("invalid data node inserted")
 	}
-	if len(pathnode) == 0 {
-		_, err := branch.insert(node, nil)
-		return err
+	op := eopt.GetOperation()
+	if op == EditDelete || op == EditRemove {
+		return setValue(root, pathnode, eopt, nil)
 	}
-	switch pathnode[0].Select {
-	case NodeSelectSelf:
-		return replaceNode(root, pathnode[1:], node)
-	case NodeSelectParent:
-		if root.Parent() == nil {
-			return fmt.Errorf("unknown parent node selected from %s", root)
-		}
-		root = root.Parent()
-		return replaceNode(root, pathnode[1:], node)
-	case NodeSelectFromRoot:
-		for root.Parent() != nil {
-			root = root.Parent()
+	if eopt.GetRejectStateEdit() {
+		if err := rejectStateNode(node, op); err != nil {
+			return err
 		}
-	case NodeSelectAllChildren:
-		return fmt.Errorf("unable to specify the node position replaced")
-	case NodeSelectAll:
-		return fmt.Errorf("unable to specify the node position replaced")
-	}
-
-	cschema := branch.schema.GetSchema(pathnode[0].Name)
-	if cschema == nil {
-		return fmt.Errorf("schema %s not found from %s", pathnode[0].Name, branch.schema.Name)
 	}
-	pmap, err := pathnode[0].ToMap()
-	if err != nil {
+	if err := checkKeyPredicates(pathnode, node); err != nil {
 		return err
 	}
-	switch {
-	case cschema.IsLeafList():
-		if cschema.Option.LeafListValueAsKey && len(pathnode) > 1 {
-			pmap["."] = pathnode[1].Name
-			pathnode = pathnode[:1]
+	found := findNode(root, pathnode, false)
+	switch len(found) {
+	case 0:
+		if op == EditUpdate {
+			return Errorf(ETagDataMissing, "data node %s not found", node.ID())
 		}
-	}
-	if cschema == node.Schema() {
-		if len(pathnode) > 1 {
-			return fmt.Errorf("invalid long tail path: %s", pathnode[1].Name)
+		cb := eopt.GetCallback()
+		var existing DataNode
+		if cb != nil {
+			existing = deepestNode(root, pathnode)
 		}
-		err := node.UpdateByMap(pmap)
-		if err != nil {
+		if err := replaceNode(root, pathnode, node); err != nil {
+			return err
+		}
+		if cb != nil {
+			if err := cb(op, nil, []DataNode{node}); err != nil {
+				// remove the ancestors created for the data node together.
+				top := node
+				p := node.Parent()
+				for ; p != nil && p != existing; p = p.Parent() {
+					top = p
+				}
+				detach(node)
+				if p != nil && top != node {
+					detach(top)
+				}
+				return err
+			}
+		}
+		return nil
+	case 1:
+	default:
+		return Errorf(ETagOperationNotSupported,
+			"multiple nodes are selected for setting the data node")
+	}
+	old := found[0]
+	if old.Schema() != node.Schema() {
+		return Errorf(EAppTagInvalidArg, "unable to set %s to %s", node, old.Path())
+	}
+	switch op {
+	case EditCreate:
+		return Errorf(EAppTagDataNodeExists, "data node %s exits", old.ID())
+	case EditReplace:
+		if err := replace(old, node); err != nil {
+			return err
+		}
+		if cb := eopt.GetCallback(); cb != nil {
+			if err := cb(op, []DataNode{old}, []DataNode{node}); err != nil {
+				replace(node, old)
+				return err
+			}
+		}
+	default: // merge, update
+		backup := Clone(old)
+		if err := merge(old, node); err != nil {
+			recover(old, backup)
+			return err
+		}
+		if cb := eopt.GetCallback(); cb != nil {
+			if err := cb(op, []DataNode{backup}, []DataNode{old}); err != nil {
+				recover(old, backup)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkKeyPredicates() returns an error if the key values of the data node are not matched to
+// the key predicates of the last path element. e.g. single-key-list[list-key=A] for the list entry B.
+func checkKeyPredicates(pathnode []*PathNode, node DataNode) error {
+	branch, ok := node.(*DataBranch)
+	if !ok || len(pathnode) == 0 || !branch.schema.IsListHasKey() {
+		return nil
+	}
+	pmap, err := pathnode[len(pathnode)-1].ToMap()
+	if err != nil {
+		return nil
+	}
+	for _, kname := range branch.schema.Keyname {
+		v, ok := pmap[kname].(string)
+		key := branch.Get(kname)
+		if !ok || v == "*" || key == nil {
+			continue
+		}
+		if kv, err := NewWithValueString(key.Schema(), v); err != nil || !Equal(kv, key) {
+			return Errorf(EAppTagInvalidArg, "the key %s of %s is not matched to the path predicate %s=%s",
+				kname, node.ID(), kname, v)
+		}
+	}
+	return nil
+}
+
+// deepestNode() returns the deepest data node existing in the path.
+func deepestNode(root DataNode, pathnode []*PathNode) DataNode {
+	for i := len(pathnode) - 1; i > 0; i-- {
+		if found := findNode(root, pathnode[:i], false); len(found) == 1 {
+			return found[0]
+		}
+	}
+	return root
+}
+
+func replaceNode(root DataNode, pathnode []*PathNode, node DataNode) error {
+	branch, ok := root.(*DataBranch)
+	if !ok {
+		return fmt.Errorf("%s is not a branch", root)
+	}
+	if len(pathnode) == 0 {
+		_, err := branch.insert(node, nil)
+		return err
+	}
+	switch pathnode[0].Select {
+	case NodeSelectSelf:
+		return replaceNode(root, pathnode[1:], node)
+	case NodeSelectParent:
+		if root.Parent() == nil {
+			return fmt.Errorf("unknown parent node selected from %s", root)
+		}
+		root = root.Parent()
+		return replaceNode(root, pathnode[1:], node)
+	case NodeSelectFromRoot:
+		for root.Parent() != nil {
+			root = root.Parent()
+		}
+	case NodeSelectAllChildren:
+		return fmt.Errorf("unable to specify the node position replaced")
+	case NodeSelectAll:
+		return fmt.Errorf("unable to specify the node position replaced")
+	}
+
+	cschema := branch.schema.GetSchema(pathnode[0].Name)
+	if cschema == nil {
+		return fmt.Errorf("schema %s not found from %s", pathnode[0].Name, branch.schema.Name)
+	}
+	pmap, err := pathnode[0].ToMap()
+	if err != nil {
+		return err
+	}
+	switch {
+	case cschema.IsLeafList():
+		if cschema.Option.LeafListValueAsKey && len(pathnode) > 1 {
+			pmap["."] = pathnode[1].Name
+			pathnode = pathnode[:1]
+		}
+	}
+	if cschema == node.Schema() {
+		if len(pathnode) > 1 {
+			return fmt.Errorf("invalid long tail path: %s", pathnode[1].Name)
+		}
+		err := node.UpdateByMap(pmap)
+		if err != nil {
 			return err
 		}
 		if _, err := branch.insert(node, nil); err != nil {
@@ -862,7 +1449,7 @@ func replaceNode(root DataNode, pathnode []*PathNode, node DataNode) error {
 		}
 		err = replaceNode(child, pathnode[1:], node)
 		if err != nil {
-			child.Remove()
+			detach(child)
 		}
 		return err
 	}
@@ -913,6 +1500,16 @@ func Delete(root DataNode, path string) error {
 }
 
 func returnFound(node DataNode, option ...Option) []DataNode {
+	found := selectFound(node, option...)
+	if len(found) > 0 {
+		if pager := findPagerOf(option); pager != nil {
+			return pager.page(found)
+		}
+	}
+	return found
+}
+
+func selectFound(node DataNode, option ...Option) []DataNode {
 	for i := range option {
 		switch option[i].(type) {
 		case ConfigOnly:
@@ -961,7 +1558,8 @@ func findNode(root DataNode, pathnode []*PathNode, useXPath bool, option ...Opti
 		if !ok {
 			return nil
 		}
-		for i := 0; i < len(branch.children); i++ {
+		pager := findPagerOf(option)
+		for i := 0; i < len(branch.children) && !pager.done(); i++ {
 			children = append(children, findNode(root.Child(i), pathnode[1:], useXPath, option...)...)
 		}
 		return children
@@ -971,7 +1569,8 @@ func findNode(root DataNode, pathnode []*PathNode, useXPath bool, option ...Opti
 		if !ok {
 			return children
 		}
-		for i := 0; i < len(branch.children); i++ {
+		pager := findPagerOf(option)
+		for i := 0; i < len(branch.children) && !pager.done(); i++ {
 			children = append(children, findNode(root.Child(i), pathnode, useXPath, option...)...)
 		}
 		return children
@@ -1006,18 +1605,79 @@ func findNode(root DataNode, pathnode []*PathNode, useXPath bool, option ...Opti
 	} else {
 		node = branch.find(cschema, &id, groupSearch, valueSearch, pmap)
 	}
-	for i := range node {
+	pager := findPagerOf(option)
+	for i := 0; i < len(node) && !pager.done(); i++ {
 		children = append(children, findNode(node[i], pathnode[1:], useXPath, option...)...)
 	}
 	return children
 }
 
+// Limit option is used to limit the number of the data nodes found by Find().
+// The search is stopped once the number of the data nodes are found.
+//   Find(root, "/sample/single-key-list/*", Limit(10), Offset(20))
+type Limit int
+
+func (limit Limit) IsOption() {}
+
+// Offset option is used to skip the number of the first data nodes found by Find().
+type Offset int
+
+func (offset Offset) IsOption() {}
+
+// findPager keeps the state of the Limit and Offset options while searching data nodes.
+type findPager struct {
+	offset, limit, count int
+}
+
+func (pager *findPager) IsOption() {}
+
+// page() returns the found data nodes within the window of the pager.
+func (pager *findPager) page(found []DataNode) []DataNode {
+	var paged []DataNode
+	for i := range found {
+		pager.count++
+		if pager.count <= pager.offset {
+			continue
+		}
+		if pager.limit >= 0 && pager.count > pager.offset+pager.limit {
+			break
+		}
+		paged = append(paged, found[i])
+	}
+	return paged
+}
+
+// done() returns true if the data nodes are found as many as the limit.
+func (pager *findPager) done() bool {
+	if pager == nil || pager.limit < 0 {
+		return false
+	}
+	return pager.count >= pager.offset+pager.limit
+}
+
+func findPagerOf(option []Option) *findPager {
+	for i := range option {
+		if pager, ok := option[i].(*findPager); ok {
+			return pager
+		}
+	}
+	return nil
+}
+
 type UseXPath struct{}
 
 func (useXpath UseXPath) IsOption() {}
 
+// RelativeRoot option is used to find data nodes from the starting node
+// if the path starts with `/`. The path is evaluated as an absolute path of the subtree.
+//   Find(node, "/path/to/data", RelativeRoot{})
+type RelativeRoot struct{}
+
+func (relativeRoot RelativeRoot) IsOption() {}
+
 // Find() finds all data nodes in the path. xpath format can be used for the path as following example.
 //   Find(root, "//path/to/data[name='xxx']", UseXPath{})
+// Limit and Offset options can be used to get a window of the found data nodes.
 func Find(root DataNode, path string, option ...Option) ([]DataNode, error) {
 	if !IsValid(root) {
 		return nil, fmt.Errorf("invalid root data node")
@@ -1027,14 +1687,227 @@ func Find(root DataNode, path string, option ...Option) ([]DataNode, error) {
 		return nil, err
 	}
 	useXPath := false
+	var pager *findPager
 	for i := range option {
-		if _, ok := option[i].(UseXPath); ok {
+		switch o := option[i].(type) {
+		case Limit:
+			if pager == nil {
+				pager = &findPager{limit: -1}
+			}
+			pager.limit = int(o)
+		case Offset:
+			if pager == nil {
+				pager = &findPager{limit: -1}
+			}
+			pager.offset = int(o)
+		case UseXPath:
 			useXPath = true
+		case RelativeRoot:
+			if len(pathnode) > 0 && pathnode[0].Select == NodeSelectFromRoot {
+				// the starting node is the root of the path.
+				pathnode[0].Select = NodeSelectChild
+				if pathnode[0].Name == "" {
+					pathnode[0].Select = NodeSelectSelf
+				}
+			}
 		}
 	}
+	if pager != nil {
+		option = append(option[:len(option):len(option)], pager)
+	}
 	return findNode(root, pathnode, useXPath, option...), nil
 }
 
+// Resolve() returns the data node referred by the leafref or instance-identifier leaf node.
+// The absolute paths are searched from the root and the relative paths are searched from the leaf node.
+// The predicates of the paths are evaluated as XPath expressions.
+func Resolve(root DataNode, leaf DataNode) (DataNode, error) {
+	if !IsValid(leaf) || !leaf.IsLeafNode() {
+		return nil, Errorf(EAppTagInvalidArg, "invalid leaf node inserted")
+	}
+	if !IsValid(root) {
+		root = leaf
+	}
+	schema := leaf.Schema()
+	types := []*yang.YangType{schema.Type}
+	if schema.Type.Kind == yang.Yunion {
+		types = schema.Type.Type
+	}
+	value := leaf.ValueString()
+	for _, typ := range types {
+		switch typ.Kind {
+		case yang.YinstanceIdentifier:
+			if value == "" {
+				continue
+			}
+			found, err := Find(root, value, UseXPath{})
+			if err != nil {
+				return nil, err
+			}
+			switch len(found) {
+			case 0:
+				continue
+			case 1:
+				return found[0], nil
+			default:
+				return nil, Errorf(ETagInvalidValue, "instance-identifier %s refers to multiple data nodes", value)
+			}
+		case yang.Yleafref:
+			from := root
+			if !strings.HasPrefix(typ.Path, "/") {
+				from = leaf
+			}
+			found, err := Find(from, typ.Path, UseXPath{})
+			if err != nil {
+				return nil, err
+			}
+			for i := range found {
+				if found[i].ValueString() == value {
+					return found[i], nil
+				}
+			}
+		}
+	}
+	return nil, Errorf(ETagDataMissing, "no data node referred by %s", leaf)
+}
+
+// GetValueStringAt() returns the value string of a single leaf data node in the path.
+// It returns false if the path is invalid or the path selects no leaf node, a branch node
+// or two or more data nodes.
+func GetValueStringAt(node DataNode, path string) (string, bool) {
+	found, err := Find(node, path)
+	if err != nil || len(found) != 1 || !found[0].IsLeafNode() {
+		return "", false
+	}
+	return found[0].ValueString(), true
+}
+
+// WithDefaults option is used to read or marshal the absent leaf nodes having the default values
+// as if they exist. The default values are not stored to the data tree, so it keeps the data tree
+// sparse without YANGTreeOption.CreatedWithDefault.
+//   MarshalJSON(node, WithDefaults{})
+//   GetValue(node, "leaf-name", WithDefaults{})
+type WithDefaults struct{}
+
+func (f WithDefaults) IsOption() {}
+
+func hasWithDefaults(option []Option) bool {
+	for i := range option {
+		if _, ok := option[i].(WithDefaults); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NumericKeyAware option is used to marshal the entries of the system-ordered lists
+// in the numeric order of their numeric keys. The list entries are kept in the lexical
+// order of their IDs, so the entries having the numeric keys 2, 10 and 100 are
+// marshalled as 10, 100 and 2 without this option.
+//   MarshalXML(node, NumericKeyAware{})
+type NumericKeyAware struct{}
+
+func (f NumericKeyAware) IsOption() {}
+
+// compareKeyValues() compares the key values of two list entries in the order of the keys.
+// The numeric key values are compared numerically and the others are compared lexically.
+func compareKeyValues(keyname []string, a, b DataNode) int {
+	for _, k := range keyname {
+		ka, kb := a.Get(k), b.Get(k)
+		if ka == nil || kb == nil {
+			continue
+		}
+		fa, oka := valueToFloat64(ka.Value())
+		fb, okb := valueToFloat64(kb.Value())
+		if oka && okb && fa != fb {
+			if fa < fb {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(ka.ValueString(), kb.ValueString()); c != 0 && !(oka && okb) {
+			return c
+		}
+	}
+	return 0
+}
+
+// sortByNumericKeys() returns the children in which the entries of each system-ordered list
+// are sorted in the numeric order of their keys. The children slice is not modified.
+func sortByNumericKeys(children []DataNode) []DataNode {
+	var sorted []DataNode
+	for i := 0; i < len(children); {
+		schema := children[i].Schema()
+		j := i + 1
+		for ; j < len(children) && children[j].Schema() == schema; j++ {
+		}
+		if j-i > 1 && schema.IsList() && len(schema.Keyname) > 0 && !schema.IsOrderedByUser() {
+			if sorted == nil {
+				sorted = make([]DataNode, len(children))
+				copy(sorted, children)
+			}
+			entries := sorted[i:j]
+			sort.SliceStable(entries, func(x, y int) bool {
+				return compareKeyValues(schema.Keyname, entries[x], entries[y]) < 0
+			})
+		}
+		i = j
+	}
+	if sorted == nil {
+		return children
+	}
+	return sorted
+}
+
+// getAbsentDefault() returns the default node of the absent child having the id.
+func getAbsentDefault(node DataNode, id string) DataNode {
+	branch, ok := node.(*DataBranch)
+	if !ok {
+		return nil
+	}
+	defaults, err := branch.absentDefaults()
+	if err != nil {
+		return nil
+	}
+	for i := range defaults {
+		if defaults[i].ID() == id {
+			return defaults[i]
+		}
+	}
+	return nil
+}
+
+// GetValue() returns the value of the child data node having the id like DataNode.GetValue().
+// If WithDefaults option is set, the default value is returned for the absent child leaf node.
+func GetValue(node DataNode, id string, option ...Option) interface{} {
+	if !IsValid(node) {
+		return nil
+	}
+	if !hasWithDefaults(option) || node.Exist(id) {
+		return node.GetValue(id)
+	}
+	if d := getAbsentDefault(node, id); d != nil {
+		return d.Value()
+	}
+	return nil
+}
+
+// GetValueString() returns the value string of the child data node having the id like
+// DataNode.GetValueString(). If WithDefaults option is set, the default value string is
+// returned for the absent child leaf node.
+func GetValueString(node DataNode, id string, option ...Option) string {
+	if !IsValid(node) {
+		return ""
+	}
+	if !hasWithDefaults(option) || node.Exist(id) {
+		return node.GetValueString(id)
+	}
+	if d := getAbsentDefault(node, id); d != nil {
+		return d.ValueString()
+	}
+	return ""
+}
+
 // FindValueString() finds all data in the path and then returns their values by string.
 func FindValueString(root DataNode, path string) ([]string, error) {
 	if !IsValid(root) {
@@ -1080,6 +1953,220 @@ func FindValue(root DataNode, path string) ([]interface{}, error) {
 	return vlist, nil
 }
 
+// leafValue() returns the value of the leaf data node.
+func leafValue(node DataNode) (interface{}, bool) {
+	if !IsValid(node) || !node.IsLeafNode() {
+		return nil, false
+	}
+	v := node.Value()
+	return v, v != nil
+}
+
+// GetInt64() returns the value of the leaf data node as int64 regardless of the integer width.
+// It returns false if the value is not an integer or is out of the int64 range.
+func GetInt64(node DataNode) (int64, bool) {
+	v, ok := leafValue(node)
+	if !ok {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint, uint8, uint16, uint32, uint64:
+		u, ok := GetUint64(node)
+		if !ok || u > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(u), true
+	}
+	return 0, false
+}
+
+// GetUint64() returns the value of the leaf data node as uint64 regardless of the integer width.
+// It returns false if the value is not an integer or is negative.
+func GetUint64(node DataNode) (uint64, bool) {
+	v, ok := leafValue(node)
+	if !ok {
+		return 0, false
+	}
+	switch v := v.(type) {
+	case uint:
+		return uint64(v), true
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case int, int8, int16, int32, int64:
+		i, ok := GetInt64(node)
+		if !ok || i < 0 {
+			return 0, false
+		}
+		return uint64(i), true
+	}
+	return 0, false
+}
+
+// GetFloat64() returns the value of the leaf data node as float64.
+// The integer and decimal64 values are converted to float64.
+func GetFloat64(node DataNode) (float64, bool) {
+	v, ok := leafValue(node)
+	if !ok {
+		return 0, false
+	}
+	return valueToFloat64(v)
+}
+
+// valueToFloat64() converts the integer and decimal64 values to float64.
+func valueToFloat64(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case yang.Number:
+		f, err := strconv.ParseFloat(v.String(), 64)
+		return f, err == nil
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// AggOp [AggSum, AggMin, AggMax, AggAvg] is the aggregate operation of Aggregate().
+type AggOp int
+
+const (
+	AggSum AggOp = iota // the sum of the values
+	AggMin              // the minimum of the values
+	AggMax              // the maximum of the values
+	AggAvg              // the average of the values
+)
+
+func (op AggOp) String() string {
+	switch op {
+	case AggSum:
+		return "sum"
+	case AggMin:
+		return "min"
+	case AggMax:
+		return "max"
+	case AggAvg:
+		return "avg"
+	default:
+		return "unknown"
+	}
+}
+
+// Aggregate() computes the sum, minimum, maximum or average of the numeric values of
+// the leaf and leaf-list data nodes found in the path (xpath).
+//   Aggregate(root, "/sample/single-key-list/uint32-range", AggSum)
+// The sum of no value is 0 and the other operations return an error if no value is found.
+func Aggregate(root DataNode, path string, op AggOp) (float64, error) {
+	values, err := FindValue(root, path)
+	if err != nil {
+		return 0, err
+	}
+	var result float64
+	count := 0
+	aggregate := func(v interface{}) error {
+		if v == nil {
+			return nil
+		}
+		f, ok := valueToFloat64(v)
+		if !ok {
+			return Errorf(ETagInvalidValue, "non-numeric value %v found in %s", v, path)
+		}
+		count++
+		switch {
+		case count == 1:
+			result = f
+		case op == AggSum, op == AggAvg:
+			result += f
+		case op == AggMin && f < result, op == AggMax && f > result:
+			result = f
+		}
+		return nil
+	}
+	switch op {
+	case AggSum, AggMin, AggMax, AggAvg:
+	default:
+		return 0, Errorf(EAppTagInvalidArg, "unknown aggregate operation %v", op)
+	}
+	for i := range values {
+		if vlist, ok := values[i].([]interface{}); ok { // single leaf-list node
+			for j := range vlist {
+				if err := aggregate(vlist[j]); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		if err := aggregate(values[i]); err != nil {
+			return 0, err
+		}
+	}
+	if count == 0 {
+		if op == AggSum {
+			return 0, nil
+		}
+		return 0, Errorf(ETagDataMissing, "no value found in %s for %s", path, op)
+	}
+	if op == AggAvg {
+		result /= float64(count)
+	}
+	return result, nil
+}
+
+// GetBool() returns the value of the boolean leaf data node.
+func GetBool(node DataNode) (bool, bool) {
+	v, ok := leafValue(node)
+	if !ok {
+		return false, false
+	}
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// GetString() returns the value of the leaf data node as the value string.
+// It returns false if the node is not a leaf node or has no value.
+func GetString(node DataNode) (string, bool) {
+	v, ok := leafValue(node)
+	if !ok {
+		return "", false
+	}
+	return ValueToValueString(v), true
+}
+
 func clone(destParent *DataBranch, src DataNode) (DataNode, error) {
 	var dest DataNode
 	switch node := src.(type) {
@@ -1109,7 +2196,12 @@ func clone(destParent *DataBranch, src DataNode) (DataNode, error) {
 		}
 	}
 	if destParent != nil {
-		_, err := destParent.insert(dest, nil)
+		// keep the order of the src for the ordered-by user nodes.
+		var iopt InsertOption
+		if src.Schema().IsOrderedByUser() {
+			iopt = InsertToLast{}
+		}
+		_, err := destParent.insert(dest, iopt)
 		if err != nil {
 			return nil, err
 		}
@@ -1172,6 +2264,26 @@ func Clone(src DataNode) DataNode {
 	return nil
 }
 
+// Reset() returns the branch node to the state just created from the schema.
+// It removes all children except the key nodes of a list entry and then
+// recreates the default child nodes if CreatedWithDefault is enabled.
+func Reset(node DataNode) error {
+	if !IsValid(node) {
+		return Errorf(EAppTagDataNodeMissing, "no data node to reset")
+	}
+	branch, ok := node.(*DataBranch)
+	if !ok {
+		return Errorf(ETagOperationNotSupported, "reset not supported for non-branch node %s", node)
+	}
+	if err := branch.Clear(); err != nil {
+		return err
+	}
+	if IsCreatedWithDefault(branch.schema) {
+		return branch.setDefaults()
+	}
+	return nil
+}
+
 // Move() moves the src data node to the dest node.
 // The dest node must have the same schema of the src parent nodes.
 func Move(src, dest DataNode) error {
@@ -1211,6 +2323,16 @@ func Move(src, dest DataNode) error {
 	return Errorf(EAppTagDataNodeMissing, "matched dest node not found from src parent nodes")
 }
 
+// CanonicalID() returns the stable identity of the data node independent of the tree position.
+// It is the canonical path of the schema (SchemaNode.CanonicalPath()) combined with the key predicates
+// of the node. e.g. /sample:sample/single-key-list[list-key=A]
+func CanonicalID(node DataNode) string {
+	if !IsValid(node) {
+		return ""
+	}
+	return node.Schema().CanonicalPath() + strings.TrimPrefix(node.ID(), node.Name())
+}
+
 // Equal() returns true if node1 and node2 have the same data tree and values.
 func Equal(node1, node2 DataNode) bool {
 	if node1 == node2 {
@@ -1263,19 +2385,25 @@ func Equal(node1, node2 DataNode) bool {
 	return false
 }
 
+// merge() merges the src data node to the dest data node.
+// New entries of the ordered-by user list are appended in the order of the src
+// and the existing entries are merged in place.
 func merge(dest, src DataNode) error {
 	if dest.Schema() != src.Schema() {
 		return fmt.Errorf("unable to merge different schema (%s, %s)", dest, src)
 	}
+	mergeMetadata(dest, src)
 	switch s := src.(type) {
 	case *DataBranch:
 		d := dest.(*DataBranch)
 		for i := range s.children {
 			schema := s.children[i].Schema()
 			if schema.IsDuplicatableList() {
-				if _, err := clone(d, s.children[i]); err != nil {
+				c, err := clone(d, s.children[i])
+				if err != nil {
 					return err
 				}
+				copyMetadataTree(c, s.children[i])
 			} else {
 				dchild := d.GetAll(s.children[i].ID())
 				if len(dchild) > 0 {
@@ -1285,9 +2413,11 @@ func merge(dest, src DataNode) error {
 						}
 					}
 				} else {
-					if _, err := clone(d, s.children[i]); err != nil {
+					c, err := clone(d, s.children[i])
+					if err != nil {
 						return err
 					}
+					copyMetadataTree(c, s.children[i])
 				}
 			}
 		}
@@ -1303,6 +2433,51 @@ func merge(dest, src DataNode) error {
 	return nil
 }
 
+// metadataOf() returns the metadata map of the data node to be updated.
+func metadataOf(node DataNode) *map[string]DataNode {
+	switch n := node.(type) {
+	case *DataBranch:
+		return &n.metadata
+	case *DataLeaf:
+		return &n.metadata
+	case *DataLeafList:
+		return &n.metadata
+	}
+	return nil
+}
+
+// mergeMetadata() merges the metadata of the src node to the dest node.
+// The metadata of the dest node is overridden by the src metadata having the same name.
+func mergeMetadata(dest, src DataNode) {
+	smeta := src.Metadata()
+	if len(smeta) == 0 {
+		return
+	}
+	dmeta := metadataOf(dest)
+	if dmeta == nil {
+		return
+	}
+	if *dmeta == nil {
+		*dmeta = make(map[string]DataNode, len(smeta))
+	}
+	for name, m := range smeta {
+		(*dmeta)[name] = Clone(m)
+	}
+}
+
+// copyMetadataTree() copies the metadata of the src node and its descendants
+// to the dest node cloned from the src node.
+func copyMetadataTree(dest, src DataNode) {
+	mergeMetadata(dest, src)
+	dchildren, schildren := dest.Children(), src.Children()
+	if len(dchildren) != len(schildren) {
+		return
+	}
+	for i := range schildren {
+		copyMetadataTree(dchildren[i], schildren[i])
+	}
+}
+
 // Merge() merges the src data node to the target data node in the path.
 // The target data node is updated using the src data node.
 func Merge(root DataNode, path string, src DataNode) error {
@@ -1346,6 +2521,126 @@ func PathMap(node []DataNode) map[string]DataNode {
 	return m
 }
 
+// ToFlatMap() converts the data node and its descendants to a flat map of the path and value string.
+// All leaf and leaf-list nodes are represented by their paths and value strings and
+// the branch nodes having no child are represented by their paths with empty values.
+func ToFlatMap(node DataNode) map[string]string {
+	m := map[string]string{}
+	if !IsValid(node) {
+		return m
+	}
+	toFlatMap(node, m)
+	return m
+}
+
+func toFlatMap(node DataNode, m map[string]string) {
+	switch n := node.(type) {
+	case *DataBranch:
+		if len(n.children) == 0 {
+			if n.parent != nil {
+				m[n.Path()] = ""
+			}
+			return
+		}
+		for i := range n.children {
+			toFlatMap(n.children[i], m)
+		}
+	case *DataNodeGroup:
+		for i := range n.Nodes {
+			toFlatMap(n.Nodes[i], m)
+		}
+	default:
+		m[node.Path()] = node.ValueString()
+	}
+}
+
+// FromFlatMap() creates a new data tree of the schema from the flat map of the path and value string.
+// The ancestors of the paths are created if they don't exist. The empty value string is set to
+// the data node as it is except for the branch nodes and the leaves of the empty type.
+func FromFlatMap(schema *SchemaNode, m map[string]string) (DataNode, error) {
+	root, err := New(schema)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(m))
+	for path := range m {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if isValueless(schema.FindSchema(path)) {
+			err = SetValueString(root, path, nil)
+		} else {
+			err = SetValueString(root, path, nil, m[path])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// isValueless() returns true if the data node of the schema has no value.
+// e.g. the branch nodes and the leaves of the empty type.
+func isValueless(schema *SchemaNode) bool {
+	if schema == nil {
+		return false
+	}
+	if schema.IsDir() {
+		return true
+	}
+	return schema.Type != nil && schema.Type.Kind == yang.Yempty
+}
+
+// CollectMetadata() collects the metadata of the node and all its descendants.
+// The collected metadata are keyed by the data node path and then the metadata name.
+func CollectMetadata(node DataNode) map[string]map[string]DataNode {
+	m := map[string]map[string]DataNode{}
+	if !IsValid(node) {
+		return m
+	}
+	Traverse(node, func(n DataNode, at TrvsCallOption) error {
+		if meta := n.Metadata(); len(meta) > 0 {
+			path := n.Path()
+			if m[path] == nil {
+				m[path] = map[string]DataNode{}
+			}
+			for name, v := range meta {
+				m[path][name] = v
+			}
+		}
+		return nil
+	}, TrvsCalledAtEnter, -1, false)
+	return m
+}
+
+// Depth() returns the number of ancestors of the node up to the root node.
+// The root node is depth 0 and the children of the root node are depth 1.
+func Depth(node DataNode) int {
+	depth := 0
+	if !IsValid(node) {
+		return depth
+	}
+	for n := node.Parent(); n != nil; n = n.Parent() {
+		depth++
+	}
+	return depth
+}
+
+// AncestorByName() returns the nearest ancestor of the node having the schema name.
+// It returns nil if there is no such ancestor.
+func AncestorByName(node DataNode, name string) DataNode {
+	if !IsValid(node) {
+		return nil
+	}
+	for n := node.Parent(); n != nil; n = n.Parent() {
+		if n.Schema().Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
 // FindAllInRoute() find all parent nodes in the path.
 // The path must indicate an unique node. (not support wildcard and multiple node selection)
 func FindAllInRoute(path string) []DataNode {
@@ -1366,6 +2661,26 @@ func GetKeyValues(node DataNode) ([]string, []string) {
 	return keynames, keyvals
 }
 
+// Siblings() returns the data nodes having the same parent and schema of the node
+// except the node itself. e.g. the other list entries or leaf-list nodes.
+func Siblings(node DataNode) []DataNode {
+	if !IsValid(node) {
+		return nil
+	}
+	parent, ok := node.Parent().(*DataBranch)
+	if !ok {
+		return nil
+	}
+	i, max := indexRangeBySchema(parent, node.Schema())
+	siblings := make([]DataNode, 0, max-i)
+	for ; i < max; i++ {
+		if parent.children[i] != node {
+			siblings = append(siblings, parent.children[i])
+		}
+	}
+	return siblings
+}
+
 // GetOrNew returns the target data node and the ancestor node that was created first along the path from the root.
 func GetOrNew(root DataNode, path string) (node DataNode, created DataNode, err error) {
 	if !IsValid(root) {
@@ -1426,13 +2741,73 @@ func GetOrNew(root DataNode, path string) (node DataNode, created DataNode, err
 	}
 	if err != nil {
 		if created != nil {
-			created.Remove()
+			detach(created)
 		}
 		return nil, nil, err
 	}
 	return node, created, nil
 }
 
+// newSkeletonNode() returns a new data node of the schema without any value and default node.
+func newSkeletonNode(schema *SchemaNode) DataNode {
+	switch {
+	case schema.IsLeaf() || schema.IsLeafList() || schema.IsAnyXML():
+		if schema.Option.SingleLeafList && schema.ListAttr != nil {
+			return &DataLeafList{schema: schema}
+		}
+		return &DataLeaf{schema: schema}
+	default:
+		return &DataBranch{schema: schema, children: []DataNode{}}
+	}
+}
+
+// NewSkeleton() returns a new data node of the schema having only the data nodes in the path
+// down to the target node. The data nodes are created without their values and default nodes
+// and the list entries are created without their keys unless the keys are specified in the path.
+// So the skeleton can be used as a subtree filter (FilterSubtree()) selecting the target node.
+//   NewSkeleton(RootSchema, "/sample/single-key-list/country-code")
+func NewSkeleton(schema *SchemaNode, path string) (DataNode, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("schema is nil")
+	}
+	pathnode, err := ParsePath(&path)
+	if err != nil {
+		return nil, err
+	}
+	top := newSkeletonNode(schema)
+	node := top
+	for i := range pathnode {
+		switch pathnode[i].Select {
+		case NodeSelectChild, NodeSelectFromRoot:
+		default:
+			return nil, Errorf(EAppTagInvalidArg, "invalid path %s for the skeleton", path)
+		}
+		branch, ok := node.(*DataBranch)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a branch", node)
+		}
+		cschema := branch.schema.GetSchema(pathnode[i].Name)
+		if cschema == nil {
+			return nil, fmt.Errorf("schema %s not found from %s", pathnode[i].Name, branch.schema.Name)
+		}
+		child := newSkeletonNode(cschema)
+		if len(pathnode[i].Predicates) > 0 {
+			pmap, err := pathnode[i].ToMap()
+			if err != nil {
+				return nil, err
+			}
+			if err := child.UpdateByMap(pmap); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := branch.insert(child, nil); err != nil {
+			return nil, err
+		}
+		node = child
+	}
+	return top, nil
+}
+
 // replace() replaces a node.
 func replace(from, to DataNode) error {
 	schema := from.Schema()
@@ -1505,3 +2880,77 @@ func recover(target, backup DataNode) error {
 	}
 	return nil
 }
+
+// Reindex() recomputes the ids of the list entries in the data node and its descendants
+// from their current key nodes and re-sorts the children of their parents.
+// It repairs the data tree whose key nodes are updated through the low-level APIs
+// without Rekey(). It fails if two list entries have the same keys.
+func Reindex(node DataNode) error {
+	if !IsValid(node) {
+		return Errorf(EAppTagInvalidArg, "invalid data node")
+	}
+	branch, ok := node.(*DataBranch)
+	if !ok {
+		return nil
+	}
+	// compute all new ids and check the duplicates before updating the list entries.
+	type listID struct {
+		schema *SchemaNode
+		id     string
+	}
+	ids := map[listID]bool{}
+	newIDs := map[*DataBranch]string{}
+	for _, child := range branch.children {
+		entry, ok := child.(*DataBranch)
+		if !ok || !entry.schema.IsListHasKey() {
+			continue
+		}
+		pmap := make(map[string]interface{}, len(entry.schema.Keyname))
+		for _, kname := range entry.schema.Keyname {
+			if entry.Get(kname) == nil {
+				return Errorf(ETagMissingElement, "key %s of list entry %s not found", kname, entry)
+			}
+			pmap[kname] = entry.GetValueString(kname)
+		}
+		id, multiple, _ := entry.schema.GenerateID(pmap)
+		if multiple {
+			return Errorf(ETagBadElement, "unable to generate the id of list entry %s from the keys %v", entry, pmap)
+		}
+		if ids[listID{schema: entry.schema, id: id}] {
+			return Errorf(ETagDataExists, "list entry %s duplicated on %s", id, branch)
+		}
+		ids[listID{schema: entry.schema, id: id}] = true
+		if id != entry.ID() {
+			newIDs[entry] = id
+		}
+	}
+	for entry, id := range newIDs {
+		markRemoved(branch, entry.schema, entry.ID())
+		entry.id = id
+		markDirty(entry, false)
+	}
+	if len(newIDs) > 0 {
+		for i := 0; i < len(branch.children); {
+			schema := branch.children[i].Schema()
+			max := i + 1
+			for ; max < len(branch.children); max++ {
+				if branch.children[max].Schema() != schema {
+					break
+				}
+			}
+			if schema.IsListHasKey() && !schema.IsOrderedByUser() {
+				children := branch.children[i:max]
+				sort.SliceStable(children, func(j, k int) bool {
+					return children[j].ID() < children[k].ID()
+				})
+			}
+			i = max
+		}
+	}
+	for _, child := range branch.children {
+		if err := Reindex(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
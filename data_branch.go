@@ -17,6 +17,8 @@ type DataBranch struct {
 	id       string
 	children []DataNode
 	metadata map[string]DataNode
+	dirty    bool       // set if the node is changed. (YANGTreeOption.TrackDirty)
+	removed  []DataNode // the removed children since the last MarkClean(). (YANGTreeOption.TrackDirty)
 }
 
 func (branch *DataBranch) IsDataNode()              {}
@@ -105,6 +107,11 @@ func (branch *DataBranch) String() string {
 	return branch.schema.Name
 }
 
+// Equal() returns true if the branch is equal to the other data node including all children.
+func (branch *DataBranch) Equal(other DataNode) bool {
+	return Equal(branch, other)
+}
+
 // copyDataNodeList clones the src nodes.
 func copyDataNodeList(src []DataNode) []DataNode {
 	if len(src) > 0 {
@@ -118,6 +125,12 @@ func copyDataNodeList(src []DataNode) []DataNode {
 // find() is used to find child data nodes using the id internally.
 func (branch *DataBranch) find(cschema *SchemaNode, id *string, groupSearch, valueSearch bool, pmap map[string]interface{}) []DataNode {
 	i := indexFirst(branch, id)
+	if cschema.IsOrderedByUser() {
+		// the ordered-by user nodes are not sorted by their ids.
+		if first, max := indexRangeBySchema(branch, cschema); first < max {
+			i = first
+		}
+	}
 	if i < len(branch.children) && cschema != branch.children[i].Schema() {
 		if !strings.HasPrefix(branch.children[i].ID(), *id) {
 			return nil
@@ -249,6 +262,26 @@ func (branch *DataBranch) GetOrNew(id string, insert InsertOption) (DataNode, bo
 	return child, true, nil
 }
 
+// Ensure() ensures the child node having the id exists with the value.
+// It creates the child node with the value if not found or updates the value of the found node.
+// The returned boolean value indicates the node is created. The created node is removed
+// if the value is not valid.
+func (branch *DataBranch) Ensure(id string, value ...string) (DataNode, bool, error) {
+	child, created, err := branch.GetOrNew(id, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(value) > 0 {
+		if err := child.SetValueString(value...); err != nil {
+			if created {
+				branch.Delete(child)
+			}
+			return nil, false, err
+		}
+	}
+	return child, created, nil
+}
+
 func (branch *DataBranch) Create(id string, value ...string) (DataNode, error) {
 	if len(value) > 1 {
 		return nil, Errorf(ETagInvalidValue, "a single value can only be set at a time")
@@ -356,41 +389,279 @@ func (branch *DataBranch) setValueString(safe bool, value []string) error {
 	if safe {
 		backup = Clone(branch)
 	}
-	if IsCreatedWithDefault(branch.schema) {
-		for _, s := range branch.schema.Children {
-			if !s.IsDir() && s.Default != "" {
-				if branch.Get(s.Name) != nil {
-					continue
-				}
-				var c DataNode
-				c, err = NewWithValueString(s)
-				if err != nil {
-					break
-				}
-				_, err = branch.insert(c, nil)
-				if err != nil {
-					break
-				}
-			}
+	for i := range value {
+		if value[i] == "" {
+			continue
+		}
+		if v := strings.TrimSpace(value[i]); !strings.HasPrefix(v, "{") && !strings.HasPrefix(v, "[") {
+			err = Errorf(EAppTagInvalidArg,
+				"branch node %s requires JSON/YAML value, but %q inserted", branch, value[i])
+			break
+		}
+		err = branch.UnmarshalJSON([]byte(value[i]))
+		if err != nil {
+			break
 		}
 	}
-	if err == nil {
-		for i := range value {
-			if value[i] == "" {
-				continue
+	if err == nil && IsCreatedWithDefault(branch.schema) {
+		err = branch.setDefaults()
+	}
+	if err != nil {
+		if safe {
+			recover(branch, backup)
+		}
+		return err
+	}
+	return nil
+}
+
+// setDefaults() creates the child nodes having the default values if they don't exist.
+// The default child nodes conditioned by when statements are only created if the
+// conditions are satisfied and are removed if not. The when statements of the choice
+// and case are evaluated in the context of the branch and the when statement of the
+// default child node itself is evaluated in the context of the child node.
+func (branch *DataBranch) setDefaults() error {
+	var conditional []*SchemaNode
+	for _, s := range branch.schema.Children {
+		if s.IsDir() || s.Default == "" {
+			continue
+		}
+		if !branch.isCaseSelected(s) {
+			// the default node of the unselected case must not be present.
+			if err := branch.unsetDefault(s); err != nil {
+				return err
 			}
-			err = branch.UnmarshalJSON([]byte(value[i]))
+			continue
+		}
+		if s.hasWhen() {
+			conditional = append(conditional, s)
+			continue
+		}
+		if branch.Get(s.Name) != nil {
+			continue
+		}
+		c, err := NewWithValueString(s)
+		if err != nil {
+			return err
+		}
+		if _, err := branch.insert(c, nil); err != nil {
+			return err
+		}
+	}
+	// The conditional defaults are set after all other defaults are set
+	// because their conditions may refer to them.
+	for _, s := range conditional {
+		if err := branch.setConditionalDefault(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (branch *DataBranch) setConditionalDefault(schema *SchemaNode) error {
+	for _, when := range schema.getCaseWhenXPath() {
+		ok, err := evaluatePathExpr(branch, when)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return branch.unsetDefault(schema)
+		}
+	}
+	when, ok := schema.GetWhenXPath()
+	if child := branch.Get(schema.Name); child != nil {
+		if ok {
+			ok, err := evaluatePathExpr(child, when)
 			if err != nil {
-				break
+				return err
+			}
+			if !ok {
+				return branch.unsetDefault(schema)
 			}
 		}
+		return nil
 	}
+	child, err := NewWithValueString(schema)
 	if err != nil {
-		if safe {
-			recover(branch, backup)
+		return err
+	}
+	if _, err := branch.insert(child, nil); err != nil {
+		return err
+	}
+	if ok {
+		ok, err := evaluatePathExpr(child, when)
+		if err != nil {
+			if derr := branch.Delete(child); derr != nil {
+				return derr
+			}
+			return err
+		}
+		if !ok {
+			return branch.Delete(child)
+		}
+	}
+	return nil
+}
+
+// absentDefaults() returns the new default leaf nodes that are absent in the branch.
+// The default nodes refer to the branch as their parent, but they are not inserted to
+// the branch. So the branch is kept sparse.
+func (branch *DataBranch) absentDefaults() ([]DataNode, error) {
+	var defaults, conditional []DataNode
+	for _, s := range branch.schema.Children {
+		if s.IsDir() || s.Default == "" || !branch.isCaseSelected(s) {
+			continue
 		}
+		if i, max := indexRangeBySchema(branch, s); i < max {
+			continue
+		}
+		c, err := NewWithValueString(s, s.Default)
+		if err != nil {
+			return nil, err
+		}
+		id := s.Name
+		setParent(c, branch, &id)
+		if s.hasWhen() {
+			conditional = append(conditional, c)
+			continue
+		}
+		defaults = append(defaults, c)
+	}
+	sortByID(defaults)
+	if len(conditional) == 0 {
+		return defaults, nil
+	}
+	// The when conditions of the conditional defaults are evaluated on a temporal branch
+	// having all other defaults because the conditions may refer to them.
+	temp := &DataBranch{
+		schema:   branch.schema,
+		parent:   branch.parent,
+		children: mergeByID(branch.children, defaults),
+	}
+	for _, c := range conditional {
+		id := c.ID()
+		setParent(c, temp, &id)
+		enabled := isDefaultEnabled(temp, c)
+		setParent(c, branch, &id)
+		if enabled {
+			defaults = append(defaults, c)
+		}
+	}
+	sortByID(defaults)
+	return defaults, nil
+}
+
+// isDefaultEnabled() returns true if the when conditions of the default node are satisfied.
+func isDefaultEnabled(branch *DataBranch, c DataNode) bool {
+	schema := c.Schema()
+	for _, when := range schema.getCaseWhenXPath() {
+		if ok, err := evaluatePathExpr(branch, when); err != nil || !ok {
+			return false
+		}
+	}
+	if when, ok := schema.GetWhenXPath(); ok {
+		if ok, err := evaluatePathExpr(c, when); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func sortByID(nodes []DataNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID() < nodes[j].ID() })
+}
+
+// mergeByID() returns a new node list merging the sorted children and the sorted nodes
+// not present in the children.
+func mergeByID(children, nodes []DataNode) []DataNode {
+	merged := make([]DataNode, 0, len(children)+len(nodes))
+	i := 0
+	for _, n := range nodes {
+		id := n.ID()
+		for ; i < len(children) && children[i].ID() < id; i++ {
+			merged = append(merged, children[i])
+		}
+		merged = append(merged, n)
+	}
+	return append(merged, children[i:]...)
+}
+
+// childrenWithDefaults() returns the children of the node including the absent default leaf nodes
+// if withDefaults is set.
+func childrenWithDefaults(node DataNode, withDefaults bool) ([]DataNode, error) {
+	branch, ok := node.(*DataBranch)
+	if !withDefaults || !ok {
+		return node.Children(), nil
+	}
+	defaults, err := branch.absentDefaults()
+	if err != nil || len(defaults) == 0 {
+		return branch.children, err
+	}
+	return mergeByID(branch.children, defaults), nil
+}
+
+// isCaseSelected() returns false if the schema node is placed in a case not selected
+// in the choice having the default case. The case having the data nodes except default
+// nodes is selected and the default case is selected if no case has the data nodes.
+func (branch *DataBranch) isCaseSelected(schema *SchemaNode) bool {
+	for s := schema; s.Choice != nil; s = s.Choice {
+		if s.Choice.Default != "" && branch.selectedCase(s.Choice) != s.Case {
+			return false
+		}
+	}
+	return true
+}
+
+// selectedCase() returns the case of the choice having the data nodes except default nodes.
+// The cases are checked in name order and the default case is returned if no case has
+// the data nodes. nil is returned if no case is selected.
+func (branch *DataBranch) selectedCase(choice *SchemaNode) *SchemaNode {
+	var dcase *SchemaNode
+	for _, c := range choice.cases() {
+		if c.Name == choice.Default {
+			dcase = c
+			continue
+		}
+		if branch.hasCaseData(c) {
+			return c
+		}
+	}
+	return dcase
+}
+
+// hasCaseData() returns true if the branch has the data nodes placed in the case schema node
+// except the data nodes having the default value.
+func (branch *DataBranch) hasCaseData(c *SchemaNode) bool {
+	for _, s := range branch.schema.Children {
+		if s.IsChoice() || s.IsCase() || !s.isPlacedIn(c) {
+			continue
+		}
+		i, max := indexRangeBySchema(branch, s)
+		for ; i < max; i++ {
+			if s.Default == "" || !s.IsLeaf() {
+				return true
+			}
+			if d, err := NewWithValueString(s); err == nil && !Equal(branch.children[i], d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unsetDefault() removes the child node of the schema if it has the default value.
+func (branch *DataBranch) unsetDefault(schema *SchemaNode) error {
+	child := branch.Get(schema.Name)
+	if child == nil {
+		return nil
+	}
+	d, err := NewWithValueString(schema)
+	if err != nil {
 		return err
 	}
+	if Equal(child, d) {
+		return branch.Delete(child)
+	}
 	return nil
 }
 
@@ -406,7 +677,21 @@ func (branch *DataBranch) UnsetValueString(value ...string) error {
 	return Errorf(ETagOperationNotSupported, "branch data node doesn't support unset")
 }
 
+// Remove() removes the branch from its parent. The branch and its descendants are released
+// to the pool if YANGTreeOption.UsePool is enabled, so they must not be used after removal.
 func (branch *DataBranch) Remove() error {
+	if branch.parent == nil {
+		return nil
+	}
+	if err := removeNode(branch); err != nil {
+		return err
+	}
+	releaseNode(branch)
+	return nil
+}
+
+// detach() removes the branch from its parent without releasing it to the pool.
+func (branch *DataBranch) detach() error {
 	if branch.parent == nil {
 		return nil
 	}
@@ -439,6 +724,122 @@ func (branch *DataBranch) Insert(child DataNode, insert InsertOption) (DataNode,
 	return branch.insert(child, insert)
 }
 
+// setCreatedDefaults() sets the defaults of the created nodes and their descendants again
+// after they are inserted to their parent. The when conditions of the defaults may refer to
+// the ancestors that are not reachable while the created nodes are unmarshalled.
+func setCreatedDefaults(created []DataNode) error {
+	for i := range created {
+		branch, ok := created[i].(*DataBranch)
+		if !ok {
+			continue
+		}
+		if IsCreatedWithDefault(branch.schema) {
+			if err := branch.setDefaults(); err != nil {
+				return err
+			}
+		}
+		if err := setCreatedDefaults(branch.children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BulkInsert() inserts a large number of children to the branch node at once.
+// Unlike Insert() that searches and shifts the children for each child, it appends
+// all children and then sorts them once. The children are placed in the same order
+// as they are inserted one by one without the insert option. The ordered-by user
+// children are inserted one by one to keep the insertion order.
+func (branch *DataBranch) BulkInsert(children []DataNode) error {
+	type pendingNode struct {
+		id   string
+		node DataNode
+	}
+	pending := make([]pendingNode, 0, len(children))
+	for _, child := range children {
+		if !IsValid(child) {
+			return fmt.Errorf("invalid child data node")
+		}
+		schema := child.Schema()
+		if !branch.schema.IsAnyData() && !branch.schema.ContainAny {
+			if branch.schema != schema.Parent {
+				return fmt.Errorf("unable to insert %s because it is not a child of %s", child, branch)
+			}
+		}
+		if schema.IsOrderedByUser() {
+			if _, err := branch.insert(child, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if parent := child.Parent(); parent != nil {
+			if parent == branch {
+				continue
+			}
+			detach(child)
+		}
+		pending = append(pending, pendingNode{id: child.ID(), node: child})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].id < pending[j].id })
+	// the latter replaces the former having the same id if not duplicatable.
+	n := 0
+	for i := range pending {
+		if n > 0 && pending[n-1].id == pending[i].id && !pending[i].node.Schema().IsDuplicatable() {
+			pending[n-1] = pending[i]
+			continue
+		}
+		pending[n] = pending[i]
+		n++
+	}
+	pending = pending[:n]
+
+	// merge the sorted children
+	merged := make([]DataNode, 0, len(branch.children)+len(pending))
+	i := 0
+	for j := range pending {
+		for ; i < len(branch.children); i++ {
+			id := branch.children[i].ID()
+			if pending[j].id < id {
+				break
+			}
+			if pending[j].id == id && !pending[j].node.Schema().IsDuplicatable() {
+				resetParent(branch.children[i])
+				i++
+				break
+			}
+			merged = append(merged, branch.children[i])
+		}
+		merged = append(merged, pending[j].node)
+		setParent(pending[j].node, branch, &pending[j].id)
+	}
+	merged = append(merged, branch.children[i:]...)
+	branch.children = merged
+	for j := range pending {
+		updateModifyTime(pending[j].node)
+		markDirty(pending[j].node, true)
+	}
+	return nil
+}
+
+// Clear() removes all children of the branch except the key nodes of a list entry.
+func (branch *DataBranch) Clear() error {
+	children := make([]DataNode, 0, len(branch.schema.Keyname))
+	for _, child := range branch.children {
+		if child.Schema().IsKey && branch.parent != nil {
+			children = append(children, child)
+			continue
+		}
+		markRemoved(branch, child.Schema(), child.ID())
+		resetParent(child)
+	}
+	branch.children = children
+	updateModifyTime(branch)
+	return nil
+}
+
 func (branch *DataBranch) Delete(child DataNode) error {
 	if !IsValid(child) {
 		return fmt.Errorf("invalid child node")
@@ -459,6 +860,8 @@ func (branch *DataBranch) Delete(child DataNode) error {
 			if branch.children[i] == child {
 				branch.children = append(branch.children[:i], branch.children[i+1:]...)
 				resetParent(child)
+				updateModifyTime(branch)
+				markRemoved(branch, child.Schema(), id)
 				return nil
 			}
 		}
@@ -466,6 +869,144 @@ func (branch *DataBranch) Delete(child DataNode) error {
 	return fmt.Errorf("%s not found on %s", child, branch)
 }
 
+// MoveChild() moves the child identified by the id to the index within the contiguous range
+// of the child's schema. It is only allowed for the ordered-by user list and leaf-list nodes.
+func (branch *DataBranch) MoveChild(id string, toIndex int) error {
+	from := -1
+	for i := range branch.children {
+		if branch.children[i].ID() == id {
+			from = i
+			break
+		}
+	}
+	if from < 0 {
+		return Errorf(ETagDataMissing, "data node %s not found on %s", id, branch)
+	}
+	child := branch.children[from]
+	schema := child.Schema()
+	if !schema.IsListable() || !schema.IsOrderedByUser() {
+		return Errorf(ETagOperationNotSupported,
+			"unable to move %s because it is not an ordered-by user node", child)
+	}
+	i, max := indexRangeBySchema(branch, schema)
+	if toIndex < 0 || toIndex >= max-i {
+		return Errorf(EAppTagInvalidArg, "index %d out of range of %s", toIndex, schema.Name)
+	}
+	to := i + toIndex
+	if from < to {
+		copy(branch.children[from:to], branch.children[from+1:to+1])
+	} else {
+		copy(branch.children[to+1:from+1], branch.children[to:from])
+	}
+	branch.children[to] = child
+	if from != to {
+		markDirty(child, false)
+	}
+	return nil
+}
+
+// SortChildren() sorts the children of the branch by their ids within each range of
+// the same schema. It converts the ordered-by user nodes to the system order and
+// doesn't change the order of the ordered-by system nodes already sorted.
+// The duplicatable nodes having the same id are kept in their order.
+func (branch *DataBranch) SortChildren() {
+	for i := 0; i < len(branch.children); {
+		schema := branch.children[i].Schema()
+		max := i + 1
+		for ; max < len(branch.children); max++ {
+			if branch.children[max].Schema() != schema {
+				break
+			}
+		}
+		if schema.IsOrderedByUser() {
+			children := branch.children[i:max]
+			if !sort.SliceIsSorted(children, func(j, k int) bool { return children[j].ID() < children[k].ID() }) {
+				sort.SliceStable(children, func(j, k int) bool {
+					return children[j].ID() < children[k].ID()
+				})
+				for j := range children {
+					markDirty(children[j], false)
+				}
+			}
+		}
+		i = max
+	}
+}
+
+// Rekey() changes the key values of the list entry identified by the oldID to the newKeys
+// (key names and value strings) and relocates the entry to the new id with its children.
+// The position of the ordered-by user list entry is kept. It fails if an entry having
+// the new id already exists.
+func (branch *DataBranch) Rekey(oldID string, newKeys map[string]string) (DataNode, error) {
+	entry, ok := branch.Get(oldID).(*DataBranch)
+	if !ok {
+		return nil, Errorf(ETagDataMissing, "list entry %s not found on %s", oldID, branch)
+	}
+	if !entry.schema.IsListHasKey() {
+		return nil, Errorf(EAppTagInvalidArg, "%s is not a key list entry", entry)
+	}
+	keys := make(map[string]DataNode, len(newKeys))
+	for kname, kvalue := range newKeys {
+		kschema := entry.schema.GetSchema(kname)
+		if kschema == nil || !kschema.IsKey {
+			return nil, Errorf(EAppTagInvalidArg, "%s is not a key of %s", kname, entry)
+		}
+		key, err := NewWithValueString(kschema, kvalue)
+		if err != nil {
+			return nil, err
+		}
+		keys[kschema.Name] = key
+	}
+	pmap := make(map[string]interface{}, len(entry.schema.Keyname))
+	for _, kname := range entry.schema.Keyname {
+		if key, ok := keys[kname]; ok {
+			pmap[kname] = key.ValueString()
+		} else {
+			pmap[kname] = entry.GetValueString(kname)
+		}
+	}
+	oldID = entry.ID()
+	newID, _, _ := entry.schema.GenerateID(pmap)
+	if newID == oldID {
+		return entry, nil
+	}
+	if branch.Get(newID) != nil {
+		return nil, Errorf(ETagDataExists, "list entry %s already exists on %s", newID, branch)
+	}
+	index := -1
+	if entry.schema.IsOrderedByUser() {
+		i, max := indexRangeBySchema(branch, entry.schema)
+		for j := i; j < max; j++ {
+			if branch.children[j] == entry {
+				index = j - i
+				break
+			}
+		}
+	}
+	if err := entry.detach(); err != nil {
+		return nil, err
+	}
+	markRemoved(branch, entry.schema, oldID)
+	for _, key := range keys {
+		if _, err := entry.insert(key, nil); err != nil {
+			return nil, err
+		}
+	}
+	var iopt InsertOption
+	if index >= 0 {
+		iopt = InsertToLast{}
+	}
+	if _, err := branch.insert(entry, iopt); err != nil {
+		return nil, err
+	}
+	if index >= 0 {
+		if err := branch.MoveChild(newID, index); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
 // SetMetadata() sets a metadata. for example, the following last-modified is set to the node as a metadata.
 //   node.SetMetadata("last-modified", "2015-06-18T17:01:14+02:00")
 func (branch *DataBranch) SetMetadata(name string, value ...interface{}) error {
@@ -613,6 +1154,51 @@ func (branch *DataBranch) GetValueString(id string) string {
 	}
 }
 
+// GetAllPrefix() returns the children whose IDs start with the idPrefix.
+// The children are bounded to the schema of the first matched child,
+// so that the prefix of key values (e.g. "list[key=A") only returns the entries of the list.
+func (branch *DataBranch) GetAllPrefix(idPrefix string) []DataNode {
+	if idPrefix == "" {
+		return nil
+	}
+	name, rest := idPrefix, ""
+	if j := strings.IndexByte(idPrefix, '['); j >= 0 {
+		name, rest = idPrefix[:j], idPrefix[j:]
+	}
+	var schema *SchemaNode
+	i, max := 0, len(branch.children)
+	if cschema := branch.schema.GetSchema(name); cschema != nil {
+		// the namespace-qualified name is replaced to the schema name of the id.
+		schema = cschema
+		idPrefix = cschema.Name + rest
+		i, max = indexRangeBySchema(branch, cschema)
+	} else {
+		i = indexFirst(branch, &idPrefix)
+	}
+	var node []DataNode
+	for ; i < max; i++ {
+		child := branch.children[i]
+		if schema != nil && schema != child.Schema() {
+			break
+		}
+		if !strings.HasPrefix(child.ID(), idPrefix) {
+			if schema == nil {
+				break
+			}
+			continue
+		}
+		if schema == nil {
+			schema = child.Schema()
+		}
+		node = append(node, child)
+	}
+	return node
+}
+
+// Lookup() returns the children whose IDs start with the prefix.
+// If the schema name of the prefix indicates a child schema exactly (e.g. "name" or "name[key=v"),
+// only the children of the schema are returned. Otherwise, the children of multiple schemas
+// sharing the prefix can be returned.
 func (branch *DataBranch) Lookup(prefix string) []DataNode {
 	switch prefix {
 	case ".":
@@ -625,11 +1211,21 @@ func (branch *DataBranch) Lookup(prefix string) []DataNode {
 		return findNode(branch, []*PathNode{
 			&PathNode{Name: "...", Select: NodeSelectAll}}, false)
 	default:
-		i := indexFirst(branch, &prefix)
-		node := make([]DataNode, 0, len(branch.children)-i+1)
-		for max := i; max < len(branch.children); max++ {
-			if strings.HasPrefix(branch.children[max].ID(), prefix) {
-				node = append(node, branch.children[max])
+		i, max := 0, len(branch.children)
+		name := prefix
+		if j := strings.IndexByte(prefix, '['); j >= 0 {
+			name = prefix[:j]
+		}
+		if cschema := branch.schema.GetSchema(name); cschema != nil && cschema.Name == name {
+			// bound to the children of the schema
+			i, max = indexRangeBySchema(branch, cschema)
+		} else {
+			i = indexFirst(branch, &prefix)
+		}
+		node := make([]DataNode, 0, max-i)
+		for ; i < max; i++ {
+			if strings.HasPrefix(branch.children[i].ID(), prefix) {
+				node = append(node, branch.children[i])
 			}
 		}
 		if len(node) == 0 {
@@ -662,6 +1258,14 @@ func (branch *DataBranch) QName(rfc7951 bool) (string, bool) {
 	return branch.schema.GetQName(rfc7951)
 }
 
+func (branch *DataBranch) ModuleName() string {
+	return branch.schema.GetModuleName()
+}
+
+func (branch *DataBranch) Namespace() string {
+	return branch.schema.GetNamespace()
+}
+
 func (branch *DataBranch) ID() string {
 	if branch.parent != nil {
 		if branch.id == "" {
@@ -680,7 +1284,7 @@ func (branch *DataBranch) ID() string {
 				keybuffer.WriteString(`[`)
 				keybuffer.WriteString(keyname[i])
 				keybuffer.WriteString(`=`)
-				keybuffer.WriteString(branch.children[j].ValueString())
+				keybuffer.WriteString(escapeKeyValue(branch.children[j].ValueString()))
 				keybuffer.WriteString(`]`)
 			} else {
 				return keybuffer.String()
@@ -793,6 +1397,8 @@ func (branch *DataBranch) MarshalXML(e *xml.Encoder, start xml.StartElement) err
 	} else {
 		start = xml.StartElement{Name: xml.Name{Local: branch.schema.Name}}
 	}
+	metadata, _ := metadataXMLAttrs(branch.Metadata(), nil)
+	start.Attr = append(start.Attr, metadata...)
 	if err := e.EncodeToken(xml.Token(start)); err != nil {
 		return err
 	}
@@ -814,10 +1420,27 @@ func (branch *DataBranch) UnmarshalXML(d *xml.Decoder, start xml.StartElement) e
 	}
 
 	schema := branch.schema
+	// the new list entries are inserted at once to avoid sorting for each entry.
+	var created []DataNode
+	createdByID := map[string]DataNode{}
+	insertCreated := func() error {
+		if err := branch.BulkInsert(created); err != nil {
+			return err
+		}
+		return setCreatedDefaults(created)
+	}
+	// failed() inserts the list entries created before the error and returns the error
+	// combined with the insertion error.
+	failed := func(err error) error {
+		if ierr := insertCreated(); ierr != nil {
+			return MultipleError{err, ierr}
+		}
+		return err
+	}
 	for {
 		tok, err := d.Token()
 		if err != nil {
-			return err
+			return failed(err)
 		}
 		if tok == nil {
 			break
@@ -827,25 +1450,33 @@ func (branch *DataBranch) UnmarshalXML(d *xml.Decoder, start xml.StartElement) e
 			_, name := SplitQName(&(e.Name.Local))
 			cschema := schema.GetSchema(name)
 			if cschema == nil {
-				return fmt.Errorf("schema %s not found", e.Name.Local)
+				return failed(fmt.Errorf("schema %s not found", e.Name.Local))
 			}
 			child, err := newDataNode(cschema)
 			if err != nil {
-				return err
+				return failed(err)
 			}
 			if err := d.DecodeElement(child, &e); err != nil {
-				return err
+				return failed(err)
 			}
 			// fmt.Println("Branch", branch.ID(), "E", child.ID(), start.Attr)
-			curchild := branch.Get(child.ID())
+			id := child.ID()
+			bulk := cschema.IsList() && !cschema.IsDuplicatableList()
+			curchild := branch.Get(id)
+			if curchild == nil && bulk {
+				curchild = createdByID[id]
+			}
 			if curchild == nil {
-				if _, err := branch.insert(child, nil); err != nil {
-					return err
+				if bulk {
+					created = append(created, child)
+					createdByID[id] = child
+				} else if _, err := branch.insert(child, nil); err != nil {
+					return failed(err)
 				}
 				curchild = child
 			} else {
 				if err := curchild.Merge(child); err != nil {
-					return err
+					return failed(err)
 				}
 			}
 			for i := range e.Attr {
@@ -861,10 +1492,10 @@ func (branch *DataBranch) UnmarshalXML(d *xml.Decoder, start xml.StartElement) e
 				}
 			}
 		case xml.EndElement:
-			return nil
+			return insertCreated()
 		}
 	}
-	return nil
+	return insertCreated()
 }
 
 func (branch *DataBranch) MarshalYAML() (interface{}, error) {
@@ -39,7 +39,7 @@ func NewGroupWithValue(schema *SchemaNode, value ...interface{}) (*DataNodeGroup
 					if err != nil {
 						return nil, err
 					}
-					collector.insert(node, nil)
+					collector.insert(node, groupInsertOption(schema))
 				}
 				return &DataNodeGroup{
 					schema: schema,
@@ -52,7 +52,7 @@ func NewGroupWithValue(schema *SchemaNode, value ...interface{}) (*DataNodeGroup
 			if err != nil {
 				return nil, err
 			}
-			if _, err := collector.insert(node, nil); err != nil {
+			if _, err := collector.insert(node, groupInsertOption(schema)); err != nil {
 				return nil, err
 			}
 		}
@@ -98,6 +98,15 @@ func NewGroupWithValue(schema *SchemaNode, value ...interface{}) (*DataNodeGroup
 	}, nil
 }
 
+// groupInsertOption() returns the insert option used to collect the leaf-list nodes of a group.
+// The ordered-by user leaf-list nodes are collected in the order of the input values.
+func groupInsertOption(schema *SchemaNode) InsertOption {
+	if schema.IsOrderedByUser() {
+		return InsertToLast{}
+	}
+	return nil
+}
+
 // NewGroupWithValueString() creates a set of new data nodes (*DataNodeGroup) having the same schema.
 // To create a set of data nodes, the value must be encoded to a JSON object or a JSON array of the data.
 // It is useful to create multiple list or leaf-list nodes.
@@ -139,7 +148,7 @@ func NewGroupWithValueString(schema *SchemaNode, value ...string) (*DataNodeGrou
 			if err != nil {
 				return nil, err
 			}
-			if _, err := collector.insert(node, nil); err != nil {
+			if _, err := collector.insert(node, groupInsertOption(schema)); err != nil {
 				return nil, err
 			}
 		}
@@ -242,6 +251,7 @@ func (group *DataNodeGroup) Children() []DataNode {
 func (group *DataNodeGroup) String() string                    { return "group." + group.schema.Name }
 func (group *DataNodeGroup) Path() string                      { return "" }
 func (group *DataNodeGroup) PathTo(descendant DataNode) string { return "" }
+func (group *DataNodeGroup) Equal(other DataNode) bool         { return Equal(group, other) }
 func (group *DataNodeGroup) Value() interface{}                { return group.Values() }
 func (group *DataNodeGroup) Values() []interface{} {
 	if !group.schema.IsDir() {
@@ -434,6 +444,14 @@ func (group *DataNodeGroup) QName(rfc7951 bool) (string, bool) {
 	return group.schema.GetQName(rfc7951)
 }
 
+func (group *DataNodeGroup) ModuleName() string {
+	return group.schema.GetModuleName()
+}
+
+func (group *DataNodeGroup) Namespace() string {
+	return group.schema.GetNamespace()
+}
+
 func (group *DataNodeGroup) ID() string {
 	return group.schema.Name
 }
@@ -18,6 +18,7 @@ type DataLeaf struct {
 	value    interface{}
 	id       string
 	metadata map[string]DataNode
+	dirty    bool // set if the node is changed. (YANGTreeOption.TrackDirty)
 }
 
 func (leaf *DataLeaf) IsDataNode()              {}
@@ -60,6 +61,11 @@ func (leaf *DataLeaf) PathTo(descendant DataNode) string {
 	return ""
 }
 
+// Equal() returns true if the leaf is equal to the other data node.
+func (leaf *DataLeaf) Equal(other DataNode) bool {
+	return Equal(leaf, other)
+}
+
 func (leaf *DataLeaf) Value() interface{} {
 	if c, ok := leaf.value.(func(cur DataNode) interface{}); ok {
 		return c(leaf)
@@ -130,12 +136,18 @@ func (leaf *DataLeaf) SetValue(value ...interface{}) error {
 			leaf.value = c
 			break
 		}
+		if b, ok := value[i].([]byte); ok && leaf.schema.IsAnyXML() {
+			// anyxml content is stored as an opaque string.
+			value[i] = string(b)
+		}
 		v, err := ValueToValidTypeValue(leaf.schema, leaf.schema.Type, value[i])
 		if err != nil {
 			return err
 		}
 		leaf.value = v
 	}
+	updateModifyTime(leaf)
+	markDirty(leaf, false)
 	return nil
 }
 
@@ -164,6 +176,8 @@ func (leaf *DataLeaf) unsetValue() error {
 	} else {
 		leaf.value = nil
 	}
+	updateModifyTime(leaf)
+	markDirty(leaf, false)
 	return nil
 }
 
@@ -192,6 +206,8 @@ func (leaf *DataLeaf) SetValueString(value ...string) error {
 		}
 		leaf.value = v
 	}
+	updateModifyTime(leaf)
+	markDirty(leaf, false)
 	return nil
 }
 
@@ -203,13 +219,16 @@ func (leaf *DataLeaf) UnsetValueString(value ...string) error {
 	return leaf.unsetValue()
 }
 
+// Remove() removes the leaf from its parent. The leaf is released to the pool
+// if YANGTreeOption.UsePool is enabled, so it must not be used after removal.
 func (leaf *DataLeaf) Remove() error {
 	if leaf.parent == nil {
 		return nil
 	}
-	if branch := leaf.parent; branch != nil {
-		return branch.Delete(leaf)
+	if err := leaf.parent.Delete(leaf); err != nil {
+		return err
 	}
+	releaseNode(leaf)
 	return nil
 }
 
@@ -326,15 +345,23 @@ func (leaf *DataLeaf) QName(rfc7951 bool) (string, bool) {
 	return leaf.schema.GetQName(rfc7951)
 }
 
+func (leaf *DataLeaf) ModuleName() string {
+	return leaf.schema.GetModuleName()
+}
+
+func (leaf *DataLeaf) Namespace() string {
+	return leaf.schema.GetNamespace()
+}
+
 func (leaf *DataLeaf) ID() string {
 	if leaf.id != "" {
 		return leaf.id
 	}
-	if leaf.schema.IsLeaf() {
+	if leaf.schema.IsLeaf() || leaf.schema.IsAnyXML() {
 		return leaf.schema.Name
 	}
 	// leaf-list id format: LEAF[.=VALUE]
-	return leaf.schema.Name + `[.=` + leaf.ValueString() + `]`
+	return leaf.schema.Name + `[.=` + escapeKeyValue(leaf.ValueString()) + `]`
 }
 
 // CreateByMap() updates the data node using pmap (path predicate map) and string values.
@@ -414,6 +441,8 @@ func (leaf *DataLeaf) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	} else {
 		start = xml.StartElement{Name: xml.Name{Local: leaf.schema.Name}}
 	}
+	metadata, _ := metadataXMLAttrs(leaf.Metadata(), nil)
+	start.Attr = append(start.Attr, metadata...)
 	// if err := e.EncodeToken(xml.Comment(leaf.ID())); err != nil {
 	// 	return err
 	// }
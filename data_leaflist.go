@@ -18,6 +18,7 @@ type DataLeafList struct {
 	parent   *DataBranch
 	value    []interface{}
 	metadata map[string]DataNode
+	dirty    bool // set if the node is changed. (YANGTreeOption.TrackDirty)
 }
 
 func (leaflist *DataLeafList) IsDataNode()              {}
@@ -60,6 +61,11 @@ func (leaflist *DataLeafList) PathTo(descendant DataNode) string {
 	return ""
 }
 
+// Equal() returns true if the leaf-list is equal to the other data node.
+func (leaflist *DataLeafList) Equal(other DataNode) bool {
+	return Equal(leaflist, other)
+}
+
 func (leaflist *DataLeafList) Value() interface{} {
 	if len(leaflist.value) > 0 {
 		if c, ok := leaflist.value[0].(func(cur DataNode) interface{}); ok {
@@ -139,29 +145,33 @@ func (leaflist *DataLeafList) setValueString(safe bool, value []string) error {
 			}
 		} else {
 			var index int
+			v, err := ValueStringToValue(leaflist.schema, leaflist.schema.Type, value[i])
+			if err != nil {
+				if safe {
+					leaflist.value = backup
+				}
+				return err
+			}
 			if leaflist.schema.IsOrderedByUser() || leaflist.schema.IsState {
 				index = len(leaflist.value)
 			} else {
+				// compare the canonical value strings to avoid the duplicate values.
+				vstr := ValueToValueString(v)
 				index = sort.Search(len(leaflist.value),
 					func(j int) bool {
-						return ValueToValueString(leaflist.value[j]) >= value[i]
+						return ValueToValueString(leaflist.value[j]) >= vstr
 					})
-				if index < len(leaflist.value) && ValueToValueString(leaflist.value[index]) == value[i] {
+				if index < len(leaflist.value) && ValueToValueString(leaflist.value[index]) == vstr {
 					continue
 				}
 			}
-			v, err := ValueStringToValue(leaflist.schema, leaflist.schema.Type, value[i])
-			if err != nil {
-				if safe {
-					leaflist.value = backup
-				}
-				return err
-			}
 			leaflist.value = append(leaflist.value, nil)
 			copy(leaflist.value[index+1:], leaflist.value[index:])
 			leaflist.value[index] = v
 		}
 	}
+	updateModifyTime(leaflist)
+	markDirty(leaflist, false)
 	return nil
 }
 
@@ -180,6 +190,8 @@ func (leaflist *DataLeafList) setValue(safe bool, value []interface{}) error {
 	if len(value) == 1 {
 		if c, ok := value[0].(func(cur DataNode) interface{}); ok {
 			leaflist.value = []interface{}{c}
+			updateModifyTime(leaflist)
+			markDirty(leaflist, false)
 			return nil
 		}
 	}
@@ -208,7 +220,8 @@ func (leaflist *DataLeafList) setValue(safe bool, value []interface{}) error {
 			if leaflist.schema.IsOrderedByUser() || leaflist.schema.IsState {
 				index = len(leaflist.value)
 			} else {
-				v := ValueToValueString(value[i])
+				// compare the canonical value strings to avoid the duplicate values.
+				v := ValueToValueString(val)
 				index = sort.Search(len(leaflist.value),
 					func(j int) bool {
 						return ValueToValueString(leaflist.value[j]) >= v
@@ -222,6 +235,8 @@ func (leaflist *DataLeafList) setValue(safe bool, value []interface{}) error {
 			leaflist.value[index] = val
 		}
 	}
+	updateModifyTime(leaflist)
+	markDirty(leaflist, false)
 	return nil
 }
 
@@ -245,6 +260,8 @@ func (leaflist *DataLeafList) UnsetValue(value ...interface{}) error {
 	if len(leaflist.value) == 1 {
 		if _, ok := leaflist.value[0].(func(cur DataNode) interface{}); ok {
 			leaflist.value = nil
+			updateModifyTime(leaflist)
+			markDirty(leaflist, false)
 			return nil
 		}
 	}
@@ -259,6 +276,8 @@ func (leaflist *DataLeafList) UnsetValue(value ...interface{}) error {
 			leaflist.value = append(leaflist.value[:index], leaflist.value[index+1:]...)
 		}
 	}
+	updateModifyTime(leaflist)
+	markDirty(leaflist, false)
 	return nil
 }
 
@@ -288,16 +307,35 @@ func (leaflist *DataLeafList) UnsetValueString(value ...string) error {
 			leaflist.value = append(leaflist.value[:index], leaflist.value[index+1:]...)
 		}
 	}
+	updateModifyTime(leaflist)
+	markDirty(leaflist, false)
 	return nil
 }
 
+// Clear() removes all values of the leaf-list.
+func (leaflist *DataLeafList) Clear() error {
+	if leaflist.parent != nil {
+		if leaflist.schema.IsKey {
+			// ignore id update
+			return nil
+		}
+	}
+	leaflist.value = nil
+	updateModifyTime(leaflist)
+	markDirty(leaflist, false)
+	return nil
+}
+
+// Remove() removes the leaf-list from its parent. The leaf-list is released to the pool
+// if YANGTreeOption.UsePool is enabled, so it must not be used after removal.
 func (leaflist *DataLeafList) Remove() error {
 	if leaflist.parent == nil {
 		return nil
 	}
-	if branch := leaflist.parent; branch != nil {
-		return branch.Delete(leaflist)
+	if err := leaflist.parent.Delete(leaflist); err != nil {
+		return err
 	}
+	releaseNode(leaflist)
 	return nil
 }
 
@@ -404,6 +442,14 @@ func (leaflist *DataLeafList) QName(rfc7951 bool) (string, bool) {
 	return leaflist.schema.GetQName(rfc7951)
 }
 
+func (leaflist *DataLeafList) ModuleName() string {
+	return leaflist.schema.GetModuleName()
+}
+
+func (leaflist *DataLeafList) Namespace() string {
+	return leaflist.schema.GetNamespace()
+}
+
 func (leaflist *DataLeafList) ID() string {
 	return leaflist.schema.Name
 }
@@ -489,11 +535,18 @@ func (leaflist *DataLeafList) MarshalXML(e *xml.Encoder, start xml.StartElement)
 	} else {
 		start = xml.StartElement{Name: xml.Name{Local: leaflist.schema.Name}}
 	}
+	metadata, _ := metadataXMLAttrs(leaflist.Metadata(), nil)
+	start.Attr = append(start.Attr, metadata...)
 	// if err := e.EncodeToken(xml.Comment(leaflist.ID())); err != nil {
 	// 	return err
 	// }
+	transform := outputTransform(leaflist.schema)
 	for _, v := range leaflist.Values() {
-		if err := e.EncodeElement(ValueToValueString(v), start); err != nil {
+		vstr := ValueToValueString(v)
+		if transform != nil {
+			vstr = transform(vstr)
+		}
+		if err := e.EncodeElement(vstr, start); err != nil {
 			return err
 		}
 	}
@@ -3,6 +3,7 @@ package yangtree
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -263,3 +264,245 @@ func TestMultipleLeafList(t *testing.T) {
 		})
 	}
 }
+
+// Test deleting a leaf-list value using SetValue and SetValueString
+func TestDeleteLeafListValue(t *testing.T) {
+	for _, singleLeafList := range []bool{false, true} {
+		schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{SingleLeafList: singleLeafList})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := New(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := "/sample/container-val/leaf-list-val"
+		if err := SetValueString(root, path, nil, "first", "second", "third", "fourth"); err != nil {
+			t.Fatal(err)
+		}
+		tests := []struct {
+			op       EditOp
+			typed    bool
+			value    string
+			wantErr  bool
+			expected []string
+		}{
+			{op: EditDelete, value: "third", expected: []string{"first", "fourth", "second"}},
+			{op: EditDelete, value: "third", wantErr: true, expected: []string{"first", "fourth", "second"}},
+			{op: EditRemove, value: "third", expected: []string{"first", "fourth", "second"}},
+			{op: EditRemove, typed: true, value: "first", expected: []string{"fourth", "second"}},
+			{op: EditDelete, typed: true, value: "second", expected: []string{"fourth"}},
+		}
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("single-leaf-list=%v.%s.%s", singleLeafList, tt.op, tt.value), func(t *testing.T) {
+				if tt.typed {
+					err = SetValue(root, path, &EditOption{EditOp: tt.op}, tt.value)
+				} else {
+					err = SetValueString(root, path, &EditOption{EditOp: tt.op}, tt.value)
+				}
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("delete error = %v, wantErr = %v", err, tt.wantErr)
+				}
+				j, err := MarshalJSON(root, RFC7951Format{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if strings.Contains(string(j), `"`+tt.value+`"`) {
+					t.Errorf("deleted value %s remains: %s", tt.value, j)
+				}
+				for _, v := range tt.expected {
+					if !strings.Contains(string(j), `"`+v+`"`) {
+						t.Errorf("value %s must remain: %s", v, j)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{SingleLeafList: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/leaf-list-val", nil, "first", "second", "third"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := Find(root, "/sample/container-val/leaf-list-val")
+	if err != nil || len(node) != 1 {
+		t.Fatalf("leaf-list-val not found: %v", err)
+	}
+	leaflist, ok := node[0].(*DataLeafList)
+	if !ok {
+		t.Fatalf("unexpected leaf-list type %T", node[0])
+	}
+	if err := leaflist.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if leaflist.Len() != 0 || leaflist.Parent() == nil {
+		t.Errorf("leaf-list must be empty and remain in the tree: %v", leaflist.Values())
+	}
+
+	container := root.Get("sample").Get("container-val").(*DataBranch)
+	if err := container.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if container.Len() != 0 || leaflist.Parent() != nil {
+		t.Errorf("all children of container-val must be removed: %d remains", container.Len())
+	}
+
+	node, err = Find(root, "/sample/single-key-list[list-key=AAA]")
+	if err != nil || len(node) != 1 {
+		t.Fatalf("single-key-list not found: %v", err)
+	}
+	entry := node[0].(*DataBranch)
+	if err := entry.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Len() != 1 || entry.Get("list-key") == nil || entry.Get("country-code") != nil {
+		t.Errorf("only the key node must remain in the list entry: %v", entry.Children())
+	}
+	if entry.ID() != "single-key-list[list-key=AAA]" {
+		t.Errorf("unexpected list entry id: %s", entry.ID())
+	}
+}
+
+func TestLeafListValueOrder(t *testing.T) {
+	leafListValues := func(root DataNode, name string) []string {
+		var values []string
+		for _, n := range root.Children() {
+			if n.Name() != name {
+				continue
+			}
+			for _, v := range n.Values() {
+				values = append(values, ValueToValueString(v))
+			}
+		}
+		return values
+	}
+	type orderTest struct {
+		opt      *EditOption
+		value    []string
+		expected []string
+	}
+	for _, single := range []bool{false, true} {
+		schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{SingleLeafList: single})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := New(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests := []orderTest{
+			{value: []string{"c", "a", "b"}, expected: []string{"c", "a", "b"}},
+			{value: []string{"e", "d"}, expected: []string{"c", "a", "b", "e", "d"}},
+		}
+		if !single {
+			tests = append(tests,
+				orderTest{
+					opt:      &EditOption{EditOp: EditReplace},
+					value:    []string{"3", "1", "2"},
+					expected: []string{"3", "1", "2"},
+				},
+				orderTest{
+					opt:      &EditOption{InsertOption: InsertToFirst{}},
+					value:    []string{"y", "x"},
+					expected: []string{"y", "x", "3", "1", "2"},
+				},
+				orderTest{
+					opt:      &EditOption{InsertOption: InsertToAfter{Key: "[.=1]"}},
+					value:    []string{"q", "p"},
+					expected: []string{"y", "x", "3", "1", "q", "p", "2"},
+				})
+		}
+		for _, tt := range tests {
+			if err := SetValueString(root, "/single-leaf-list-rw-user", tt.opt, tt.value...); err != nil {
+				t.Fatalf("SetValueString(%v) error: %v", tt.value, err)
+			}
+			if got := leafListValues(root, "single-leaf-list-rw-user"); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("SingleLeafList=%v: SetValueString(%v) results in %v, want %v", single, tt.value, got, tt.expected)
+			}
+		}
+		if err := SetValueString(root, "/single-leaf-list-rw-system", nil, "c", "a", "b"); err != nil {
+			t.Fatal(err)
+		}
+		if got := leafListValues(root, "single-leaf-list-rw-system"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+			t.Errorf("SingleLeafList=%v: ordered-by system leaf-list must be sorted: %v", single, got)
+		}
+	}
+}
+
+func TestLeafListUniqueValues(t *testing.T) {
+	for _, single := range []bool{true, false} {
+		schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{SingleLeafList: single})
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := New(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := "/single-leaf-list-rw-system"
+		count := func(name string) {
+			t.Helper()
+			var values []string
+			nodes, err := Find(root, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range nodes {
+				switch n := nodes[i].(type) {
+				case *DataLeafList:
+					for _, v := range n.Values() {
+						values = append(values, ValueToValueString(v))
+					}
+				default:
+					values = append(values, n.ValueString())
+				}
+			}
+			if !reflect.DeepEqual(values, []string{"A"}) {
+				t.Errorf("[single=%v] %s: duplicate values stored %v", single, name, values)
+			}
+		}
+		if err := SetValueString(root, path, nil, "A"); err != nil {
+			t.Fatal(err)
+		}
+		count("set string")
+		if err := SetValueString(root, path, nil, "A"); err != nil {
+			t.Fatal(err)
+		}
+		count("set string again")
+		if err := SetValue(root, path, nil, "A"); err != nil {
+			t.Fatal(err)
+		}
+		count("set value")
+		src, err := NewWithValueString(schema.GetSchema("single-leaf-list-rw-system"), "A")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Merge(root, path, src); err != nil {
+			t.Fatal(err)
+		}
+		count("merge")
+		if err := UnmarshalJSON(root, []byte(`{"single-leaf-list-rw-system": ["A", "A"]}`)); err != nil {
+			t.Fatal(err)
+		}
+		count("json")
+		if err := UnmarshalYAML(root, []byte("single-leaf-list-rw-system: [A, A]")); err != nil {
+			t.Fatal(err)
+		}
+		count("yaml")
+	}
+}
@@ -1,12 +1,14 @@
 package yangtree
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/goccy/go-json"
 )
@@ -463,6 +465,82 @@ func TestDataNode(t *testing.T) {
 	}
 }
 
+func TestLookup(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample":{
+		"str-val":"abc",
+		"str-val-ext":"ext",
+		"single-key-list":[{"list-key":"AAA"},{"list-key":"AAB"},{"list-key":"BBB"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := root.Get("sample")
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "str-val", want: []string{"str-val"}},
+		{prefix: "str-val-ext", want: []string{"str-val-ext"}},
+		{prefix: "str-v", want: []string{"str-val", "str-val-ext"}},
+		{prefix: "single-key-list", want: []string{
+			"single-key-list[list-key=AAA]", "single-key-list[list-key=AAB]", "single-key-list[list-key=BBB]"}},
+		{prefix: "single-key-list[list-key=AA", want: []string{
+			"single-key-list[list-key=AAA]", "single-key-list[list-key=AAB]"}},
+		{prefix: "unknown", want: nil},
+	}
+	for _, tt := range tests {
+		nodes := sample.Lookup(tt.prefix)
+		var got []string
+		for i := range nodes {
+			got = append(got, nodes[i].ID())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestGetAllPrefix(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample":{
+		"str-val":"abc",
+		"str-val-ext":"ext",
+		"single-key-list":[{"list-key":"A1"},{"list-key":"A12"},{"list-key":"A24"},{"list-key":"B1"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := root.Get("sample").(*DataBranch)
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "single-key-list[list-key=A1", want: []string{
+			"single-key-list[list-key=A1]", "single-key-list[list-key=A12]"}},
+		{prefix: "sample:single-key-list[list-key=A", want: []string{
+			"single-key-list[list-key=A1]", "single-key-list[list-key=A12]", "single-key-list[list-key=A24]"}},
+		{prefix: "single-key-list[list-key=B", want: []string{"single-key-list[list-key=B1]"}},
+		{prefix: "single-key-list[list-key=C", want: nil},
+		{prefix: "str-v", want: []string{"str-val"}},
+		{prefix: "unknown", want: nil},
+	}
+	for _, tt := range tests {
+		nodes := sample.GetAllPrefix(tt.prefix)
+		var got []string
+		for i := range nodes {
+			got = append(got, nodes[i].ID())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetAllPrefix(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
 func TestComplexModel(t *testing.T) {
 	rootschema, err := Load(
 		[]string{
@@ -544,6 +622,168 @@ func TestComplexModel(t *testing.T) {
 	t.Log(string(j))
 }
 
+func TestResolve(t *testing.T) {
+	rootschema, err := Load(
+		[]string{
+			"testdata/sample/sample.yang",
+			"testdata/modules/choice-case-example.yang",
+			"testdata/modules/leafref-predicate.yang",
+		}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(rootschema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, value := range map[string]string{
+		"/servers/server[name=S1]/role": "backup",
+		"/servers/server[name=S2]/role": "primary",
+		"/primary-server":               "S2",
+	} {
+		if err := SetValueString(root, path, nil, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetValueString(root, "/choice-case-with-leafref/referenced", nil, "referenced.value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/choice-case-with-leafref/ptr", nil, "referenced.value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/str-val", nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/test-instance-identifier", nil, "/sample/str-val"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		target  string
+		wantErr bool
+	}{
+		{path: "/choice-case-with-leafref/ptr", target: "/choice-case-with-leafref/referenced"},
+		{path: "/sample/container-val/test-instance-identifier", target: "/sample/str-val"},
+		{path: "/primary-server", target: "/servers/server[name=S2]/name"},
+		{path: "/sample/str-val", wantErr: true},
+	}
+	for _, tt := range tests {
+		node, err := Find(root, tt.path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", tt.path, err)
+		}
+		target, err := Resolve(root, node[0])
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Resolve() error = %v, wantErr = %v", err, tt.wantErr)
+		}
+		if !tt.wantErr && target.Path() != tt.target {
+			t.Errorf("unexpected target %s of %s", target.Path(), tt.path)
+		}
+	}
+
+	// references to absent data nodes
+	if err := SetValueString(root, "/choice-case-with-leafref/ptr", nil, "unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/test-instance-identifier", nil, "/sample/empty-val"); err != nil {
+		t.Fatal(err)
+	}
+	// the backup server is not selected by the predicate of the leafref path.
+	if err := SetValueString(root, "/primary-server", nil, "S1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/choice-case-with-leafref/ptr", "/sample/container-val/test-instance-identifier", "/primary-server"} {
+		node, err := Find(root, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		if target, err := Resolve(root, node[0]); err == nil {
+			t.Errorf("unexpected target %s of %s", target.Path(), path)
+		}
+	}
+}
+
+func TestNewValidated(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cschema := schema.FindSchema("/sample/container-val")
+	if cschema == nil {
+		t.Fatal("container-val schema not found")
+	}
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: `{"test-must":2,"leaf-list-val":["a","b"]}`},
+		{value: `{"test-must":5,"leaf-list-val":["a","b"]}`, wantErr: true},
+		{value: `{"leaf-list-val":["a"]}`},
+	}
+	for _, tt := range tests {
+		node, err := NewValidated(cschema, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewValidated(%s) error = %v, wantErr = %v", tt.value, err, tt.wantErr)
+		}
+		if tt.wantErr && node != nil {
+			t.Errorf("invalid data node must not be returned for %s", tt.value)
+		}
+		if !tt.wantErr && node == nil {
+			t.Errorf("valid data node must be returned for %s", tt.value)
+		}
+	}
+}
+
+func TestCanSet(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=A]/uint32-range", nil, "100"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/leaf-list-val", nil, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	backup := Clone(root)
+	called := false
+	opt := &EditOption{Callback: func(op EditOp, old, new []DataNode) error {
+		called = true
+		return nil
+	}}
+	tests := []struct {
+		path    string
+		value   []string
+		wantErr bool
+	}{
+		{path: "/sample/single-key-list[list-key=A]/uint32-range", value: []string{"200"}},
+		{path: "/sample/single-key-list[list-key=A]/uint32-range", value: []string{"500"}, wantErr: true},
+		{path: "/sample/single-key-list[list-key=B]/uint32-range", value: []string{"0"}, wantErr: true},
+		{path: "/sample/container-val/test-must", value: []string{"2"}},
+		{path: "/sample/container-val/test-must", value: []string{"3"}, wantErr: true},
+		{path: "/sample/unknown", value: []string{"1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := CanSet(root, tt.path, opt, tt.value...); (err != nil) != tt.wantErr {
+			t.Errorf("CanSet(%s, %v) error = %v, wantErr %v", tt.path, tt.value, err, tt.wantErr)
+		}
+	}
+	if !Equal(root, backup) {
+		t.Errorf("CanSet() must not change the original tree")
+	}
+	if called {
+		t.Errorf("CanSet() must not invoke the callback")
+	}
+	node, err := Find(root, "/sample/single-key-list[list-key=A]/uint32-range")
+	if err != nil || len(node) != 1 || node[0].ValueString() != "100" {
+		t.Errorf("unexpected value after CanSet(): %v", node)
+	}
+}
+
 func TestCreatedWithDefault(t *testing.T) {
 	rootschema, err := Load(
 		[]string{
@@ -588,6 +828,178 @@ func TestCreatedWithDefault(t *testing.T) {
 	t.Log(string(j))
 }
 
+func TestConditionalDefault(t *testing.T) {
+	rootschema, err := Load(
+		[]string{
+			"testdata/modules/when-default.yang",
+		}, nil, nil, YANGTreeOption{CreatedWithDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "default case-a",
+			value:   "",
+			present: []string{"mode", "a-val"},
+			absent:  []string{"b-val", "b-extra"},
+		},
+		{
+			name:    "case-b selected",
+			value:   `{"mode":"b"}`,
+			present: []string{"mode", "b-val", "b-extra"},
+			absent:  []string{"a-val"},
+		},
+		{
+			name:    "explicit case-a",
+			value:   `{"mode":"a","a-val":30}`,
+			present: []string{"mode", "a-val"},
+			absent:  []string{"b-val", "b-extra"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := NewWithValueString(rootschema.GetSchema("settings"), tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.present {
+				if settings.Get(name) == nil {
+					t.Errorf("%s must be created with default", name)
+				}
+			}
+			for _, name := range tt.absent {
+				if settings.Get(name) != nil {
+					t.Errorf("%s must not be created in the inactive case", name)
+				}
+			}
+		})
+	}
+
+	// The when condition of the list entry default refers to the ancestor
+	// outside of the entry being unmarshalled.
+	entries := []struct {
+		name      string
+		unmarshal func(settings DataNode) error
+	}{
+		{
+			name: "json",
+			unmarshal: func(settings DataNode) error {
+				return UnmarshalJSON(settings, []byte(`{"entry":[{"name":"e1"}]}`))
+			},
+		},
+		{
+			name: "yaml",
+			unmarshal: func(settings DataNode) error {
+				return UnmarshalYAML(settings, []byte("entry:\n  - name: e1\n"))
+			},
+		},
+		{
+			name: "xml",
+			unmarshal: func(settings DataNode) error {
+				return UnmarshalXML(settings, []byte(`<settings xmlns="urn:when-default"><entry><name>e1</name></entry></settings>`))
+			},
+		},
+	}
+	for _, tt := range entries {
+		t.Run("list entry default in "+tt.name, func(t *testing.T) {
+			settings, err := NewWithValueString(rootschema.GetSchema("settings"), `{"mode":"b"}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.unmarshal(settings); err != nil {
+				t.Fatal(err)
+			}
+			found, err := Find(settings, "entry[name=e1]/b-weight")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != 1 {
+				t.Errorf("entry[name=e1]/b-weight must be created with default")
+			}
+		})
+	}
+
+	errschema, err := Load(
+		[]string{
+			"testdata/modules/when-default-error.yang",
+		}, nil, nil, YANGTreeOption{CreatedWithDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWithValueString(errschema.GetSchema("settings"), `{"mode":"b"}`); err == nil {
+		t.Errorf("the evaluation error of the when statement of the default must be returned")
+	}
+}
+
+func TestChoiceDefaultCase(t *testing.T) {
+	rootschema, err := Load(
+		[]string{
+			"testdata/modules/choice-default.yang",
+		}, nil, nil, YANGTreeOption{CreatedWithDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		present map[string]string
+		absent  []string
+	}{
+		{
+			name:    "default case",
+			value:   "",
+			present: map[string]string{"tcp-port": "80", "mtu": "1500"},
+			absent:  []string{"udp-port"},
+		},
+		{
+			name:    "explicit default case",
+			value:   `{"tcp-port":8080}`,
+			present: map[string]string{"tcp-port": "8080", "mtu": "1500"},
+			absent:  []string{"udp-port"},
+		},
+		{
+			name:    "non-default case",
+			value:   `{"udp-port":5353}`,
+			present: map[string]string{"udp-port": "5353", "mtu": "1500"},
+			absent:  []string{"tcp-port"},
+		},
+	}
+	// the choice and case must be rebuilt from the schema cache.
+	var buf bytes.Buffer
+	if err := SaveSchemaCache(rootschema, &buf); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := LoadSchemaCache(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, schema := range []*SchemaNode{rootschema, cached} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				transport, err := NewWithValueString(schema.GetSchema("transport"), tt.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for name, value := range tt.present {
+					if child := transport.Get(name); child == nil || child.ValueString() != value {
+						t.Errorf("%s must be present with %s: %v", name, value, child)
+					}
+				}
+				for _, name := range tt.absent {
+					if transport.Get(name) != nil {
+						t.Errorf("%s must not be created in the unselected case", name)
+					}
+				}
+			})
+		}
+	}
+}
+
 func TestReplace(t *testing.T) {
 	files := []string{
 		"../../YangModels/yang/standard/ietf/RFC/iana-if-type@2017-01-19.yang",
@@ -961,6 +1373,57 @@ non-key-list:
 	// fmt.Println(string(y))
 }
 
+func TestAnyXML(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/anyxml-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := schema.FindSchema("/message/content"); s == nil || !s.IsAnyXML() || s.IsAnyData() {
+		t.Fatalf("content must be an anyxml schema node")
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := `<data xmlns="urn:example"><a>1</a><b/></data>`
+	if err := SetValueString(root, "/message/content", nil, raw); err != nil {
+		t.Fatal(err)
+	}
+	node, err := Find(root, "/message/content")
+	if err != nil || len(node) != 1 {
+		t.Fatalf("content not found: %v", err)
+	}
+	if node[0].IsBranchNode() || len(node[0].Children()) != 0 {
+		t.Errorf("anyxml content must not be navigable")
+	}
+	if node[0].ValueString() != raw || node[0].ID() != "content" {
+		t.Errorf("unexpected anyxml content %s: %s", node[0].ID(), node[0].ValueString())
+	}
+	if found, _ := Find(root, "/message/content/data"); len(found) != 0 {
+		t.Errorf("anyxml content must not be navigable: %v", found)
+	}
+
+	// raw bytes
+	if err := SetValue(root, "/message/content", nil, []byte("<x>raw</x>")); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := FindValueString(root, "/message/content"); len(v) != 1 || v[0] != "<x>raw</x>" {
+		t.Errorf("unexpected anyxml content: %v", v)
+	}
+
+	j, err := MarshalJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root2, err := NewWithValueString(schema, string(j))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(root, root2) {
+		t.Errorf("anyxml content must be unmarshalled from %s", j)
+	}
+}
+
 func TestReadCallback(t *testing.T) {
 	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
 	if err != nil {
@@ -1002,3 +1465,2420 @@ func TestReadCallback(t *testing.T) {
 		t.Fatal("readcallbak operation is failed")
 	}
 }
+
+func TestMoveChild(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"A", "B", "C", "D"} {
+		err := SetValueString(root, "/sample/ordered-by-user-list[name="+name+"]", &EditOption{InsertOption: InsertToLast{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=X]", nil); err != nil {
+		t.Fatal(err)
+	}
+	sample := root.Get("sample").(*DataBranch)
+	order := func() []string {
+		var names []string
+		for _, c := range sample.Children() {
+			if c.Name() == "ordered-by-user-list" {
+				names = append(names, c.GetValueString("name"))
+			}
+		}
+		return names
+	}
+	if err := sample.MoveChild("ordered-by-user-list[name=D]", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := sample.MoveChild("ordered-by-user-list[name=A]", 3); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(); !reflect.DeepEqual(got, []string{"D", "B", "C", "A"}) {
+		t.Errorf("unexpected order after move: %v", got)
+	}
+	b, err := MarshalJSON(root, RFC7951Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `"ordered-by-user-list":[{"name":"D"},{"name":"B"},{"name":"C"},{"name":"A"}]`
+	if !strings.Contains(string(b), expected) {
+		t.Errorf("unexpected marshalled output: %s", b)
+	}
+	if err := sample.MoveChild("ordered-by-user-list[name=B]", 4); err == nil {
+		t.Errorf("moving to out of range index must fail")
+	}
+	if err := sample.MoveChild("single-key-list[list-key=X]", 0); err == nil {
+		t.Errorf("moving a system-ordered list entry must fail")
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample, _, err := root.(*DataBranch).GetOrNew("sample", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch := sample.(*DataBranch)
+
+	// create
+	node, created, err := branch.Ensure("str-val", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || node.ValueString() != "abc" || branch.Get("str-val") != node {
+		t.Errorf("Ensure() must create str-val with the value: %v, %v", node, created)
+	}
+	// update
+	node2, created, err := branch.Ensure("str-val", "def")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || node2 != node || node.ValueString() != "def" {
+		t.Errorf("Ensure() must update the value of str-val: %v, %v", node2, created)
+	}
+	// list entry
+	entry, created, err := branch.Ensure("single-key-list[list-key=A]", `{"country-code":"KR"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || entry.GetValueString("country-code") != "KR" {
+		t.Errorf("Ensure() must create the list entry with the value: %v, %v", entry, created)
+	}
+	// invalid value
+	if _, _, err := branch.Ensure("single-key-list[list-key=B]", `{"uint32-range":1000}`); err == nil {
+		t.Errorf("Ensure() must fail for an invalid value")
+	}
+	if branch.Get("single-key-list[list-key=B]") != nil {
+		t.Errorf("the created node must be removed if the value is invalid")
+	}
+	if _, _, err := branch.Ensure("str-val", "a", "b"); err == nil {
+		t.Errorf("Ensure() must fail for multiple values of a leaf")
+	}
+	if v := branch.Get("str-val").ValueString(); v != "def" {
+		t.Errorf("the value must not be updated if failed: %s", v)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"A", "B"} {
+		if err := SetValueString(root, "/sample/single-key-list[list-key="+key+"]/country-code", nil, "C-"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=A]/uint32-range", nil, "100"); err != nil {
+		t.Fatal(err)
+	}
+	sample := root.Get("sample").(*DataBranch)
+	if _, err := sample.Rekey("single-key-list[list-key=A]", map[string]string{"list-key": "B"}); err == nil {
+		t.Errorf("Rekey() must fail for the existing entry")
+	}
+	if _, err := sample.Rekey("single-key-list[list-key=A]", map[string]string{"country-code": "X"}); err == nil {
+		t.Errorf("Rekey() must fail for a non-key leaf")
+	}
+	if _, err := sample.Rekey("single-key-list[list-key=Z]", map[string]string{"list-key": "Y"}); err == nil {
+		t.Errorf("Rekey() must fail for a non-existent entry")
+	}
+	entry, err := sample.Rekey("single-key-list[list-key=A]", map[string]string{"list-key": "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.ID() != "single-key-list[list-key=C]" || entry.Parent() != sample {
+		t.Errorf("unexpected rekeyed entry %s", entry.Path())
+	}
+	if sample.Get("single-key-list[list-key=A]") != nil {
+		t.Errorf("the entry must not exist at the old key")
+	}
+	expected := map[string]string{
+		"/sample/single-key-list[list-key=C]/list-key":     "C",
+		"/sample/single-key-list[list-key=C]/country-code": "C-A",
+		"/sample/single-key-list[list-key=C]/uint32-range": "100",
+		"/sample/single-key-list[list-key=B]/country-code": "C-B",
+	}
+	for path, value := range expected {
+		if v, ok := GetValueStringAt(root, path); !ok || v != value {
+			t.Errorf("unexpected value of %s: %s", path, v)
+		}
+	}
+
+	// the position of the ordered-by user list entry is kept.
+	for _, name := range []string{"X", "Y", "Z"} {
+		err := SetValueString(root, "/sample/ordered-by-user-list[name="+name+"]/value", &EditOption{InsertOption: InsertToLast{}}, "v-"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := sample.Rekey("ordered-by-user-list[name=Y]", map[string]string{"name": "A"}); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range sample.Children() {
+		if c.Name() == "ordered-by-user-list" {
+			names = append(names, c.GetValueString("name"))
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"X", "A", "Z"}) {
+		t.Errorf("unexpected order after rekey: %v", names)
+	}
+	if v, ok := GetValueStringAt(sample, "ordered-by-user-list[name=A]/value"); !ok || v != "v-Y" {
+		t.Errorf("unexpected value of the rekeyed entry: %s", v)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"A", "B", "C"} {
+		if err := SetValueString(root, "/sample/single-key-list[list-key="+key+"]/country-code", nil, "C-"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sample := root.Get("sample").(*DataBranch)
+	// update the key leaf directly without Rekey().
+	sample.Get("single-key-list[list-key=A]").Get("list-key").(*DataLeaf).value = "D"
+	if sample.Get("single-key-list[list-key=D]") != nil {
+		t.Fatalf("the entry must not be found by the new key before reindexing")
+	}
+	if err := Reindex(root); err != nil {
+		t.Fatal(err)
+	}
+	entry := sample.Get("single-key-list[list-key=D]")
+	if entry == nil {
+		t.Fatalf("the entry must be found by the new key after reindexing")
+	}
+	if v := entry.GetValueString("country-code"); v != "C-A" {
+		t.Errorf("unexpected country-code of the reindexed entry: %s", v)
+	}
+	if sample.Get("single-key-list[list-key=A]") != nil {
+		t.Errorf("the entry must not exist at the old key")
+	}
+	for _, key := range []string{"B", "C"} {
+		if sample.Get("single-key-list[list-key="+key+"]") == nil {
+			t.Errorf("single-key-list[list-key=%s] not found after reindexing", key)
+		}
+	}
+
+	// duplicated keys can't be repaired.
+	sample.Get("single-key-list[list-key=B]").Get("list-key").(*DataLeaf).value = "C"
+	if err := Reindex(root); err == nil {
+		t.Errorf("Reindex() must fail for the duplicated list entries")
+	}
+	// the failed reindexing must not change the ids.
+	for _, key := range []string{"B", "C", "D"} {
+		if sample.Get("single-key-list[list-key="+key+"]") == nil {
+			t.Errorf("single-key-list[list-key=%s] must not be changed by the failed reindexing", key)
+		}
+	}
+
+	// the list entry having the invalid key can't be reindexed.
+	sample.Get("single-key-list[list-key=B]").Get("list-key").(*DataLeaf).value = "B"
+	sample.Get("single-key-list[list-key=C]").Get("list-key").(*DataLeaf).value = "*"
+	sample.Get("single-key-list[list-key=D]").Get("list-key").(*DataLeaf).value = "E"
+	if err := Reindex(root); err == nil {
+		t.Errorf("Reindex() must fail for the list entry having the invalid key")
+	}
+	for _, key := range []string{"B", "C", "D"} {
+		if sample.Get("single-key-list[list-key="+key+"]") == nil {
+			t.Errorf("single-key-list[list-key=%s] must not be changed by the failed reindexing", key)
+		}
+	}
+}
+
+func TestMergeOrderedByUser(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSample := func(names ...string) *DataBranch {
+		root, err := New(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			err := SetValueString(root, "/sample/ordered-by-user-list[name="+name+"]/value", &EditOption{InsertOption: InsertToLast{}}, "v-"+name)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return root.Get("sample").(*DataBranch)
+	}
+	order := func(sample *DataBranch) []string {
+		var names []string
+		for _, c := range sample.Children() {
+			if c.Name() == "ordered-by-user-list" {
+				names = append(names, c.GetValueString("name"))
+			}
+		}
+		return names
+	}
+	dest := newSample("C", "A")
+	src := newSample("D", "A", "B", "C")
+	if err := SetValueString(src, "ordered-by-user-list[name=C]/value", nil, "updated"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dest.Merge(src); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(dest); !reflect.DeepEqual(got, []string{"C", "A", "D", "B"}) {
+		t.Errorf("unexpected order after merge: %v", got)
+	}
+	if c := dest.Get("ordered-by-user-list[name=C]"); c == nil || c.GetValueString("value") != "updated" {
+		t.Errorf("unexpected merged entry: %v", c)
+	}
+
+	// merging into an empty list keeps the order of the src.
+	dest = newSample()
+	if err := dest.Merge(src); err != nil {
+		t.Fatal(err)
+	}
+	if got := order(dest); !reflect.DeepEqual(got, []string{"D", "A", "B", "C"}) {
+		t.Errorf("unexpected order after merge: %v", got)
+	}
+}
+
+func TestMergeMetadata(t *testing.T) {
+	yangfiles := []string{
+		"testdata/sample/sample.yang",
+		"testdata/modules/example-annotations.yang",
+	}
+	dir := []string{"../../openconfig/public/", "../../YangModels/yang"}
+	schema, err := Load(yangfiles, dir, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	newTree := func(metadata map[string]string) DataNode {
+		root, err := New(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{"/sample/container-val/a", "/sample/leaf-list-val"} {
+			if err := SetValueString(root, path, nil, "A"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for path, value := range metadata {
+			if err := SetValueString(root, path, nil, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return root
+	}
+	dest := newTree(map[string]string{
+		"/sample/container-val/@owner":   "dest",
+		"/sample/container-val/a/@tag":   "dest-tag",
+		"/sample/leaf-list-val/@comment": "dest-comment",
+	})
+	src := newTree(map[string]string{
+		"/sample/container-val/@owner":    "src",
+		"/sample/container-val/a/@origin": "src-origin",
+		"/sample/leaf-list-val/@tag":      "src-tag",
+	})
+	if err := SetValueString(src, "/sample/str-val", nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(src, "/sample/str-val/@comment", nil, "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dest.Merge(src); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[string]string{
+		"/sample/container-val":   {"owner": "src"},
+		"/sample/container-val/a": {"tag": "dest-tag", "origin": "src-origin"},
+		"/sample/leaf-list-val":   {"comment": "dest-comment", "tag": "src-tag"},
+		"/sample/str-val":         {"comment": "new"},
+	}
+	for path, metadata := range expected {
+		node, err := Find(dest, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		meta := node[0].Metadata()
+		if len(meta) != len(metadata) {
+			t.Errorf("unexpected metadata of %s: %v", path, meta)
+		}
+		for name, value := range metadata {
+			if meta[name] == nil || meta[name].ValueString() != value {
+				t.Errorf("unexpected metadata %s of %s: %v", name, path, meta[name])
+			}
+		}
+	}
+}
+
+func TestMergeChildrenInsertOption(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {"ordered-by-user-list": [{"name": "A"}, {"name": "Z"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged []DataNode
+	for _, v := range []struct{ path, value string }{
+		{path: "/sample/ordered-by-user-list", value: `{"name": "B"}`},
+		{path: "/sample/ordered-by-user-list", value: `{"name": "C"}`},
+		{path: "/sample/non-key-list", value: `{"uintval": 1}`},
+		{path: "/sample/single-key-list", value: `{"list-key": "AAA"}`},
+	} {
+		n, err := NewWithValueString(schema.FindSchema(v.path), v.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		merged = append(merged, n)
+	}
+	sample := root.Get("sample")
+	edit := &EditOption{InsertOption: InsertToAfter{Key: "[name=A]"}}
+	if _, _, err := mergeChildren(sample, merged, edit); err != nil {
+		t.Fatalf("the insert option must not be applied to the other schema: %v", err)
+	}
+	var names []string
+	for _, c := range sample.GetAll("ordered-by-user-list") {
+		names = append(names, c.GetValueString("name"))
+	}
+	if !reflect.DeepEqual(names, []string{"A", "B", "C", "Z"}) {
+		t.Errorf("unexpected order after merge: %v", names)
+	}
+	if sample.Get("single-key-list[list-key=AAA]") == nil || len(sample.GetAll("non-key-list")) != 1 {
+		t.Errorf("the children of the other schema must be merged")
+	}
+}
+
+func TestCollectMetadata(t *testing.T) {
+	yangfiles := []string{
+		"testdata/sample/sample.yang",
+		"testdata/modules/example-last-modified.yang",
+	}
+	dir := []string{"../../openconfig/public/", "../../YangModels/yang"}
+	schema, err := Load(yangfiles, dir, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/str-val", nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]string{
+		"/sample":                 "2015-06-18T17:01:14+02:01",
+		"/sample/container-val/a": "2015-06-18T17:01:14+02:02",
+		"/sample/str-val":         "2015-06-18T17:01:14+02:03",
+	}
+	for path, value := range metadata {
+		if err := SetValueString(root, path+"/@last-modified", nil, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	collected := CollectMetadata(root)
+	if len(collected) != len(metadata) {
+		t.Errorf("unexpected number of collected metadata: %v", collected)
+	}
+	for path, value := range metadata {
+		meta, ok := collected[path]
+		if !ok {
+			t.Errorf("metadata of %s not collected", path)
+			continue
+		}
+		if meta["last-modified"] == nil || meta["last-modified"].ValueString() != value {
+			t.Errorf("unexpected metadata of %s: %v", path, meta)
+		}
+	}
+}
+
+func TestDepth(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "A"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		depth int
+	}{
+		{path: "/sample", depth: 1},
+		{path: "/sample/container-val", depth: 2},
+		{path: "/sample/container-val/a", depth: 3},
+		{path: "/sample/single-key-list[list-key=AAA]/country-code", depth: 3},
+	}
+	if d := Depth(root); d != 0 {
+		t.Errorf("unexpected depth of the root: %d", d)
+	}
+	for _, tt := range tests {
+		node, err := Find(root, tt.path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", tt.path, err)
+		}
+		if d := Depth(node[0]); d != tt.depth {
+			t.Errorf("unexpected depth of %s: got %d, want %d", tt.path, d, tt.depth)
+		}
+	}
+}
+
+func TestGetValueStringAt(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"A", "B"} {
+		if err := SetValueString(root, "/sample/single-key-list[list-key="+key+"]/country-code", nil, "C-"+key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sample := root.Get("sample")
+	tests := []struct {
+		node  DataNode
+		path  string
+		value string
+		ok    bool
+	}{
+		{node: root, path: "/sample/single-key-list[list-key=A]/country-code", value: "C-A", ok: true},
+		{node: sample, path: "single-key-list[list-key=B]/country-code", value: "C-B", ok: true},
+		{node: sample, path: "single-key-list[list-key=B]/list-key", value: "B", ok: true},
+		{node: root, path: "/sample/single-key-list/country-code"},             // multiple matches
+		{node: root, path: "/sample/single-key-list[list-key=A]"},              // branch node
+		{node: root, path: "/sample/single-key-list[list-key=C]/country-code"}, // not found
+		{node: root, path: "/sample/single-key-list[list-key=A"},               // invalid path
+	}
+	for _, tt := range tests {
+		value, ok := GetValueStringAt(tt.node, tt.path)
+		if ok != tt.ok || value != tt.value {
+			t.Errorf("GetValueStringAt(%s) = (%q, %v), want (%q, %v)", tt.path, value, ok, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestFindRelativeRoot(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "A"); err != nil {
+		t.Fatal(err)
+	}
+	cv := root.Get("sample").Get("container-val")
+	tests := []struct {
+		path   string
+		option []Option
+		want   []string
+	}{
+		{path: "/sample/container-val/a", want: []string{"/sample/container-val/a"}},
+		{path: "/sample/container-val/a", option: []Option{RelativeRoot{}}, want: nil},
+		{path: "/a", want: nil},
+		{path: "/a", option: []Option{RelativeRoot{}}, want: []string{"/sample/container-val/a"}},
+		{path: "/", option: []Option{RelativeRoot{}}, want: []string{"/sample/container-val"}},
+		{path: "a", option: []Option{RelativeRoot{}}, want: []string{"/sample/container-val/a"}},
+	}
+	for _, tt := range tests {
+		node, err := Find(cv, tt.path, tt.option...)
+		if err != nil {
+			t.Fatalf("Find(%s) error = %v", tt.path, err)
+		}
+		var got []string
+		for i := range node {
+			got = append(got, node[i].Path())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Find(%s, %v) = %v, want %v", tt.path, tt.option, got, tt.want)
+		}
+	}
+}
+
+func TestAncestorByName(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+	node, err := Find(root, "/sample/single-key-list[list-key=AAA]/country-code")
+	if err != nil || len(node) != 1 {
+		t.Fatalf("country-code not found: %v", err)
+	}
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "single-key-list", path: "/sample/single-key-list[list-key=AAA]"},
+		{name: "sample", path: "/sample"},
+		{name: "container-val", path: ""},
+		{name: "country-code", path: ""},
+	}
+	for _, tt := range tests {
+		ancestor := AncestorByName(node[0], tt.name)
+		switch {
+		case tt.path == "" && ancestor != nil:
+			t.Errorf("unexpected ancestor %s found", ancestor.Path())
+		case tt.path != "" && ancestor == nil:
+			t.Errorf("ancestor %s not found", tt.name)
+		case tt.path != "" && ancestor.Path() != tt.path:
+			t.Errorf("unexpected ancestor %s found", ancestor.Path())
+		}
+	}
+	if AncestorByName(nil, "sample") != nil {
+		t.Errorf("no ancestor must be found for nil")
+	}
+}
+
+func TestPresenceContainer(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/presence-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := schema.FindSchema("/system/tls"); s == nil || !s.IsPresence() {
+		t.Errorf("tls must be a presence container")
+	}
+	if s := schema.FindSchema("/system/logging"); s == nil || s.IsPresence() {
+		t.Errorf("logging must be a non-presence container")
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/system/logging", nil); err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) > 0 {
+		t.Errorf("non-presence container must not be required: %v", errs)
+	}
+	if err := SetValueString(root, "/system/tls", nil); err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) == 0 {
+		t.Errorf("mandatory cert of the present tls container must be required")
+	}
+	b, err := MarshalJSON(root, SkipEmpty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"system":{"tls":{}}}` {
+		t.Errorf("unexpected json output with SkipEmpty: %s", b)
+	}
+	b, err = MarshalJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"system":{"logging":{},"tls":{}}}` {
+		t.Errorf("unexpected json output: %s", b)
+	}
+	if err := SetValueString(root, "/system/tls/cert", nil, "server.crt"); err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) > 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+}
+
+func TestValidateMandatoryChoice(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/mandatory-choice.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		value  string
+		errors []string
+	}{
+		{path: "", errors: []string{"mandatory choice transport not present in /service/"}},
+		{path: "/service/tcp-keepalive", value: "true", errors: []string{"mandatory node /service/tcp-port not present"}},
+		{path: "/service/tcp-port", value: "80"},
+		{path: "/service/type", value: "secure", errors: []string{"mandatory node /service/secret not present"}},
+		{path: "/service/secret", value: "pass"},
+	}
+	for _, tt := range tests {
+		if tt.path != "" {
+			if err := SetValueString(root, tt.path, nil, tt.value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		errs := Validate(root)
+		if len(errs) != len(tt.errors) {
+			t.Errorf("unexpected validation errors after setting %s: %v", tt.path, errs)
+			continue
+		}
+		for i := range errs {
+			if !strings.Contains(errs[i].Error(), tt.errors[i]) {
+				t.Errorf("expected error %q, got %q", tt.errors[i], errs[i])
+			}
+		}
+	}
+}
+
+func TestWildcardDelete(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"/sample/single-key-list[list-key=A]/country-code",
+		"/sample/single-key-list[list-key=B]/country-code",
+		"/sample/single-key-list[list-key=C]/country-code",
+		"/sample/str-val",
+		"/sample/container-val/a",
+	} {
+		if err := SetValueString(root, path, nil, "KR"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var removed []DataNode
+	callback := func(op EditOp, old, new []DataNode) error {
+		if len(old) != 1 {
+			t.Errorf("callback must be called once per removed node: %v", old)
+		}
+		removed = append(removed, old...)
+		return nil
+	}
+	err = SetValueString(root, "/sample/single-key-list[list-key=*]", &EditOption{EditOp: EditRemove, Callback: callback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("unexpected number of removed nodes: %d", len(removed))
+	}
+	if node, _ := Find(root, "/sample/single-key-list"); len(node) != 0 {
+		t.Errorf("all entries of single-key-list must be removed: %v", node)
+	}
+	removed = nil
+	err = SetValueString(root, "/sample/*", &EditOption{EditOp: EditDelete, Callback: callback})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("unexpected number of removed nodes: %d", len(removed))
+	}
+	if n := root.Get("sample").Len(); n != 0 {
+		t.Errorf("all children of sample must be removed: %d remains", n)
+	}
+
+	for _, path := range []string{
+		"/sample/single-key-list[list-key=A]/country-code",
+		"/sample/single-key-list[list-key=B]/country-code",
+	} {
+		if err := SetValueString(root, path, nil, "KR"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Delete(root, "/sample/single-key-list[list-key=*]"); err != nil {
+		t.Fatal(err)
+	}
+	if n := root.Get("sample").Len(); n != 0 {
+		t.Errorf("all entries of single-key-list must be deleted: %d remains", n)
+	}
+}
+
+func TestSetDataNode(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listSchema := schema.FindSchema("/sample/single-key-list")
+	newEntry := func(jstr string) DataNode {
+		entry, err := NewWithValueString(listSchema, jstr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entry
+	}
+	path := "/sample/single-key-list[list-key=AAA]"
+	var updated int
+	callback := func(op EditOp, old, new []DataNode) error {
+		updated += len(new)
+		return nil
+	}
+	// create
+	opt := &EditOption{EditOp: EditCreate, Callback: callback}
+	if err := SetValue(root, path, opt, newEntry(`{"country-code":"KR","uint64-node":10}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(root, path, opt, newEntry(`{"country-code":"US"}`)); err == nil {
+		t.Errorf("create must fail if the data node exists")
+	}
+	// merge
+	opt = &EditOption{EditOp: EditMerge, Callback: callback}
+	if err := SetValue(root, path, opt, newEntry(`{"country-code":"US"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := FindValueString(root, path+"/country-code"); len(v) != 1 || v[0] != "US" {
+		t.Errorf("unexpected country-code after merge: %v", v)
+	}
+	if v, _ := FindValueString(root, path+"/uint64-node"); len(v) != 1 || v[0] != "10" {
+		t.Errorf("uint64-node must remain after merge: %v", v)
+	}
+	// replace
+	opt = &EditOption{EditOp: EditReplace, Callback: callback}
+	if err := SetValue(root, path, opt, newEntry(`{"country-code":"JP"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := FindValueString(root, path+"/country-code"); len(v) != 1 || v[0] != "JP" {
+		t.Errorf("unexpected country-code after replace: %v", v)
+	}
+	if v, _ := FindValueString(root, path+"/uint64-node"); len(v) != 0 {
+		t.Errorf("uint64-node must be removed after replace: %v", v)
+	}
+	if v, _ := FindValueString(root, path+"/list-key"); len(v) != 1 || v[0] != "AAA" {
+		t.Errorf("unexpected list-key: %v", v)
+	}
+	if updated != 3 {
+		t.Errorf("unexpected number of callbacks: %d", updated)
+	}
+	if n := root.Get("sample").Len(); n != 1 {
+		t.Errorf("unexpected number of list entries: %d", n)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(RootSchema, `{
+		"sample": {
+			"length-val": "ab",
+			"single-key-list": {"A": {"list-key": "A", "uint32-range": 100}},
+			"container-val": {"enum-val": "enum1", "leaf-list-val": ["x"], "test-must": 1}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateAll(root); len(errs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", errs)
+	}
+
+	// make several independent violations in the tree.
+	for path, value := range map[string]interface{}{
+		"/sample/length-val": "abcdefg",
+		"/sample/single-key-list[list-key=A]/uint32-range": uint32(1000),
+		"/sample/container-val/enum-val":                   "enum9",
+	} {
+		node, err := Find(root, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		node[0].(*DataLeaf).value = value
+	}
+	if err := SetValueString(root, "/sample/container-val/test-must", nil, "5"); err != nil {
+		t.Fatal(err)
+	}
+	errs := ValidateAll(root)
+	if len(errs) != 4 {
+		t.Errorf("4 validation errors expected, but got %d: %v", len(errs), errs)
+	}
+	for _, keyword := range []string{"length-val", "uint32-range", "enum-val", "count(../leaf-list-val)"} {
+		found := false
+		for i := range errs {
+			if strings.Contains(errs[i].Error(), keyword) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("violation of %s not reported: %v", keyword, errs)
+		}
+	}
+}
+
+func TestEqualMethod(t *testing.T) {
+	for _, option := range [][]Option{nil, {YANGTreeOption{SingleLeafList: true}}} {
+		schema, err := Load([]string{"testdata/sample"}, nil, nil, option...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root1, err := NewWithValueString(schema, `{
+			"sample": {"str-val": "abc", "container-val": {"a": "A"}},
+			"single-leaf-list-rw-user": ["x", "y"]
+		}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root2 := Clone(root1)
+		if err := SetValueString(root2, "/sample/container-val/a", nil, "B"); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetValueString(root2, "/single-leaf-list-rw-user", nil, "z"); err != nil {
+			t.Fatal(err)
+		}
+		nodes := func(root DataNode) []DataNode {
+			return []DataNode{
+				root,
+				root.Get("sample"),
+				root.Get("sample").Get("str-val"),
+				root.Get("sample").Get("container-val").Get("a"),
+				root.Get("single-leaf-list-rw-user"),
+			}
+		}
+		n1, n2, n3 := nodes(root1), nodes(root2), nodes(Clone(root1))
+		for i := range n1 {
+			for _, other := range []DataNode{n1[i], n2[i], n3[i], nil} {
+				if n1[i].Equal(other) != Equal(n1[i], other) {
+					t.Errorf("%s.Equal(%v) is not equal to Equal()", n1[i].Path(), other)
+				}
+			}
+			if !n1[i].Equal(n3[i]) {
+				t.Errorf("%s must be equal to the cloned node", n1[i].Path())
+			}
+		}
+		if n1[0].Equal(n2[0]) || n1[3].Equal(n2[3]) {
+			t.Errorf("updated nodes must not be equal")
+		}
+	}
+}
+
+func TestCanonicalID(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{
+		"sample": {
+			"single-key-list": {"A": {"list-key": "A", "country-code": "KR"}},
+			"multiple-key-list": {"first": {"1": {"str": "first", "integer": 1}}},
+			"container-val": {"a": "A"}
+		},
+		"single-leaf-list-rw-user": ["x"]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloned := Clone(root)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/sample/single-key-list[list-key=A]", want: "/sample:sample/single-key-list[list-key=A]"},
+		{path: "/sample/single-key-list[list-key=A]/country-code", want: "/sample:sample/single-key-list/country-code"},
+		{path: "/sample/multiple-key-list[str=first][integer=1]", want: "/sample:sample/multiple-key-list[str=first][integer=1]"},
+		{path: "/sample/container-val/a", want: "/sample:sample/container-val/a"},
+		{path: "/single-leaf-list-rw-user[.=x]", want: "/leaf-list-test:single-leaf-list-rw-user[.=x]"},
+	}
+	for _, tt := range tests {
+		n1, err := Find(root, tt.path)
+		if err != nil || len(n1) != 1 {
+			t.Fatalf("%s not found: %v", tt.path, err)
+		}
+		n2, err := Find(cloned, tt.path)
+		if err != nil || len(n2) != 1 {
+			t.Fatalf("%s not found in the cloned tree: %v", tt.path, err)
+		}
+		if id := CanonicalID(n1[0]); id != tt.want {
+			t.Errorf("CanonicalID(%s) = %s, want %s", tt.path, id, tt.want)
+		}
+		if CanonicalID(n1[0]) != CanonicalID(n2[0]) {
+			t.Errorf("CanonicalID(%s) of the cloned tree must be the same", tt.path)
+		}
+	}
+	// a detached entry has the same canonical id.
+	entry := Clone(root.Get("sample").Get("single-key-list[list-key=A]"))
+	if CanonicalID(entry) != "/sample:sample/single-key-list[list-key=A]" {
+		t.Errorf("unexpected canonical id of the detached node: %s", CanonicalID(entry))
+	}
+}
+
+func TestValidateUnique(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/unique-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unique := schema.FindSchema("/services/server").GetUnique(); !reflect.DeepEqual(unique, [][]string{{"ip", "port"}}) {
+		t.Errorf("unexpected unique statements: %v", unique)
+	}
+	root, err := NewWithValueString(schema, `{"services": {"server": [
+		{"name": "a", "ip": "10.0.0.1", "port": 80},
+		{"name": "b", "ip": "10.0.0.1", "port": 8080},
+		{"name": "c", "ip": "10.0.0.2", "port": 80},
+		{"name": "d", "ip": "10.0.0.1"}
+	]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) > 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+
+	// server[name=e] has the same ip and port with server[name=a].
+	if err := SetValueString(root, "/services/server[name=e]", nil, `{"ip": "10.0.0.1", "port": 80}`); err != nil {
+		t.Fatal(err)
+	}
+	for _, errs := range [][]error{Validate(root), ValidateAll(root)} {
+		if len(errs) != 1 {
+			t.Errorf("one unique violation expected: %v", errs)
+			continue
+		}
+		if !strings.Contains(errs[0].Error(), "server[name=a]") || !strings.Contains(errs[0].Error(), "server[name=e]") {
+			t.Errorf("the violation must report the key ids: %v", errs[0])
+		}
+	}
+	if err := SetValueString(root, "/services/server[name=e]/port", nil, "443"); err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) > 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+}
+
+func TestSetValueStringToBranch(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/sample/container-val", "/sample/single-key-list[list-key=A]"} {
+		err := SetValueString(root, path, nil, "hello")
+		if err == nil {
+			t.Errorf("scalar value must be rejected for %s", path)
+			continue
+		}
+		if !strings.Contains(err.Error(), "requires JSON/YAML value") {
+			t.Errorf("unclear error for the scalar value of %s: %v", path, err)
+		}
+	}
+	if root.Get("sample") != nil && root.Get("sample").Get("single-key-list[list-key=A]") != nil {
+		t.Errorf("list entry must not be created with the invalid value")
+	}
+	if err := SetValueString(root, "/sample/container-val", nil, ` {"a": "A"}`); err != nil {
+		t.Errorf("json value must be allowed for the branch: %v", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	rootschema, err := Load([]string{"testdata/modules/default.yang"}, nil, nil, YANGTreeOption{CreatedWithDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(rootschema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/test/config", nil, `{"d1": 5, "d2": "changed"}`); err != nil {
+		t.Fatal(err)
+	}
+	config := root.Get("test").Get("config")
+	if config.GetValueString("d1") != "5" {
+		t.Fatalf("d1 must be updated")
+	}
+	if err := Reset(config); err != nil {
+		t.Fatalf("Reset() error: %v", err)
+	}
+	expected, err := New(rootschema.FindSchema("/test/config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(config, expected) {
+		j1, _ := MarshalJSON(config)
+		j2, _ := MarshalJSON(expected)
+		t.Errorf("the reset node must have only the default values: %s, %s", j1, j2)
+	}
+	if config.Parent() == nil {
+		t.Errorf("the reset node must remain in the tree")
+	}
+	if err := Reset(config.Get("d1")); err == nil {
+		t.Errorf("reset of a leaf node must not be supported")
+	}
+
+	// the keys of the list entry are kept.
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(sample, "/sample/single-key-list[list-key=A]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+	entry := sample.Get("sample").Get("single-key-list[list-key=A]")
+	if err := Reset(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Len() != 1 || entry.GetValueString("list-key") != "A" {
+		t.Errorf("only the key node must remain after reset: %d children", entry.Len())
+	}
+}
+
+func TestXPathBooleanFunctions(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {"multiple-key-list": [
+		{"str": "first", "integer": 1, "ok": true},
+		{"str": "first", "integer": 2, "ok": false},
+		{"str": "second", "integer": 3}
+	]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		predicate string
+		expected  []string
+	}{
+		{predicate: "not(ok)", expected: []string{"[str=first][integer=2]", "[str=second][integer=3]"}},
+		{predicate: "ok = true()", expected: []string{"[str=first][integer=1]"}},
+		{predicate: "ok = false()", expected: []string{"[str=first][integer=2]"}},
+		{predicate: "true()", expected: []string{"[str=first][integer=1]", "[str=first][integer=2]", "[str=second][integer=3]"}},
+		{predicate: "false()", expected: nil},
+		{predicate: "not(false())", expected: []string{"[str=first][integer=1]", "[str=first][integer=2]", "[str=second][integer=3]"}},
+		{predicate: "not(ok) and str = 'first'", expected: []string{"[str=first][integer=2]"}},
+	}
+	for _, tt := range tests {
+		nodes, err := Find(root, "/sample/multiple-key-list["+tt.predicate+"]", UseXPath{})
+		if err != nil {
+			t.Errorf("Find() with [%s] error: %v", tt.predicate, err)
+			continue
+		}
+		var got []string
+		for i := range nodes {
+			got = append(got, strings.TrimPrefix(nodes[i].ID(), "multiple-key-list"))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Find() with [%s] = %v, want %v", tt.predicate, got, tt.expected)
+		}
+	}
+}
+
+func TestTrackModifyTime(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{TrackModifyTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "A"); err != nil {
+		t.Fatal(err)
+	}
+	modified := func(path string) string {
+		node, err := Find(root, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		meta := node[0].Metadata()["yangtree:modified"]
+		if meta == nil {
+			return ""
+		}
+		return meta.ValueString()
+	}
+	for _, path := range []string{"/sample/container-val/a", "/sample/container-val", "/sample"} {
+		if modified(path) == "" {
+			t.Errorf("modified metadata not found on %s", path)
+		}
+	}
+	first := modified("/sample")
+	time.Sleep(time.Millisecond)
+	if err := SetValueString(root, "/sample/str-val", nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if modified("/sample/str-val") == "" || modified("/sample") == first {
+		t.Errorf("modified metadata of /sample not updated: %s", first)
+	}
+	if modified("/sample/container-val") != modified("/sample/container-val/a") {
+		t.Errorf("modified metadata of the unchanged node must not be updated")
+	}
+	b, err := MarshalJSON(root, RFC7951Format{}, Metadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "yangtree:modified") {
+		t.Errorf("modified metadata not found in %s", string(b))
+	}
+
+	if err := SetValueString(root, "/sample/container-val/leaf-list-val", nil, "x"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/sample/container-val/leaf-list-val", "/sample/container-val"} {
+		last := modified(path)
+		time.Sleep(time.Millisecond)
+		node, err := Find(root, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		switch n := node[0].(type) {
+		case *DataLeafList:
+			err = n.Clear()
+		case *DataBranch:
+			err = n.Clear()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if modified(path) == last {
+			t.Errorf("modified metadata of %s not updated by Clear()", path)
+		}
+	}
+}
+
+func TestBulkInsert(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sschema := schema.GetSchema("sample")
+	tests := []struct {
+		schema string
+		id     string // the id of the keyed child
+		value  string // the value of the keyless child
+	}{
+		{schema: "single-key-list", id: "single-key-list[list-key=7]"},
+		{schema: "multiple-key-list", id: "multiple-key-list[str=b][integer=1]"},
+		{schema: "non-key-list", value: `{"uintval": 3}`},
+		{schema: "ordered-by-user-list", id: "ordered-by-user-list[name=z]"},
+		{schema: "single-key-list", id: "single-key-list[list-key=1]"},
+		{schema: "multiple-key-list", id: "multiple-key-list[str=a][integer=2]"},
+		{schema: "non-key-list", value: `{"uintval": 1}`},
+		{schema: "ordered-by-user-list", id: "ordered-by-user-list[name=a]"},
+		{schema: "single-key-list", id: "single-key-list[list-key=3]"}, // replaces the existing one
+		{schema: "single-key-list", id: "single-key-list[list-key=7]"}, // replaces the former one
+		{schema: "non-key-list", value: `{"uintval": 3}`},
+		{schema: "str-val", value: "replaced"},
+	}
+	var roots []DataNode
+	for i := 0; i < 2; i++ {
+		root, err := NewWithValueString(sschema, `{"str-val": "abc", "single-key-list": [{"list-key": "3"}]}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+	var children []DataNode
+	for i, root := range roots {
+		children = children[:0]
+		for _, tt := range tests {
+			var child DataNode
+			var err error
+			if tt.id != "" {
+				child, err = NewWithID(sschema.GetSchema(tt.schema), tt.id)
+			} else {
+				child, err = NewWithValueString(sschema.GetSchema(tt.schema), tt.value)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			children = append(children, child)
+		}
+		if i == 1 {
+			if err := root.(*DataBranch).BulkInsert(children); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		for _, child := range children {
+			if _, err := root.Insert(child, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expected, err := MarshalJSON(roots[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MarshalJSON(roots[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(got) {
+		t.Errorf("unexpected order of bulk insert:\n%s\n%s", expected, got)
+	}
+	for i := range roots[1].Children() {
+		if roots[0].Children()[i].ID() != roots[1].Children()[i].ID() {
+			t.Fatalf("unexpected child %s, want %s", roots[1].Children()[i].ID(), roots[0].Children()[i].ID())
+		}
+		if roots[1].Children()[i].Parent() != roots[1] {
+			t.Fatalf("invalid parent of %s", roots[1].Children()[i].ID())
+		}
+	}
+}
+
+func benchmarkInsert(b *testing.B, bulk bool) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sschema := schema.GetSchema("sample")
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		root, err := NewWithValueString(sschema)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var children []DataNode
+		for j := 0; j < 5000; j++ {
+			child, err := NewWithID(sschema.GetSchema("single-key-list"), fmt.Sprintf("single-key-list[list-key=%d]", (j*7919)%5000))
+			if err != nil {
+				b.Fatal(err)
+			}
+			children = append(children, child)
+		}
+		b.StartTimer()
+		if bulk {
+			if err := root.(*DataBranch).BulkInsert(children); err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+		for j := range children {
+			if _, err := root.Insert(children[j], nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkInsert(b *testing.B)     { benchmarkInsert(b, false) }
+func BenchmarkBulkInsert(b *testing.B) { benchmarkInsert(b, true) }
+
+func TestFindLimitOffset(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []string
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("k%d", i)
+		if err := SetValueString(root, "/sample/single-key-list[list-key="+key+"]", nil); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, key)
+	}
+	tests := []struct {
+		option   []Option
+		expected []string
+	}{
+		{option: nil, expected: all},
+		{option: []Option{Limit(3)}, expected: all[:3]},
+		{option: []Option{Offset(8)}, expected: all[8:]},
+		{option: []Option{Limit(3), Offset(4)}, expected: all[4:7]},
+		{option: []Option{Offset(8), Limit(5)}, expected: all[8:]},
+		{option: []Option{Limit(0)}, expected: nil},
+		{option: []Option{Offset(10)}, expected: nil},
+	}
+	for _, tt := range tests {
+		found, err := Find(root, "/sample/single-key-list/*", tt.option...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i := range found {
+			got = append(got, found[i].ValueString())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Find() with %v = %v, want %v", tt.option, got, tt.expected)
+		}
+	}
+}
+
+func TestSortChildren(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample, err := NewWithValueString(schema.GetSchema("sample"), `{
+		"single-key-list": [{"list-key": "BBB"}, {"list-key": "AAA"}],
+		"str-val": "abc"
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"c", "a", "b"} {
+		entry, err := NewWithID(schema.FindSchema("/sample/ordered-by-user-list"), "ordered-by-user-list[name="+name+"]")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sample.Insert(entry, InsertToLast{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ids := func() []string {
+		var ids []string
+		for _, child := range sample.Children() {
+			ids = append(ids, child.ID())
+		}
+		return ids
+	}
+	if got := ids(); got[0] != "ordered-by-user-list[name=c]" {
+		t.Fatalf("ordered-by user list is not kept in the user order: %v", got)
+	}
+	expected := []string{
+		"ordered-by-user-list[name=a]",
+		"ordered-by-user-list[name=b]",
+		"ordered-by-user-list[name=c]",
+		"single-key-list[list-key=AAA]",
+		"single-key-list[list-key=BBB]",
+		"str-val",
+	}
+	sample.(*DataBranch).SortChildren()
+	if got := ids(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected children order after sorting:\n got %v\nwant %v", got, expected)
+	}
+	// no-op for the sorted children
+	sample.(*DataBranch).SortChildren()
+	if got := ids(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected children order after sorting again:\n got %v\nwant %v", got, expected)
+	}
+	if n := sample.Get("ordered-by-user-list[name=b]"); n == nil {
+		t.Errorf("ordered-by user list entry not found after sorting")
+	}
+}
+
+func TestTypedValueAccessors(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"single-key-list": [{"list-key": "AAA", "int8-range": -5, "uint32-range": 100,
+			"uint64-node": "1234567890", "decimal-range": 1.01}],
+		"multiple-key-list": [{"str": "first", "integer": 1, "ok": true}]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) DataNode {
+		nodes, err := Find(root, path)
+		if err != nil || len(nodes) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		return nodes[0]
+	}
+	entry := "/sample/single-key-list[list-key=AAA]/"
+	if v, ok := GetInt64(get(entry + "int8-range")); !ok || v != -5 {
+		t.Errorf("GetInt64(int8) = %v, %v", v, ok)
+	}
+	if v, ok := GetUint64(get(entry + "int8-range")); ok {
+		t.Errorf("GetUint64(negative int8) = %v, %v", v, ok)
+	}
+	if v, ok := GetInt64(get(entry + "uint32-range")); !ok || v != 100 {
+		t.Errorf("GetInt64(uint32) = %v, %v", v, ok)
+	}
+	if v, ok := GetUint64(get(entry + "uint32-range")); !ok || v != 100 {
+		t.Errorf("GetUint64(uint32) = %v, %v", v, ok)
+	}
+	if v, ok := GetUint64(get(entry + "uint64-node")); !ok || v != 1234567890 {
+		t.Errorf("GetUint64(uint64) = %v, %v", v, ok)
+	}
+	if v, ok := GetFloat64(get(entry + "uint64-node")); !ok || v != 1234567890 {
+		t.Errorf("GetFloat64(uint64) = %v, %v", v, ok)
+	}
+	if v, ok := GetFloat64(get(entry + "decimal-range")); !ok || v != 1.01 {
+		t.Errorf("GetFloat64(decimal64) = %v, %v", v, ok)
+	}
+	if v, ok := GetBool(get("/sample/multiple-key-list[str=first][integer=1]/ok")); !ok || !v {
+		t.Errorf("GetBool() = %v, %v", v, ok)
+	}
+	if v, ok := GetString(get("/sample/str-val")); !ok || v != "abc" {
+		t.Errorf("GetString() = %v, %v", v, ok)
+	}
+	if v, ok := GetString(get(entry + "uint32-range")); !ok || v != "100" {
+		t.Errorf("GetString(uint32) = %v, %v", v, ok)
+	}
+	if v, ok := GetInt64(get("/sample/str-val")); ok {
+		t.Errorf("GetInt64(string) = %v, %v", v, ok)
+	}
+	if _, ok := GetString(get("/sample")); ok {
+		t.Errorf("GetString() must fail for a branch node")
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"str-val-ext": "",
+		"empty-val": [null],
+		"container-val": {"a": "A", "enum-val": "enum2", "leaf-list-val": ["x", "y"], "test-default": 11},
+		"single-key-list": [
+			{"list-key": "AAA", "country-code": "KR", "uint64-node": "1234567890"},
+			{"list-key": "BBB", "country-code": "US"}
+		],
+		"multiple-key-list": [{"str": "first", "integer": 1, "ok": true}]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := ToFlatMap(root)
+	if m["/sample/single-key-list[list-key=AAA]/country-code"] != "KR" {
+		t.Errorf("unexpected flat map: %v", m)
+	}
+	rebuilt, err := FromFlatMap(schema, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m["/sample/str-val-ext"]; !ok || v != "" {
+		t.Errorf("the empty string value must be kept in the flat map: %v", m)
+	}
+	if found, _ := Find(rebuilt, "/sample/str-val-ext"); len(found) != 1 || found[0].ValueString() != "" {
+		t.Errorf("the empty string value must be set by FromFlatMap(): %v", found)
+	}
+	if !Equal(root, rebuilt) {
+		j1, _ := MarshalJSON(root)
+		j2, _ := MarshalJSON(rebuilt)
+		t.Errorf("FromFlatMap(ToFlatMap()) is not equal to the original:\n%s\n%s", j1, j2)
+	}
+}
+
+func TestAddValidator(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := 0
+	err = AddValidator(schema, "/sample:sample/container-val", func(node DataNode) error {
+		called++
+		if node.Get("a") != nil && node.Get("enum-val") == nil {
+			return fmt.Errorf("enum-val must be set with a in %s", node.Path())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveValidators(schema, "/sample/container-val")
+
+	root, err := NewWithValueString(schema, `{"sample": {"container-val": {"a": "A"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := ValidateAll(root)
+	if called != 1 {
+		t.Errorf("validator called %d times", called)
+	}
+	found := false
+	for i := range errs {
+		if strings.Contains(errs[i].Error(), "enum-val must be set") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("validator error not returned: %v", errs)
+	}
+	if err := SetValueString(root, "/sample/container-val/enum-val", nil, "enum1"); err != nil {
+		t.Fatal(err)
+	}
+	container, err := Find(root, "/sample/container-val")
+	if err != nil || len(container) != 1 {
+		t.Fatalf("container-val not found: %v", err)
+	}
+	if errs := Validate(container[0]); len(errs) > 0 {
+		t.Errorf("unexpected validation errors: %v", errs)
+	}
+	if called < 2 {
+		t.Errorf("validator not called by Validate()")
+	}
+
+	// the validator is only registered to the schema tree.
+	other, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called = 0
+	root, err = NewWithValueString(other, `{"sample": {"container-val": {"a": "A"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateAll(root); len(errs) > 0 || called != 0 {
+		t.Errorf("validator of other schema tree called: %v", errs)
+	}
+	if err := AddValidator(schema, "/sample/unknown", func(DataNode) error { return nil }); err == nil {
+		t.Errorf("validator for unknown schema must be rejected")
+	}
+}
+
+func TestLeafrefCache(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/leafref-list.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path  string
+		value []string
+	}{
+		{path: "/servers/server[name=S1]"},
+		{path: "/servers/server[name=S2]"},
+		{path: "/servers/server[name=S3]"},
+		{path: "/clients/client[id=C1]/server", value: []string{"S1"}},
+		{path: "/clients/client[id=C2]/server", value: []string{"S2"}},
+		{path: "/clients/client[id=C3]/server", value: []string{"S3"}},
+		{path: "/clients/client[id=C4]/server", value: []string{"S1"}},
+		{path: "/clients/client[id=C5]/server", value: []string{"S2"}},
+	}
+	for _, tt := range tests {
+		if err := SetValueString(root, tt.path, nil, tt.value...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if errs := ValidateAll(root); len(errs) > 0 {
+		t.Errorf("ValidateAll() returns errors for valid leafrefs: %v", errs)
+	}
+	if errs := Validate(root); len(errs) > 0 {
+		t.Errorf("Validate() returns errors for valid leafrefs: %v", errs)
+	}
+	refs, err := Find(root, "/clients/client/server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := newLeafrefCache()
+	for i := range refs {
+		path := refs[i].Schema().Type.Path
+		cached, err := cache.resolve(refs[i], path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uncached, err := (*leafrefCache)(nil).resolve(refs[i], path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(cached, uncached) {
+			t.Errorf("cached resolution of %s differs: %v, %v", refs[i].Path(), cached, uncached)
+		}
+	}
+	if len(cache.values) != 1 {
+		t.Errorf("the leafrefs into the same list must share a cache entry: %d", len(cache.values))
+	}
+
+	if err := SetValueString(root, "/clients/client[id=C1]/server", nil, "unknown"); err != nil {
+		t.Fatal(err)
+	}
+	if errs := ValidateAll(root); len(errs) != 1 {
+		t.Errorf("ValidateAll() must return an error for the invalid leafref: %v", errs)
+	}
+}
+
+func benchmarkLeafrefValidation(b *testing.B, cached bool) {
+	schema, err := Load([]string{"testdata/modules/leafref-list.yang"}, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if err := SetValueString(root, fmt.Sprintf("/servers/server[name=S%d]", i), nil); err != nil {
+			b.Fatal(err)
+		}
+		if err := SetValueString(root, fmt.Sprintf("/clients/client[id=C%d]/server", i), nil, fmt.Sprintf("S%d", i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var cache *leafrefCache
+		if cached {
+			cache = newLeafrefCache()
+		}
+		if errs := validateAll(root, cache); len(errs) > 0 {
+			b.Fatal(errs)
+		}
+	}
+}
+
+func BenchmarkLeafrefValidation(b *testing.B)       { benchmarkLeafrefValidation(b, false) }
+func BenchmarkLeafrefValidationCached(b *testing.B) { benchmarkLeafrefValidation(b, true) }
+
+func TestFilterSubtree(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"container-val": {"a": "A"},
+		"single-key-list": [
+			{"list-key": "AAA", "country-code": "KR", "int8-range": 1},
+			{"list-key": "BBB", "country-code": "US", "int8-range": 2},
+			{"list-key": "CCC", "country-code": "KR", "int8-range": 3}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{
+			name:     "selection",
+			filter:   `{"sample": {"str-val": "", "container-val": {}}}`,
+			expected: `{"sample": {"str-val": "abc", "container-val": {"a": "A"}}}`,
+		},
+		{
+			name: "list entries and leaves",
+			filter: `{"sample": {"str-val": "", "single-key-list": [
+				{"list-key": "AAA", "country-code": ""},
+				{"list-key": "CCC"}
+			]}}`,
+			expected: `{"sample": {"str-val": "abc", "single-key-list": [
+				{"list-key": "AAA", "country-code": "KR"},
+				{"list-key": "CCC", "country-code": "KR", "int8-range": 3}
+			]}}`,
+		},
+		{
+			name:     "content match",
+			filter:   `{"sample": {"str-val": "xyz", "container-val": {}}}`,
+			expected: `{}`,
+		},
+		{
+			name:     "no match",
+			filter:   `{"sample": {"single-key-list": [{"list-key": "DDD"}]}}`,
+			expected: `{}`,
+		},
+		{
+			name:     "empty filter",
+			filter:   `{}`,
+			expected: `{}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewWithValueString(schema, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := NewWithValueString(schema, tt.expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := FilterSubtree(data, filter)
+			if err != nil {
+				t.Fatalf("FilterSubtree() error = %v", err)
+			}
+			if !Equal(got, expected) {
+				g, _ := MarshalJSON(got)
+				e, _ := MarshalJSON(expected)
+				t.Errorf("FilterSubtree() = %s, want %s", g, e)
+			}
+		})
+	}
+}
+
+func TestModuleNameAndNamespace(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{
+		"sample": {"str-val": "abc", "single-key-list": [{"list-key": "AAA"}]},
+		"single-leaf-list-rw-system": ["x", "y"]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path      string
+		module    string
+		namespace string
+	}{
+		{path: "/sample", module: "sample", namespace: "urn:network"},
+		{path: "/sample/str-val", module: "sample", namespace: "urn:network"},
+		{path: "/sample/single-key-list[list-key=AAA]", module: "sample", namespace: "urn:network"},
+		{path: "/single-leaf-list-rw-system", module: "leaf-list-test", namespace: "yangtree:leaf-list-test"},
+	}
+	for _, tt := range tests {
+		found, err := Find(root, tt.path)
+		if err != nil || len(found) == 0 {
+			t.Fatalf("%s not found: %v", tt.path, err)
+		}
+		if got := found[0].ModuleName(); got != tt.module {
+			t.Errorf("ModuleName() of %s = %s, want %s", tt.path, got, tt.module)
+		}
+		if got := found[0].Namespace(); got != tt.namespace {
+			t.Errorf("Namespace() of %s = %s, want %s", tt.path, got, tt.namespace)
+		}
+	}
+}
+
+func TestValidateListKeys(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {"single-key-list": [{"list-key": "AAA"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) > 0 {
+		t.Fatalf("unexpected validation errors: %v", errs)
+	}
+	lschema := schema.FindSchema("/sample/single-key-list")
+	blank, err := NewWithID(lschema, "single-key-list[list-key=]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample := root.Get("sample")
+	if _, err := sample.Insert(blank, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, errs := range [][]error{Validate(root), ValidateAll(root)} {
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "empty key list-key") {
+			t.Errorf("an empty key error expected: %v", errs)
+		}
+	}
+	if err := blank.Remove(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a list entry without the key node
+	missing, err := New(lschema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sample.Insert(missing, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, errs := range [][]error{Validate(root), ValidateAll(root)} {
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), "key list-key") {
+			t.Errorf("a missing key error expected: %v", errs)
+		}
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	rootschema, err := Load(
+		[]string{
+			"testdata/modules/default.yang",
+			"testdata/modules/when-default.yang",
+		}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(rootschema, `{"test": {"config": {"d2": "abc"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := root.Get("test").Get("config")
+	if config.Len() != 1 || config.Get("d1") != nil {
+		t.Fatalf("the default node must not be stored: %v", config.Children())
+	}
+	if v := GetValue(config, "d1"); v != nil {
+		t.Errorf("GetValue() without WithDefaults must return nil: %v", v)
+	}
+	if v := GetValue(config, "d1", WithDefaults{}); v != int32(100) {
+		t.Errorf("GetValue() with WithDefaults must return the default value: %v", v)
+	}
+	if v := GetValueString(config, "d2", WithDefaults{}); v != "abc" {
+		t.Errorf("GetValueString() must return the stored value: %v", v)
+	}
+
+	j, err := MarshalJSON(root.Get("test"), WithDefaults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"config":{"d1":100,"d2":"abc"}}`
+	if string(j) != expected {
+		t.Errorf("MarshalJSON() with WithDefaults:\n got: %s\nwant: %s", j, expected)
+	}
+	if j, _ := MarshalJSON(root.Get("test")); string(j) != `{"config":{"d2":"abc"}}` {
+		t.Errorf("MarshalJSON() without WithDefaults must not include the defaults: %s", j)
+	}
+	y, err := MarshalYAML(root.Get("test"), WithDefaults{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(y), "d1: 100") {
+		t.Errorf("MarshalYAML() with WithDefaults must include the defaults:\n%s", y)
+	}
+	if config.Len() != 1 {
+		t.Errorf("the data tree must be kept sparse after marshalling: %v", config.Children())
+	}
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{value: `{}`, expected: `{"a-val":10,"mode":"a"}`},
+		{value: `{"mode":"b"}`, expected: `{"b-extra":"extra","b-val":20,"mode":"b"}`},
+		{value: `{"a-val":30}`, expected: `{"a-val":30,"mode":"a"}`},
+	}
+	for _, tt := range tests {
+		settings, err := NewWithValueString(rootschema.GetSchema("settings"), tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, err := MarshalJSON(settings, WithDefaults{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(j) != tt.expected {
+			t.Errorf("MarshalJSON(%s) with WithDefaults:\n got: %s\nwant: %s", tt.value, j, tt.expected)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"single-key-list": [
+			{"list-key": "AAA", "uint32-range": 100},
+			{"list-key": "BBB", "uint32-range": 300},
+			{"list-key": "CCC", "uint32-range": 200},
+			{"list-key": "DDD"}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		op       AggOp
+		expected float64
+		wantErr  bool
+	}{
+		{path: "/sample/single-key-list/uint32-range", op: AggSum, expected: 600},
+		{path: "/sample/single-key-list/uint32-range", op: AggMin, expected: 100},
+		{path: "/sample/single-key-list/uint32-range", op: AggMax, expected: 300},
+		{path: "/sample/single-key-list/uint32-range", op: AggAvg, expected: 200},
+		{path: "/sample/single-key-list[list-key='BBB']/uint32-range", op: AggSum, expected: 300},
+		{path: "/sample/single-key-list[list-key='BBB' or list-key='CCC']/uint32-range", op: AggSum, expected: 500},
+		{path: "/sample/non-key-list/uintval", op: AggSum, expected: 0},
+		{path: "/sample/non-key-list/uintval", op: AggMax, wantErr: true},
+		{path: "/sample/str-val", op: AggSum, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Aggregate(root, tt.path, tt.op)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Aggregate(%s, %s) error = %v, wantErr %v", tt.path, tt.op, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.expected {
+			t.Errorf("Aggregate(%s, %s) = %v, want %v", tt.path, tt.op, got, tt.expected)
+		}
+	}
+}
+
+func TestNewSkeleton(t *testing.T) {
+	dschema, err := Load([]string{"testdata/modules/default.yang"}, nil, nil, YANGTreeOption{CreatedWithDefault: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	skeleton, err := NewSkeleton(dschema, "/test/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := MarshalJSON(skeleton); string(j) != `{"test":{"config":{}}}` {
+		t.Errorf("unexpected skeleton without default nodes: %s", j)
+	}
+
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"single-key-list": [
+			{"list-key": "AAA", "country-code": "KR", "int8-range": 1},
+			{"list-key": "BBB", "country-code": "US", "int8-range": 2}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{
+			path: "/sample/single-key-list/country-code",
+			expected: `{"sample": {"single-key-list": [
+				{"list-key": "AAA", "country-code": "KR"},
+				{"list-key": "BBB", "country-code": "US"}
+			]}}`,
+		},
+		{
+			path:     "/sample/single-key-list[list-key=BBB]/int8-range",
+			expected: `{"sample": {"single-key-list": [{"list-key": "BBB", "int8-range": 2}]}}`,
+		},
+	}
+	for _, tt := range tests {
+		filter, err := NewSkeleton(schema, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found, err := Find(filter, tt.path); err != nil || len(found) != 1 || found[0].ValueString() != "" {
+			t.Errorf("the target node %s must be created without value: %v, %v", tt.path, found, err)
+		}
+		got, err := FilterSubtree(data, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := NewWithValueString(schema, tt.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !Equal(got, expected) {
+			g, _ := MarshalJSON(got)
+			e, _ := MarshalJSON(expected)
+			t.Errorf("FilterSubtree() with the skeleton of %s = %s, want %s", tt.path, g, e)
+		}
+	}
+
+	if _, err := NewSkeleton(schema, "/sample/unknown"); err == nil {
+		t.Errorf("NewSkeleton() must fail for an unknown path")
+	}
+	if _, err := NewSkeleton(schema, "/sample/str-val/unknown"); err == nil {
+		t.Errorf("NewSkeleton() must fail for a path under a leaf node")
+	}
+}
+
+func TestRejectStateEdit(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {"single-key-list": [{"list-key": "AAA", "uint32-range": 100}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eopt := &EditOption{RejectStateEdit: true}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/country-code", eopt, "KR"); err != nil {
+		t.Errorf("the config leaf must be set: %v", err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=BBB]/int8-range", eopt, "1"); err != nil {
+		t.Errorf("the config leaf of the new list entry must be set: %v", err)
+	}
+	err = SetValueString(root, "/sample/single-key-list[list-key=AAA]/uint32-range", eopt, "200")
+	if err == nil || !strings.Contains(err.Error(), "uint32-range") {
+		t.Errorf("the state leaf must not be set: %v", err)
+	}
+	if v, _ := GetValueStringAt(root, "/sample/single-key-list[list-key=AAA]/uint32-range"); v != "100" {
+		t.Errorf("the state leaf must not be changed: %s", v)
+	}
+	// the state nodes in the JSON values and the merged data nodes must be rejected.
+	for _, path := range []string{"/sample/single-key-list[list-key=AAA]", "/sample/single-key-list[list-key=CCC]", "/sample"} {
+		value := `{"uint32-range": 5}`
+		if path == "/sample" {
+			value = `{"single-key-list": [{"list-key": "AAA", "uint32-range": 5}]}`
+		}
+		if err := SetValueString(root, path, eopt, value); err == nil {
+			t.Errorf("the state leaf in the json value set to %s must be rejected", path)
+		}
+	}
+	if v, _ := GetValueStringAt(root, "/sample/single-key-list[list-key=AAA]/uint32-range"); v != "100" {
+		t.Errorf("the state leaf must not be changed: %s", v)
+	}
+	if n, _ := Find(root, "/sample/single-key-list[list-key=CCC]"); len(n) > 0 {
+		t.Errorf("the list entry having the state leaf must not be created")
+	}
+	entry, err := NewWithValueString(schema.FindSchema("/sample/single-key-list"), `{"list-key": "AAA", "uint32-range": 5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(root, "/sample/single-key-list[list-key=AAA]", eopt, entry); err == nil {
+		t.Errorf("the state leaf of the merged data node must be rejected")
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]", eopt, `{"country-code": "US"}`); err != nil {
+		t.Errorf("the config leaf in the json value must be set: %v", err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/uint32-range",
+		&EditOption{EditOp: EditRemove, RejectStateEdit: true}); err == nil {
+		t.Errorf("the state leaf must not be removed")
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/uint32-range", nil, "200"); err != nil {
+		t.Errorf("the state leaf must be set without RejectStateEdit: %v", err)
+	}
+}
+
+func TestEditUpdate(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {"str-val": "abc",
+		"single-key-list": [{"list-key": "AAA", "country-code": "KR"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eopt := &EditOption{EditOp: EditUpdate}
+	// present targets are merged.
+	if err := SetValueString(root, "/sample/str-val", eopt, "xyz"); err != nil {
+		t.Errorf("the present leaf must be updated: %v", err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]", eopt,
+		`{"uint32-range": 100}`); err != nil {
+		t.Errorf("the present list entry must be updated: %v", err)
+	}
+	entry, err := NewWithValueString(schema.FindSchema("/sample/single-key-list"),
+		`{"list-key": "AAA", "int8-range": 1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(root, "/sample/single-key-list[list-key=AAA]", eopt, entry); err != nil {
+		t.Errorf("the present list entry must be merged: %v", err)
+	}
+	for path, value := range map[string]string{
+		"/sample/str-val": "xyz",
+		"/sample/single-key-list[list-key=AAA]/country-code": "KR",
+		"/sample/single-key-list[list-key=AAA]/uint32-range": "100",
+		"/sample/single-key-list[list-key=AAA]/int8-range":   "1",
+	} {
+		if v, _ := GetValueStringAt(root, path); v != value {
+			t.Errorf("unexpected value of %s: %s", path, v)
+		}
+	}
+	// absent targets are not created.
+	backup := Clone(root)
+	for _, path := range []string{
+		"/sample/empty-val",
+		"/sample/single-key-list[list-key=BBB]/country-code",
+		"/sample/single-key-list[list-key=AAA]/decimal-range",
+	} {
+		err := SetValueString(root, path, eopt, "1")
+		if yerr, ok := err.(*YError); !ok || yerr.ErrorTag != ETagDataMissing {
+			t.Errorf("data-missing error expected for %s: %v", path, err)
+		}
+	}
+	entry, err = NewWithValueString(schema.FindSchema("/sample/single-key-list"), `{"list-key": "CCC"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetValue(root, "/sample/single-key-list[list-key=CCC]", eopt, entry)
+	if yerr, ok := err.(*YError); !ok || yerr.ErrorTag != ETagDataMissing {
+		t.Errorf("data-missing error expected for the absent list entry: %v", err)
+	}
+	if !Equal(root, backup) {
+		t.Errorf("the absent targets must not be created")
+	}
+}
+
+func TestSiblings(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"single-key-list": [{"list-key": "AAA"}, {"list-key": "BBB"}, {"list-key": "CCC"}],
+		"container-val": {"leaf-list-val": ["x", "y"]}
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{path: "/sample/single-key-list[list-key=BBB]", expected: []string{"single-key-list[list-key=AAA]", "single-key-list[list-key=CCC]"}},
+		{path: "/sample/container-val/leaf-list-val[.=x]", expected: []string{"leaf-list-val[.=y]"}},
+		{path: "/sample/str-val", expected: []string{}},
+		{path: "/sample", expected: []string{}},
+	}
+	for _, tt := range tests {
+		found, err := Find(root, tt.path)
+		if err != nil || len(found) != 1 {
+			t.Fatalf("%s not found: %v", tt.path, err)
+		}
+		ids := []string{}
+		for _, s := range Siblings(found[0]) {
+			ids = append(ids, s.ID())
+		}
+		if !reflect.DeepEqual(ids, tt.expected) {
+			t.Errorf("Siblings(%s) = %v, want %v", tt.path, ids, tt.expected)
+		}
+	}
+	if s := Siblings(root); s != nil {
+		t.Errorf("Siblings() of the root must be nil: %v", s)
+	}
+}
+
+func TestUsePool(t *testing.T) {
+	jbytes, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := NewWithValueString(schema, string(jbytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pschema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{UsePool: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(pschema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ej, _ := MarshalJSON(expected)
+	for i := 0; i < 10; i++ {
+		if err := UnmarshalJSON(root, jbytes); err != nil {
+			t.Fatal(err)
+		}
+		if j, _ := MarshalJSON(root); string(j) != string(ej) {
+			t.Fatalf("the tree built with the pool differs at %d:\n got: %s\nwant: %s", i, j, ej)
+		}
+		// move a list entry to check the moved node is not released.
+		entry := root.Get("sample").Get("single-key-list[list-key=AAA]")
+		if entry == nil {
+			t.Fatalf("list entry not found at %d", i)
+		}
+		sample := root.Get("sample").(*DataBranch)
+		if err := detach(entry); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sample.Insert(entry, nil); err != nil {
+			t.Fatal(err)
+		}
+		if entry.GetValueString("list-key") != "AAA" {
+			t.Fatalf("the moved node must not be released at %d", i)
+		}
+		// teardown
+		for _, child := range copyDataNodeList(root.Children()) {
+			if err := child.Remove(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if root.Len() != 0 {
+			t.Fatalf("all children must be removed at %d", i)
+		}
+		if entry.Schema() != nil || entry.Parent() != nil {
+			t.Errorf("the removed node must be reset by the release at %d", i)
+		}
+	}
+}
+
+func TestUsePoolRollback(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{UsePool: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := NewWithValueString(schema.FindSchema("/sample/single-key-list"),
+		`{"list-key": "AAA", "country-code": "KR"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eopt := &EditOption{Callback: func(op EditOp, old, new []DataNode) error {
+		return fmt.Errorf("rejected")
+	}}
+	if err := SetValue(root, "/sample/single-key-list[list-key=AAA]", eopt, entry); err == nil {
+		t.Fatal("the callback failure must be returned")
+	}
+	if entry.Schema() == nil || entry.Parent() != nil || entry.GetValueString("country-code") != "KR" {
+		t.Errorf("the node of the caller must not be released by the rollback")
+	}
+	// the parentless nodes are not released by Remove().
+	if err := root.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := entry.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if root.Schema() == nil || entry.GetValueString("list-key") != "AAA" {
+		t.Errorf("the parentless nodes must not be released by Remove()")
+	}
+}
+
+func TestUsePoolDeleteCallback(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{UsePool: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []DataNode
+	eopt := &EditOption{EditOp: EditDelete, Callback: func(op EditOp, old, new []DataNode) error {
+		kept = append(kept, old...)
+		kept = append(kept, new...)
+		return nil
+	}}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("K%d", i)
+		if err := SetValueString(root, "/sample/single-key-list[list-key="+key+"]/country-code", nil, "KR"); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetValueString(root, "/sample/leaf-list-rw", nil, key); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetValueString(root, "/sample/single-key-list[list-key="+key+"]", eopt); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetValueString(root, "/sample/leaf-list-rw", eopt, key); err != nil {
+			t.Fatal(err)
+		}
+		// churn the pools with the explicit removals and the new nodes reusing them.
+		if err := SetValueString(root, "/sample/multiple-key-list[str=A][integer=1]/ok", nil, "true"); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.Get("sample").Get("multiple-key-list[str=A][integer=1]").Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(kept) != 300 {
+		t.Fatalf("unexpected number of the deleted nodes passed to the callback: %d", len(kept))
+	}
+	for i := range kept {
+		key := fmt.Sprintf("K%d", i/3)
+		switch i % 3 {
+		case 0:
+			if kept[i].Schema() == nil || kept[i].GetValueString("list-key") != key ||
+				kept[i].GetValueString("country-code") != "KR" {
+				t.Errorf("the deleted list entry kept by the callback must not be released: %s", kept[i])
+			}
+		default:
+			if kept[i].Schema() == nil || kept[i].Schema().Name != "leaf-list-rw" {
+				t.Errorf("the deleted leaf-list kept by the callback must not be released: %s", kept[i])
+			}
+		}
+	}
+}
+
+func TestSetDataNodeKeyPredicates(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := NewWithValueString(schema.FindSchema("/sample/single-key-list"), `{"list-key": "B"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eopt := &EditOption{Callback: func(op EditOp, old, new []DataNode) error {
+		return fmt.Errorf("rejected")
+	}}
+	if err := SetValue(root, "/sample/single-key-list[list-key=B]", eopt, entry); err == nil {
+		t.Fatal("the callback failure must be returned")
+	}
+	if root.Get("sample") != nil || entry.Parent() != nil {
+		t.Errorf("the ancestors created for the data node must be removed by the rollback")
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=A]", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []EditOp{EditMerge, EditReplace, EditCreate} {
+		for _, path := range []string{"/sample/single-key-list[list-key=A]", "/sample/single-key-list[list-key=C]"} {
+			if err := SetValue(root, path, &EditOption{EditOp: op}, entry); err == nil {
+				t.Errorf("the list entry B must not be set to %s by %s", path, op)
+			}
+		}
+	}
+	if n, _ := Find(root, "/sample/single-key-list"); len(n) != 1 || n[0].ID() != "single-key-list[list-key=A]" {
+		t.Errorf("unexpected list entries: %v", n)
+	}
+	if err := SetValue(root, "/sample/single-key-list[list-key=B]", nil, entry); err != nil {
+		t.Errorf("the list entry B must be set: %v", err)
+	}
+}
+
+func benchmarkChurn(b *testing.B, usePool bool) {
+	jbytes, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{UsePool: usePool})
+	if err != nil {
+		b.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := UnmarshalJSON(root, jbytes); err != nil {
+			b.Fatal(err)
+		}
+		for _, child := range copyDataNodeList(root.Children()) {
+			child.Remove()
+		}
+	}
+}
+
+func BenchmarkChurn(b *testing.B)     { benchmarkChurn(b, false) }
+func BenchmarkChurnPool(b *testing.B) { benchmarkChurn(b, true) }
@@ -130,3 +130,125 @@ func Diff(node1, node2 DataNode) ([]DataNode, []DataNode, []DataNode) {
 	d := DiffCreated(node2, node1, false)
 	return c, r, d
 }
+
+// DiffOperation is the operation to apply a difference of data nodes.
+type DiffOperation string
+
+const (
+	// DiffCreate indicates the data node is created.
+	DiffCreate DiffOperation = "create"
+	// DiffReplace indicates the data node is replaced.
+	DiffReplace DiffOperation = "replace"
+	// DiffDelete indicates the data node is deleted.
+	DiffDelete DiffOperation = "delete"
+)
+
+// DiffEntry is a difference between data nodes with the operation.
+// The Node is the data node of node2 for DiffCreate and DiffReplace
+// and the data node of node1 for DiffDelete.
+type DiffEntry struct {
+	Operation DiffOperation
+	Node      DataNode
+}
+
+// DiffEntries() returns differences between nodes as a list of DiffEntry.
+// It returns all created, replaced and deleted nodes in node2 (including itself) against node1.
+func DiffEntries(node1, node2 DataNode) []DiffEntry {
+	c, r, d := Diff(node1, node2)
+	entries := make([]DiffEntry, 0, len(c)+len(r)+len(d))
+	for i := range c {
+		entries = append(entries, DiffEntry{Operation: DiffCreate, Node: c[i]})
+	}
+	for i := range r {
+		entries = append(entries, DiffEntry{Operation: DiffReplace, Node: r[i]})
+	}
+	for i := range d {
+		entries = append(entries, DiffEntry{Operation: DiffDelete, Node: d[i]})
+	}
+	return entries
+}
+
+// diffTree() returns a skeleton tree of the top node having the diff nodes and their ancestors.
+// The branch nodes in the skeleton tree only have their key nodes unless they have the diff nodes.
+func diffTree(top DataNode, nodes []DataNode) (DataNode, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	copied := map[DataNode]DataNode{}
+	copyNode := func(node DataNode) (DataNode, error) {
+		branch, ok := node.(*DataBranch)
+		if !ok {
+			return Clone(node), nil
+		}
+		c := newSkeletonNode(branch.schema)
+		for _, kname := range branch.schema.Keyname {
+			if key := branch.Get(kname); key != nil {
+				ckey, err := clone(c, key)
+				if err != nil {
+					return nil, err
+				}
+				copied[key] = ckey
+			}
+		}
+		return c, nil
+	}
+	tree, err := copyNode(top)
+	if err != nil {
+		return nil, err
+	}
+	copied[top] = tree
+	for _, node := range nodes {
+		var ancestors []DataNode
+		for n := node; n != nil && copied[n] == nil; n = n.Parent() {
+			ancestors = append(ancestors, n)
+		}
+		for i := len(ancestors) - 1; i >= 0; i-- {
+			n := ancestors[i]
+			parent := copied[n.Parent()]
+			if parent == nil {
+				return nil, Errorf(EAppTagInvalidArg, "%s is not a descendant of %s", node.Path(), top.Path())
+			}
+			c, err := copyNode(n)
+			if err != nil {
+				return nil, err
+			}
+			var iopt InsertOption
+			if n.Schema().IsOrderedByUser() {
+				iopt = InsertToLast{}
+			}
+			if _, err := parent.Insert(c, iopt); err != nil {
+				return nil, err
+			}
+			copied[n] = c
+		}
+	}
+	return tree, nil
+}
+
+// DiffTree() returns the differences between nodes as three skeleton trees having
+// the created (added), deleted (removed) and replaced (changed) data nodes
+// of node2 against node1. The ancestors of the differences are included in the trees
+// with their key nodes, so the trees can be marshalled for review.
+// A nil tree is returned if there is no difference of the kind.
+//   added, removed, changed, err := DiffTree(running, candidate)
+//   b, _ := MarshalJSON(added)
+func DiffTree(node1, node2 DataNode) (added, removed, changed DataNode, err error) {
+	if node1 != nil && node2 != nil && node1.Schema() != node2.Schema() {
+		return nil, nil, nil, Errorf(EAppTagInvalidArg, "unable to compare different schema (%s, %s)", node1, node2)
+	}
+	c, r, d := Diff(node1, node2)
+	if node2 != nil {
+		if added, err = diffTree(node2, c); err != nil {
+			return nil, nil, nil, err
+		}
+		if changed, err = diffTree(node2, r); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if node1 != nil {
+		if removed, err = diffTree(node1, d); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return added, removed, changed, nil
+}
@@ -78,3 +78,52 @@ func TestDiffUpdate(t *testing.T) {
 	b, _ := MarshalJSON(root)
 	t.Log(string(b))
 }
+
+func TestDiffTree(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTree := func(jstr string) DataNode {
+		node, err := NewWithValueString(schema, jstr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return node
+	}
+	node1 := newTree(`{"sample": {"str-val": "abc", "single-key-list": [
+		{"list-key": "A", "country-code": "KR"},
+		{"list-key": "B", "country-code": "US"}]}}`)
+	node2 := newTree(`{"sample": {"str-val": "xyz", "single-key-list": [
+		{"list-key": "A", "country-code": "JP"},
+		{"list-key": "C", "country-code": "CN"}]}}`)
+	added, removed, changed, err := DiffTree(node1, node2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name     string
+		got      DataNode
+		expected string
+	}{
+		{name: "added", got: added,
+			expected: `{"sample": {"single-key-list": [{"list-key": "C", "country-code": "CN"}]}}`},
+		{name: "removed", got: removed,
+			expected: `{"sample": {"single-key-list": [{"list-key": "B", "country-code": "US"}]}}`},
+		{name: "changed", got: changed,
+			expected: `{"sample": {"str-val": "xyz", "single-key-list": [{"list-key": "A", "country-code": "JP"}]}}`},
+	} {
+		if !Equal(tt.got, newTree(tt.expected)) {
+			b, _ := MarshalJSON(tt.got)
+			t.Errorf("unexpected %s tree: %s", tt.name, string(b))
+		}
+	}
+	// the source trees are not changed.
+	if v, _ := FindValueString(node1, "/sample/single-key-list[list-key=B]/country-code"); len(v) != 1 || v[0] != "US" {
+		t.Errorf("node1 must not be changed: %v", v)
+	}
+	added, removed, changed, err = DiffTree(node1, Clone(node1))
+	if err != nil || added != nil || removed != nil || changed != nil {
+		t.Errorf("no difference expected: %v, %v, %v, %v", added, removed, changed, err)
+	}
+}
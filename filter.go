@@ -0,0 +1,161 @@
+package yangtree
+
+import "fmt"
+
+// FilterSubtree() returns the data nodes selected by the subtree filter like the NETCONF subtree
+// filtering (https://datatracker.ietf.org/doc/html/rfc6241#section-6). The filter is a skeleton
+// data tree having the same schema of the data node and its nodes are interpreted as follows.
+//  - Containment node: a branch node having child nodes. The child nodes are filtered.
+//  - Selection node: an empty branch node or a leaf node without a value. All data nodes
+//    matched to the selection node are selected with their descendants.
+//  - Content match node: a leaf node having a value. The sibling data nodes are selected only
+//    if the data node having the same value exists. If the containment node has only content
+//    match nodes, all the siblings are selected.
+// It returns an empty data node if no data node is selected.
+func FilterSubtree(data, filter DataNode) (DataNode, error) {
+	if !IsValid(data) {
+		return nil, Errorf(EAppTagInvalidArg, "invalid data node")
+	}
+	if !IsValid(filter) {
+		return nil, Errorf(EAppTagInvalidArg, "invalid filter node")
+	}
+	if data.Schema() != filter.Schema() {
+		return nil, Errorf(EAppTagInvalidArg, "different schema of the filter %s for %s", filter, data)
+	}
+	if filter.Schema().IsRoot && len(filter.Children()) == 0 {
+		// an empty filter selects nothing.
+		return newFilteredBranch(data.(*DataBranch))
+	}
+	selected, err := filterSubtree(data, filter)
+	if err != nil {
+		return nil, err
+	}
+	if selected == nil {
+		if branch, ok := data.(*DataBranch); ok {
+			return newFilteredBranch(branch)
+		}
+		return New(data.Schema())
+	}
+	return selected, nil
+}
+
+// isContentMatchNode() returns true if the filter node is a content match node.
+func isContentMatchNode(filter DataNode) bool {
+	return filter.IsLeafNode() && filter.ValueString() != ""
+}
+
+// filterSubtree() returns the copy of the data node selected by the filter node.
+// It returns nil if the data node is not selected.
+func filterSubtree(data, filter DataNode) (DataNode, error) {
+	switch d := data.(type) {
+	case *DataBranch:
+		fchildren := filter.Children()
+		if len(fchildren) == 0 { // selection node
+			return Clone(data), nil
+		}
+		containment := false
+		for _, fchild := range fchildren {
+			if !isContentMatchNode(fchild) {
+				containment = true
+				continue
+			}
+			if !hasContentMatch(d, fchild) {
+				return nil, nil
+			}
+		}
+		if !containment { // only content match nodes
+			return Clone(data), nil
+		}
+		branch, err := newFilteredBranch(d)
+		if err != nil {
+			return nil, err
+		}
+		selected := false
+		for _, fchild := range fchildren {
+			i, max := indexRangeBySchema(d, fchild.Schema())
+			for ; i < max; i++ {
+				child, err := filterSubtree(d.children[i], fchild)
+				if err != nil {
+					return nil, err
+				}
+				if child == nil {
+					continue
+				}
+				if !isContentMatchNode(fchild) {
+					selected = true
+				}
+				if existing := branch.GetAll(child.ID()); len(existing) > 0 && !child.Schema().IsDuplicatable() {
+					if err := merge(existing[0], child); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				if _, err := branch.insert(child, nil); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if !selected {
+			return nil, nil
+		}
+		return branch, nil
+	case *DataLeaf:
+		if !isContentMatchNode(filter) || filter.ValueString() == data.ValueString() {
+			return Clone(data), nil
+		}
+		return nil, nil
+	case *DataLeafList:
+		values := filter.Values()
+		if len(values) == 0 {
+			return Clone(data), nil
+		}
+		leaflist := Clone(data).(*DataLeafList)
+		leaflist.value = nil
+		for _, v := range data.Values() {
+			for j := range values {
+				if ValueToValueString(v) == ValueToValueString(values[j]) {
+					leaflist.value = append(leaflist.value, v)
+					break
+				}
+			}
+		}
+		if len(leaflist.value) == 0 {
+			return nil, nil
+		}
+		return leaflist, nil
+	default:
+		return nil, fmt.Errorf("unable to filter %s", data)
+	}
+}
+
+// hasContentMatch() returns true if the branch has a child matched to the content match node.
+func hasContentMatch(branch *DataBranch, filter DataNode) bool {
+	i, max := indexRangeBySchema(branch, filter.Schema())
+	for ; i < max; i++ {
+		if branch.children[i].ValueString() == filter.ValueString() {
+			return true
+		}
+	}
+	return false
+}
+
+// newFilteredBranch() returns an empty copy of the branch having only the key nodes.
+func newFilteredBranch(data *DataBranch) (*DataBranch, error) {
+	n, err := New(data.schema)
+	if err != nil {
+		return nil, err
+	}
+	branch := n.(*DataBranch)
+	// remove the default nodes created with the new node.
+	if err := branch.Clear(); err != nil {
+		return nil, err
+	}
+	for _, kname := range data.schema.Keyname {
+		if key := data.Get(kname); key != nil {
+			if _, err := branch.insert(Clone(key), nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return branch, nil
+}
@@ -0,0 +1,347 @@
+// gnmi - provides the conversion between gNMI paths and yangtree data paths
+// and the gNMI Get and Set helpers for a yangtree data tree.
+package gnmi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neoul/yangtree"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// ToGNMIPath() converts a yangtree data path to a gNMI path.
+// The module name (prefix) of the first path element (MODULE:NODE) is set to the origin
+// of the gNMI path and the path elements are named without the module names.
+// The origin is set to the given origin if the first path element is not qualified.
+func ToGNMIPath(path string, origin ...string) (*gnmipb.Path, error) {
+	pathnode, err := yangtree.ParsePath(&path)
+	if err != nil {
+		return nil, err
+	}
+	gpath := &gnmipb.Path{}
+	if len(origin) > 0 {
+		gpath.Origin = origin[0]
+	}
+	for i := range pathnode {
+		var name string
+		switch pathnode[i].Select {
+		case yangtree.NodeSelectSelf:
+			continue
+		case yangtree.NodeSelectParent:
+			return nil, fmt.Errorf("parent node selection not supported in gNMI path %s", path)
+		case yangtree.NodeSelectAllChildren:
+			name = "*"
+		case yangtree.NodeSelectAll:
+			name = "..."
+		default:
+			name = pathnode[i].Name
+		}
+		if name == "" {
+			continue
+		}
+		if len(gpath.Elem) == 0 && pathnode[i].Prefix != "" {
+			if gpath.Origin != "" && gpath.Origin != pathnode[i].Prefix {
+				return nil, fmt.Errorf("module %s of %s not matched with origin %s",
+					pathnode[i].Prefix, name, gpath.Origin)
+			}
+			gpath.Origin = pathnode[i].Prefix
+		}
+		elem := &gnmipb.PathElem{Name: name}
+		if len(pathnode[i].Predicates) > 0 {
+			pmap, err := pathnode[i].ToMap()
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range pmap {
+				if k == "." || strings.HasPrefix(k, "@") {
+					return nil, fmt.Errorf("predicate of %s not supported in gNMI path", name)
+				}
+				if elem.Key == nil {
+					elem.Key = map[string]string{}
+				}
+				elem.Key[k] = v.(string)
+			}
+		}
+		gpath.Elem = append(gpath.Elem, elem)
+	}
+	return gpath, nil
+}
+
+// FromGNMIPath() converts a gNMI path to a yangtree data path.
+// The origin of the gNMI path becomes the module name (prefix) of the first path element.
+func FromGNMIPath(gpath *gnmipb.Path) string {
+	if gpath == nil {
+		return ""
+	}
+	var b strings.Builder
+	if len(gpath.GetElem()) > 0 {
+		b.WriteString("/")
+		if gpath.GetOrigin() != "" {
+			b.WriteString(gpath.GetOrigin())
+			b.WriteString(":")
+		}
+		writeGNMIPathElem(&b, gpath.GetElem())
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+// writeGNMIPathElem() writes the gNMI path elements to the data path.
+// The key values are escaped for the key predicates of the data path.
+func writeGNMIPathElem(b *strings.Builder, elems []*gnmipb.PathElem) {
+	for i, elem := range elems {
+		if i > 0 {
+			b.WriteString("/")
+		}
+		b.WriteString(elem.GetName())
+		keys := make([]string, 0, len(elem.GetKey()))
+		for k := range elem.GetKey() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b.WriteString("[")
+			b.WriteString(k)
+			b.WriteString("=")
+			b.WriteString(yangtree.EscapeKeyValue(elem.GetKey()[k]))
+			b.WriteString("]")
+		}
+	}
+}
+
+// ValidateGNMIPath() checks the gNMI path is valid against the schema.
+// The origin of the gNMI path must be the module name of the first path element if it is set.
+// The path elements following the first one are checked against the top-level schema
+// node selected by the origin.
+func ValidateGNMIPath(schema *yangtree.SchemaNode, gpath *gnmipb.Path) error {
+	if schema == nil {
+		return fmt.Errorf("nil schema")
+	}
+	if gpath == nil {
+		return nil
+	}
+	top, err := resolveOrigin(schema.GetRootSchema(), gpath)
+	if err != nil {
+		return err
+	}
+	path := FromGNMIPath(gpath)
+	if top != schema.GetRootSchema() {
+		// the rest of the path elements are found from the top-level schema node
+		// because the schema lookup doesn't distinguish the module names.
+		schema = top
+		var b strings.Builder
+		writeGNMIPathElem(&b, gpath.GetElem()[1:])
+		path = b.String()
+		if path == "" {
+			return nil
+		}
+	}
+	for _, p := range yangtree.FindAllPossiblePath(schema, path) {
+		if schema.FindSchema(p) != nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("schema not found for gNMI path %s", FromGNMIPath(gpath))
+}
+
+// resolveOrigin() returns the top-level schema node selected by the origin and
+// the first element of the gNMI path.
+func resolveOrigin(root *yangtree.SchemaNode, gpath *gnmipb.Path) (*yangtree.SchemaNode, error) {
+	elem := gpath.GetElem()
+	if len(elem) == 0 || !root.IsRoot {
+		return root, nil
+	}
+	name := elem[0].GetName()
+	if name == "*" || name == "..." {
+		if gpath.GetOrigin() != "" {
+			return nil, fmt.Errorf("wildcard %s not allowed with origin %s", name, gpath.GetOrigin())
+		}
+		return root, nil
+	}
+	if origin := gpath.GetOrigin(); origin != "" {
+		top := root.GetSchema(origin + ":" + name)
+		if top == nil || top.Module == nil || top.Module.Name != origin {
+			return nil, fmt.Errorf("schema %s not found from origin %s", name, origin)
+		}
+		return top, nil
+	}
+	top := root.GetSchema(name)
+	if top == nil {
+		return nil, fmt.Errorf("schema %s not found", name)
+	}
+	return top, nil
+}
+
+// Get() returns the data nodes selected by the gNMI path.
+// The top-level data node is selected by the origin of the gNMI path if it is set.
+func Get(root yangtree.DataNode, gpath *gnmipb.Path, option ...yangtree.Option) ([]yangtree.DataNode, error) {
+	if !yangtree.IsValid(root) {
+		return nil, fmt.Errorf("invalid root data node")
+	}
+	if _, err := resolveOrigin(root.Schema().GetRootSchema(), gpath); err != nil {
+		return nil, err
+	}
+	return yangtree.Find(root, FromGNMIPath(gpath), option...)
+}
+
+// Set() updates the data node selected by the gNMI path using the typed value.
+// The top-level data node is selected by the origin of the gNMI path if it is set.
+func Set(root yangtree.DataNode, gpath *gnmipb.Path, value *gnmipb.TypedValue) error {
+	if !yangtree.IsValid(root) {
+		return fmt.Errorf("invalid root data node")
+	}
+	if _, err := resolveOrigin(root.Schema().GetRootSchema(), gpath); err != nil {
+		return err
+	}
+	if value == nil {
+		return fmt.Errorf("nil value for gNMI path %s", FromGNMIPath(gpath))
+	}
+	vstr, err := typedValueToString(value)
+	if err != nil {
+		return err
+	}
+	return yangtree.SetValueString(root, FromGNMIPath(gpath), nil, vstr)
+}
+
+// typedValueToString() returns the yangtree value string of the gNMI typed value.
+func typedValueToString(value *gnmipb.TypedValue) (string, error) {
+	switch v := value.GetValue().(type) {
+	case *gnmipb.TypedValue_JsonIetfVal:
+		return string(v.JsonIetfVal), nil
+	case *gnmipb.TypedValue_JsonVal:
+		return string(v.JsonVal), nil
+	case *gnmipb.TypedValue_StringVal:
+		return v.StringVal, nil
+	case *gnmipb.TypedValue_IntVal:
+		return fmt.Sprint(v.IntVal), nil
+	case *gnmipb.TypedValue_UintVal:
+		return fmt.Sprint(v.UintVal), nil
+	case *gnmipb.TypedValue_BoolVal:
+		return fmt.Sprint(v.BoolVal), nil
+	case *gnmipb.TypedValue_FloatVal:
+		return fmt.Sprint(v.FloatVal), nil
+	case *gnmipb.TypedValue_AsciiVal:
+		return v.AsciiVal, nil
+	}
+	return "", fmt.Errorf("unsupported gNMI typed value %T", value.GetValue())
+}
+
+// BuildGetResponse() builds a gNMI GetResponse that has a notification for each requested path.
+// The data nodes found by the path are encoded to the updates of the notification.
+// Subtrees are encoded to the JSON or JSON_IETF typed values according to the encoding
+// and leaf values are encoded to the scalar typed values.
+func BuildGetResponse(root yangtree.DataNode, paths []*gnmipb.Path, enc gnmipb.Encoding) (*gnmipb.GetResponse, error) {
+	if !yangtree.IsValid(root) {
+		return nil, fmt.Errorf("invalid root data node")
+	}
+	switch enc {
+	case gnmipb.Encoding_JSON, gnmipb.Encoding_JSON_IETF, gnmipb.Encoding_ASCII:
+	default:
+		return nil, fmt.Errorf("unsupported encoding %s", enc)
+	}
+	timestamp := time.Now().UnixNano()
+	response := &gnmipb.GetResponse{}
+	for _, gpath := range paths {
+		nodes, err := Get(root, gpath)
+		if err != nil {
+			return nil, err
+		}
+		notification := &gnmipb.Notification{Timestamp: timestamp}
+		for _, node := range nodes {
+			upath, err := ToGNMIPath(node.Path(), gpath.GetOrigin())
+			if err != nil {
+				return nil, err
+			}
+			value, err := toTypedValue(node, enc)
+			if err != nil {
+				return nil, err
+			}
+			notification.Update = append(notification.Update, &gnmipb.Update{Path: upath, Val: value})
+		}
+		response.Notification = append(response.Notification, notification)
+	}
+	return response, nil
+}
+
+// toTypedValue() encodes the data node to a gNMI typed value.
+func toTypedValue(node yangtree.DataNode, enc gnmipb.Encoding) (*gnmipb.TypedValue, error) {
+	switch {
+	case node.IsBranchNode():
+		switch enc {
+		case gnmipb.Encoding_JSON:
+			b, err := yangtree.MarshalJSON(node)
+			if err != nil {
+				return nil, err
+			}
+			return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonVal{JsonVal: b}}, nil
+		case gnmipb.Encoding_JSON_IETF:
+			b, err := yangtree.MarshalJSON(node, yangtree.RFC7951Format{})
+			if err != nil {
+				return nil, err
+			}
+			return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{JsonIetfVal: b}}, nil
+		}
+		return nil, fmt.Errorf("subtree %s cannot be encoded in %s", node.Path(), enc)
+	case enc == gnmipb.Encoding_ASCII:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_AsciiVal{AsciiVal: node.ValueString()}}, nil
+	case node.HasMultipleValues():
+		values := node.Values()
+		leaflist := &gnmipb.ScalarArray{Element: make([]*gnmipb.TypedValue, 0, len(values))}
+		for i := range values {
+			leaflist.Element = append(leaflist.Element, toScalarTypedValue(values[i]))
+		}
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_LeaflistVal{LeaflistVal: leaflist}}, nil
+	}
+	return toScalarTypedValue(node.Value()), nil
+}
+
+// toScalarTypedValue() converts a leaf value to a scalar gNMI typed value.
+func toScalarTypedValue(value interface{}) *gnmipb.TypedValue {
+	switch v := value.(type) {
+	case bool:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_BoolVal{BoolVal: v}}
+	case int8:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: int64(v)}}
+	case int16:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: int64(v)}}
+	case int32:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: int64(v)}}
+	case int64:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_IntVal{IntVal: v}}
+	case uint8:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: uint64(v)}}
+	case uint16:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: uint64(v)}}
+	case uint32:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: uint64(v)}}
+	case uint64:
+		return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_UintVal{UintVal: v}}
+	}
+	return &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: yangtree.ValueToValueString(value)}}
+}
+
+// BuildCapabilitiesResponse() returns the gNMI CapabilityResponse built from the schema tree.
+// The modules loaded to the schema tree are reported as the supported models with their
+// organizations and revisions (as the version) and JSON_IETF and JSON are reported
+// as the supported encodings.
+func BuildCapabilitiesResponse(rootschema *yangtree.SchemaNode) *gnmipb.CapabilityResponse {
+	resp := &gnmipb.CapabilityResponse{
+		SupportedEncodings: []gnmipb.Encoding{gnmipb.Encoding_JSON_IETF, gnmipb.Encoding_JSON},
+	}
+	if rootschema == nil {
+		return resp
+	}
+	for _, m := range rootschema.LoadedModules() {
+		model := &gnmipb.ModelData{Name: m.Name, Version: m.Current()}
+		if m.Organization != nil {
+			model.Organization = m.Organization.Name
+		}
+		resp.SupportedModels = append(resp.SupportedModels, model)
+	}
+	return resp
+}
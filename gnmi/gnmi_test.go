@@ -0,0 +1,249 @@
+package gnmi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neoul/yangtree"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func TestGNMIPathOrigin(t *testing.T) {
+	schema, err := yangtree.Load([]string{"../testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := yangtree.New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gpath, err := ToGNMIPath("/sample:sample/single-key-list[list-key=A]/country-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpath.GetOrigin() != "sample" {
+		t.Errorf("ToGNMIPath() origin = %q, want %q", gpath.GetOrigin(), "sample")
+	}
+	if len(gpath.GetElem()) != 3 || gpath.GetElem()[0].GetName() != "sample" ||
+		gpath.GetElem()[1].GetKey()["list-key"] != "A" {
+		t.Errorf("ToGNMIPath() got unexpected path elements %v", gpath.GetElem())
+	}
+	if p := FromGNMIPath(gpath); p != "/sample:sample/single-key-list[list-key=A]/country-code" {
+		t.Errorf("FromGNMIPath() = %s", p)
+	}
+	if err := ValidateGNMIPath(schema, gpath); err != nil {
+		t.Errorf("ValidateGNMIPath() error = %v", err)
+	}
+
+	gpath, err = ToGNMIPath("/sample/single-key-list[list-key=A]/country-code", "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpath.GetOrigin() != "sample" {
+		t.Errorf("ToGNMIPath() origin = %q, want %q", gpath.GetOrigin(), "sample")
+	}
+	if p := FromGNMIPath(gpath); p != "/sample:sample/single-key-list[list-key=A]/country-code" {
+		t.Errorf("FromGNMIPath() = %s", p)
+	}
+	if err := ValidateGNMIPath(schema, gpath); err != nil {
+		t.Errorf("ValidateGNMIPath() error = %v", err)
+	}
+
+	if err := Set(root, gpath, nil); err == nil {
+		t.Errorf("Set() must reject the nil value")
+	}
+	if err := Set(root, gpath, &gnmipb.TypedValue{Value: &gnmipb.TypedValue_StringVal{StringVal: "KR"}}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	tests := []struct {
+		origin  string
+		elem    string
+		wantErr bool
+	}{
+		{origin: "sample", elem: "sample"},
+		{origin: "", elem: "sample"},
+		{origin: "leaf-list-test", elem: "sample", wantErr: true},
+		{origin: "leaf-list-test", elem: "single-leaf-list-ro"},
+		{origin: "sample", elem: "single-leaf-list-ro", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin+":"+tt.elem, func(t *testing.T) {
+			gpath := &gnmipb.Path{Origin: tt.origin, Elem: []*gnmipb.PathElem{{Name: tt.elem}}}
+			if err := ValidateGNMIPath(schema, gpath); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGNMIPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := Get(root, gpath); (err != nil) != tt.wantErr {
+				t.Errorf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	node, err := Get(root, gpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(node) != 1 || node[0].ValueString() != "KR" {
+		t.Errorf("Get() returns unexpected nodes %v", node)
+	}
+}
+
+func TestGNMIPathOriginModules(t *testing.T) {
+	schema, err := yangtree.Load([]string{
+		"../testdata/modules/origin-a.yang",
+		"../testdata/modules/origin-b.yang",
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, origin := range []string{"origin-a", "origin-b"} {
+		gpath, err := ToGNMIPath("/" + origin + ":common")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gpath.GetOrigin() != origin || len(gpath.GetElem()) != 1 || gpath.GetElem()[0].GetName() != "common" {
+			t.Errorf("ToGNMIPath() got unexpected path %v", gpath)
+		}
+		if p := FromGNMIPath(gpath); p != "/"+origin+":common" {
+			t.Errorf("FromGNMIPath() = %s", p)
+		}
+		top, err := resolveOrigin(schema, gpath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if top.Module == nil || top.Module.Name != origin {
+			t.Errorf("resolveOrigin() selects common of %v, want %s", top.Module, origin)
+		}
+	}
+	tests := []struct {
+		origin  string
+		leaf    string
+		wantErr bool
+	}{
+		{origin: "origin-a", leaf: "a-leaf"},
+		{origin: "origin-a", leaf: "b-leaf", wantErr: true},
+		{origin: "origin-b", leaf: "b-leaf"},
+		{origin: "origin-b", leaf: "a-leaf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin+":"+tt.leaf, func(t *testing.T) {
+			gpath := &gnmipb.Path{Origin: tt.origin, Elem: []*gnmipb.PathElem{{Name: "common"}, {Name: tt.leaf}}}
+			if err := ValidateGNMIPath(schema, gpath); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGNMIPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := ToGNMIPath("/origin-a:common", "origin-b"); err == nil {
+		t.Errorf("ToGNMIPath() must reject the module not matched with the origin")
+	}
+}
+
+func TestGNMIPathKeyEscape(t *testing.T) {
+	gpath := &gnmipb.Path{Elem: []*gnmipb.PathElem{
+		{Name: "sample"},
+		{Name: "single-key-list", Key: map[string]string{"list-key": `A[1]\B`}},
+	}}
+	p := FromGNMIPath(gpath)
+	if p != `/sample/single-key-list[list-key=A\[1\]\\B]` {
+		t.Errorf("FromGNMIPath() = %s", p)
+	}
+	rpath, err := ToGNMIPath(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpath.GetElem()[1].GetKey()["list-key"] != `A[1]\B` {
+		t.Errorf("ToGNMIPath() got unexpected key %v", rpath.GetElem()[1].GetKey())
+	}
+}
+
+func TestBuildGetResponse(t *testing.T) {
+	schema, err := yangtree.Load([]string{"../testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := yangtree.New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yangtree.SetValueString(root, "/sample/single-key-list[list-key=A]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+	if err := yangtree.SetValueString(root, "/sample/single-key-list[list-key=A]/uint32-range", nil, "100"); err != nil {
+		t.Fatal(err)
+	}
+	var paths []*gnmipb.Path
+	for _, p := range []string{
+		"/sample/single-key-list[list-key=A]/country-code",
+		"/sample/single-key-list[list-key=A]/uint32-range",
+		"/sample/single-key-list[list-key=A]",
+		"/sample/single-key-list[list-key=B]",
+	} {
+		gpath, err := ToGNMIPath(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, gpath)
+	}
+	response, err := BuildGetResponse(root, paths, gnmipb.Encoding_JSON_IETF)
+	if err != nil {
+		t.Fatalf("BuildGetResponse() error = %v", err)
+	}
+	if len(response.GetNotification()) != len(paths) {
+		t.Fatalf("BuildGetResponse() returns %d notifications, want %d", len(response.GetNotification()), len(paths))
+	}
+	for _, n := range response.GetNotification() {
+		if n.GetTimestamp() == 0 {
+			t.Errorf("BuildGetResponse() returns a notification without timestamp")
+		}
+	}
+	updates := response.GetNotification()[0].GetUpdate()
+	if len(updates) != 1 || updates[0].GetVal().GetStringVal() != "KR" {
+		t.Errorf("BuildGetResponse() returns unexpected leaf update %v", updates)
+	} else if p := FromGNMIPath(updates[0].GetPath()); p != "/sample/single-key-list[list-key=A]/country-code" {
+		t.Errorf("BuildGetResponse() returns unexpected update path %s", p)
+	}
+	updates = response.GetNotification()[1].GetUpdate()
+	if len(updates) != 1 || updates[0].GetVal().GetUintVal() != 100 {
+		t.Errorf("BuildGetResponse() returns unexpected leaf update %v", updates)
+	}
+	updates = response.GetNotification()[2].GetUpdate()
+	if len(updates) != 1 || !strings.Contains(string(updates[0].GetVal().GetJsonIetfVal()), `"country-code":"KR"`) {
+		t.Errorf("BuildGetResponse() returns unexpected subtree update %v", updates)
+	}
+	if len(response.GetNotification()[3].GetUpdate()) != 0 {
+		t.Errorf("BuildGetResponse() returns updates for a non-existent path")
+	}
+	if _, err := BuildGetResponse(root, paths, gnmipb.Encoding_BYTES); err == nil {
+		t.Errorf("BuildGetResponse() must fail for unsupported encoding")
+	}
+}
+
+func TestBuildCapabilitiesResponse(t *testing.T) {
+	schema, err := yangtree.Load([]string{"../testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := BuildCapabilitiesResponse(schema)
+	models := map[string]*gnmipb.ModelData{}
+	for _, m := range resp.GetSupportedModels() {
+		models[m.GetName()] = m
+	}
+	for name, m := range schema.Modules.Modules {
+		if m.BelongsTo != nil {
+			continue
+		}
+		if models[m.Name] == nil {
+			t.Errorf("module %s (%s) not found in the supported models", m.Name, name)
+		}
+	}
+	if m := models["sample"]; m == nil || m.GetVersion() != schema.Modules.Modules["sample"].Current() {
+		t.Errorf("unexpected sample model: %v", m)
+	}
+	if len(models) != len(resp.GetSupportedModels()) {
+		t.Errorf("duplicated models in the supported models: %v", resp.GetSupportedModels())
+	}
+	encodings := resp.GetSupportedEncodings()
+	if len(encodings) != 2 || encodings[0] != gnmipb.Encoding_JSON_IETF || encodings[1] != gnmipb.Encoding_JSON {
+		t.Errorf("unexpected supported encodings: %v", encodings)
+	}
+}
@@ -19,6 +19,8 @@ type DataNode interface {
 
 	Name() string                      // Name() returns the name of the data node.
 	QName(rfc7951 bool) (string, bool) // QName() returns the namespace-qualified name e.g. module-name:node-name or module-prefix:node-name.
+	ModuleName() string                // ModuleName() returns the name of the module that defines the data node.
+	Namespace() string                 // Namespace() returns the namespace URI of the module that defines the data node.
 	ID() string                        // ID() returns the data node ID (NODE[KEY=VALUE]). The ID is an XPath element combined with XPath predicates to identify the node instance.
 
 	Schema() *SchemaNode  // Schema() returns the schema of the data node.
@@ -56,6 +58,7 @@ type DataNode interface {
 	String() string                    // String() returns a string to identify the node.
 	Path() string                      // Path() returns the path from the root to the current data node.
 	PathTo(descendant DataNode) string // PathTo() returns a relative path to a descendant node.
+	Equal(other DataNode) bool         // Equal() returns true if the data node is equal to the other including all children.
 
 	SetValue(value ...interface{}) error     // SetValue() writes the values to the data node.
 	SetValueSafe(value ...interface{}) error // SetValueSafe() writes the values to the data node. It will recover the value if failed.
@@ -98,7 +101,37 @@ type YANGTreeOption struct {
 	YANGLibrary2016    bool   // Load ietf-yang-library@2016-06-21
 	YANGLibrary2019    bool   // Load ietf-yang-library@2019-01-04
 	SchemaSetName      string // The name of the schema set
+	// Enum, bits and identityref values are matched case-insensitively if it is set
+	// and then stored with the schema-declared spelling.
+	CaseInsensitiveEnum bool
+	// Unknown enum values are stored as they are and reported as the warnings
+	// of the schema tree (SchemaNode.Warnings()) instead of being rejected if it is set.
+	LenientEnum bool
+	// The enabled YANG features. If it is set (even if empty), the schema nodes whose
+	// if-feature statements are not satisfied are excluded from the schema tree.
+	// The feature can be represented with its module name. e.g. "module-name:feature-name"
+	// The feature without the module name enables the features having the name in all modules.
+	Features []string
+	// The top-level paths of the schema tree to be built. If it is set, only the top-level
+	// schema nodes and their descendants are built and the others are skipped.
+	// e.g. []string{"/interfaces", "/ietf-system:system"}
+	RootFilter []string
+	// The modified metadata (yangtree:modified) of the changed data node and its ancestors
+	// are updated with the current time whenever the data node is changed if it is set.
+	TrackModifyTime bool
+	// The dirty flags of the changed data node and its ancestors are set whenever
+	// the data node is changed, moved or removed if it is set. They are cleared by MarkClean().
+	TrackDirty bool
+	// The top-level schema node having the same name with another module's top-level node is
+	// renamed with its module prefix (e.g. "prefix_name") instead of failing if it is set.
+	PrefixDuplicatedTopNodes bool
+	// The data nodes are allocated from the pools and released to the pools by Remove()
+	// to reduce the garbage of high-churn data trees if it is set. The removed data nodes
+	// and their descendants are reset and reused, so they must not be used after Remove().
+	UsePool bool
 	// DefaultValueString [json, yaml, xml]
+
+	warnings *warnings // used to accumulate the warnings of the schema tree.
 }
 
 func (yangtreeOption YANGTreeOption) IsOption() {}
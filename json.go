@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/goccy/go-json"
@@ -15,6 +16,70 @@ type RFC7951Format struct{}
 
 func (f RFC7951Format) IsOption() {}
 
+// OmitKeyLeaves option is used to omit the key leaves of the list entries that are
+// already represented by the JSON object keys. It is ignored in RFC7951 format.
+type OmitKeyLeaves struct{}
+
+func (f OmitKeyLeaves) IsOption() {}
+
+// SkipEmpty option is used to omit empty non-presence containers.
+// Presence containers are always printed out even if they are empty.
+type SkipEmpty struct{}
+
+func (f SkipEmpty) IsOption() {}
+
+// NumbersAsStrings option is used to encode all integer and decimal64 values
+// to JSON strings in RFC7951 format.
+type NumbersAsStrings struct{}
+
+func (f NumbersAsStrings) IsOption() {}
+
+// DirtyOnly option is used to encode only the changed (dirty) data nodes and their ancestors
+// since the last MarkClean(). The key leaves of the changed list entries are always encoded
+// to identify them. The removed data nodes are encoded to null (the removed list entries are
+// only encoded in the object format). It requires YANGTreeOption.TrackDirty.
+type DirtyOnly struct{}
+
+func (f DirtyOnly) IsOption() {}
+
+// NamespaceMode option is used to decide which data node names are qualified
+// by their module names in RFC7951 format.
+type NamespaceMode int
+
+const (
+	// NamespaceBoundary - qualify the names of the top nodes and the nodes at module boundaries (RFC7951).
+	NamespaceBoundary NamespaceMode = iota
+	// NamespaceAlways - qualify the names of all data nodes.
+	NamespaceAlways
+	// NamespaceTopOnly - qualify the names of the top nodes only.
+	NamespaceTopOnly
+)
+
+func (mode NamespaceMode) IsOption() {}
+
+func (mode NamespaceMode) String() string {
+	switch mode {
+	case NamespaceBoundary:
+		return "namespace.boundary"
+	case NamespaceAlways:
+		return "namespace.always"
+	case NamespaceTopOnly:
+		return "namespace.top-only"
+	}
+	return "namespace.unknown"
+}
+
+// qualify() returns true if the name of the schema node should be qualified.
+func (mode NamespaceMode) qualify(schema *SchemaNode, top bool) bool {
+	switch mode {
+	case NamespaceAlways:
+		return true
+	case NamespaceTopOnly:
+		return top
+	}
+	return top || schema.Qboundary
+}
+
 // RFC7951S (rfc7951 processing status)
 type RFC7951S int
 
@@ -42,16 +107,27 @@ func (s RFC7951S) String() string {
 type jsonNode struct {
 	DataNode
 	RFC7951S
-	ConfigOnly yang.TriState
-	printMeta  bool
+	ConfigOnly    yang.TriState
+	printMeta     bool
+	omitKeyLeaves bool // omit the key leaves of list entries in the object format
+	keyedEntry    bool // used to indicate the node is a list entry represented in the object format
+	skipEmpty     bool // omit empty non-presence containers
+	nsMode        NamespaceMode
+	numbersAsStr  bool // encode all integer and decimal64 values to strings in RFC7951 format
+	dirtyOnly     bool // encode only the changed data nodes
+	withDefaults  bool // encode the absent leaf nodes having the default values
+	numericKeys   bool // encode the list entries in the numeric order of the keys
 }
 
+// getQname() returns the name of the node, qualified by its module name if required.
+// It only updates the RFC7951S of the jnode that is allocated or copied for each marshalling,
+// so the data tree is not modified and can be marshalled concurrently.
 func (jnode *jsonNode) getQname() string {
 	switch jnode.RFC7951S {
 	case RFC7951InProgress, RFC7951Enabled:
-		if qname, boundary := jnode.Schema().GetQName(true); boundary ||
-			jnode.RFC7951S == RFC7951Enabled {
+		if jnode.nsMode.qualify(jnode.Schema(), jnode.RFC7951S == RFC7951Enabled) {
 			jnode.RFC7951S = RFC7951InProgress
+			qname, _ := jnode.Schema().GetQName(true)
 			return qname
 		}
 		return jnode.Schema().Name
@@ -59,6 +135,12 @@ func (jnode *jsonNode) getQname() string {
 	return jnode.Schema().Name
 }
 
+// isRemoved() returns true if the node is the removed child of the branch encoded with DirtyOnly.
+func (jnode *jsonNode) isRemoved(node DataNode) bool {
+	branch, ok := jnode.DataNode.(*DataBranch)
+	return ok && branch.isRemoved(node)
+}
+
 func (jnode *jsonNode) MarshalJSON() ([]byte, error) {
 	var buffer bytes.Buffer
 	_, err := jnode.marshalJSON(&buffer, false, false, false)
@@ -148,7 +230,18 @@ func (jnode *jsonNode) marshalJSON(buffer *bytes.Buffer, comma, printName, skipR
 	case jnode.IsBranchNode():
 		cjnode := *jnode
 		childcomma := false
-		children := jnode.Children()
+		children, err := childrenWithDefaults(jnode.DataNode, jnode.withDefaults)
+		if err != nil {
+			return comma, err
+		}
+		if jnode.dirtyOnly {
+			if branch, ok := jnode.DataNode.(*DataBranch); ok {
+				children = branch.childrenWithRemoved(children)
+			}
+		}
+		if jnode.numericKeys {
+			children = sortByNumericKeys(children)
+		}
 		if !skipRoot {
 			buffer.WriteString(`{`)
 		}
@@ -169,8 +262,36 @@ func (jnode *jsonNode) marshalJSON(buffer *bytes.Buffer, comma, printName, skipR
 				i++
 				continue
 			}
+			if jnode.keyedEntry && jnode.omitKeyLeaves && schema.IsKey {
+				// skip the key leaf already represented by the object key
+				i++
+				continue
+			}
+			if jnode.skipEmpty && isEmptyContainer(children[i]) {
+				i++
+				continue
+			}
+			if jnode.dirtyOnly && !schema.IsKey && !IsDirty(children[i]) {
+				i++
+				continue
+			}
+			if jnode.dirtyOnly && jnode.isRemoved(children[i]) {
+				// the removed node is encoded to null.
+				cjnode.DataNode = children[i]
+				cjnode.RFC7951S = jnode.RFC7951S
+				if childcomma {
+					buffer.WriteString(",")
+				}
+				childcomma = true
+				buffer.WriteString(`"`)
+				buffer.WriteString(cjnode.getQname())
+				buffer.WriteString(`":null`)
+				i++
+				continue
+			}
 			cjnode.DataNode = children[i]
 			cjnode.RFC7951S = jnode.RFC7951S
+			cjnode.keyedEntry = false
 
 			if childcomma, err = cjnode.marshalJSON(buffer, childcomma, true, false); err != nil {
 				return comma, err
@@ -206,7 +327,7 @@ func (jnode *jsonNode) marshalJSON(buffer *bytes.Buffer, comma, printName, skipR
 			if valcomma {
 				buffer.WriteString(",")
 			}
-			b, err := schema.ValueToJSONBytes(schema.Type, value[i], jnode.RFC7951S != RFC7951Disabled)
+			b, err := schema.valueToJSONBytes(schema.Type, value[i], jnode.RFC7951S != RFC7951Disabled, jnode.numbersAsStr)
 			if err != nil {
 				return false, err
 			}
@@ -216,7 +337,7 @@ func (jnode *jsonNode) marshalJSON(buffer *bytes.Buffer, comma, printName, skipR
 		buffer.WriteString("]")
 	case jnode.IsLeafNode(): // leaf, multiple leaf-list schema node
 		schema := jnode.Schema()
-		b, err := schema.ValueToJSONBytes(schema.Type, jnode.Value(), jnode.RFC7951S != RFC7951Disabled)
+		b, err := schema.valueToJSONBytes(schema.Type, jnode.Value(), jnode.RFC7951S != RFC7951Disabled, jnode.numbersAsStr)
 		if err != nil {
 			return comma, err
 		}
@@ -225,6 +346,22 @@ func (jnode *jsonNode) marshalJSON(buffer *bytes.Buffer, comma, printName, skipR
 	return comma, nil
 }
 
+// isEmptyContainer() returns true if the node is a non-presence container
+// that has no data node except empty non-presence containers.
+func isEmptyContainer(node DataNode) bool {
+	schema := node.Schema()
+	if !node.IsBranchNode() || !schema.IsContainer() || schema.IsPresence() {
+		return false
+	}
+	children := node.Children()
+	for i := range children {
+		if !isEmptyContainer(children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (parent *jsonNode) marshalJSONListableNode(buffer *bytes.Buffer, node []DataNode, i int, comma bool, skipRoot bool) (int, bool, error) {
 	first := *parent
 	first.DataNode = node[i]
@@ -247,6 +384,16 @@ func (parent *jsonNode) marshalJSONListableNode(buffer *bytes.Buffer, node []Dat
 			}
 		}
 	}
+	if first.dirtyOnly {
+		dirty := false
+		j := i
+		for ; j < len(node) && schema == node[j].Schema(); j++ {
+			dirty = dirty || IsDirty(node[j])
+		}
+		if !dirty {
+			return j, comma, nil
+		}
+	}
 	if !skipRoot {
 		if comma {
 			buffer.WriteString(",")
@@ -270,11 +417,17 @@ func (parent *jsonNode) marshalJSONListableNode(buffer *bytes.Buffer, node []Dat
 		}
 		nodelist := make([]interface{}, 0, i-ii)
 		for ; ii < i; ii++ {
+			if first.dirtyOnly && (!IsDirty(node[ii]) || parent.isRemoved(node[ii])) {
+				// the removed entries are not encoded in the array format.
+				continue
+			}
 			jnode := &jsonNode{DataNode: node[ii], ConfigOnly: first.ConfigOnly,
-				RFC7951S: first.RFC7951S, printMeta: printMeta}
+				RFC7951S: first.RFC7951S, printMeta: printMeta, omitKeyLeaves: first.omitKeyLeaves,
+				skipEmpty: first.skipEmpty, nsMode: first.nsMode, numbersAsStr: first.numbersAsStr,
+				dirtyOnly: first.dirtyOnly, numericKeys: first.numericKeys}
 			nodelist = append(nodelist, jnode)
 		}
-		err := marshalJNodeTree(buffer, nodelist)
+		err := marshalJNodeTree(buffer, nodelist, first.numericKeys)
 		if err == nil {
 			// marshalling metadata of a leaf-list
 			if first.printMeta && schema.IsLeafList() {
@@ -323,10 +476,16 @@ func (parent *jsonNode) marshalJSONListableNode(buffer *bytes.Buffer, node []Dat
 	nodemap := map[string]interface{}{}
 	for ; i < len(node); i++ {
 		jnode := &jsonNode{DataNode: node[i], ConfigOnly: first.ConfigOnly,
-			RFC7951S: first.RFC7951S, printMeta: first.printMeta}
+			RFC7951S: first.RFC7951S, printMeta: first.printMeta,
+			omitKeyLeaves: first.omitKeyLeaves, keyedEntry: true, skipEmpty: first.skipEmpty,
+			nsMode: first.nsMode, numbersAsStr: first.numbersAsStr, dirtyOnly: first.dirtyOnly,
+			numericKeys: first.numericKeys}
 		if schema != jnode.Schema() {
 			break
 		}
+		if first.dirtyOnly && !IsDirty(jnode.DataNode) {
+			continue
+		}
 		keyname, keyval := GetKeyValues(jnode.DataNode)
 		if len(keyname) != len(keyval) {
 			return i, comma, fmt.Errorf("list %s doesn't have key value pairs", schema.Name)
@@ -341,16 +500,20 @@ func (parent *jsonNode) marshalJSONListableNode(buffer *bytes.Buffer, node []Dat
 				} else {
 					m = n.(map[string]interface{})
 				}
+			} else if first.dirtyOnly && parent.isRemoved(jnode.DataNode) {
+				m[keyval[x]] = &jsonNode{} // the removed entry is encoded to null.
 			} else {
 				m[keyval[x]] = jnode
 			}
 		}
 	}
-	err := marshalJNodeTree(buffer, nodemap)
+	err := marshalJNodeTree(buffer, nodemap, first.numericKeys)
 	return i, comma, err
 }
 
-func marshalJNodeTree(buffer *bytes.Buffer, jnodeTree interface{}) error {
+// marshalJNodeTree() encodes the list entries in the array or object format.
+// The object keys are sorted lexically or numerically if numericKeys is set.
+func marshalJNodeTree(buffer *bytes.Buffer, jnodeTree interface{}, numericKeys bool) error {
 	comma := false
 	switch jj := jnodeTree.(type) {
 	case map[string]interface{}:
@@ -359,7 +522,16 @@ func marshalJNodeTree(buffer *bytes.Buffer, jnodeTree interface{}) error {
 		for key := range jj {
 			k = append(k, key)
 		}
-		sort.Slice(k, func(i, j int) bool { return k[i] < k[j] })
+		sort.Slice(k, func(i, j int) bool {
+			if numericKeys {
+				a, erra := strconv.ParseFloat(k[i], 64)
+				b, errb := strconv.ParseFloat(k[j], 64)
+				if erra == nil && errb == nil && a != b {
+					return a < b
+				}
+			}
+			return k[i] < k[j]
+		})
 		for i := range k {
 			if comma {
 				buffer.WriteString(",")
@@ -368,7 +540,7 @@ func marshalJNodeTree(buffer *bytes.Buffer, jnodeTree interface{}) error {
 			buffer.WriteString(`"`)
 			buffer.WriteString(k[i])
 			buffer.WriteString(`":`)
-			if err := marshalJNodeTree(buffer, jj[k[i]]); err != nil {
+			if err := marshalJNodeTree(buffer, jj[k[i]], numericKeys); err != nil {
 				return err
 			}
 		}
@@ -380,7 +552,7 @@ func marshalJNodeTree(buffer *bytes.Buffer, jnodeTree interface{}) error {
 				buffer.WriteString(",")
 			}
 			comma = true
-			if err := marshalJNodeTree(buffer, jj[i]); err != nil {
+			if err := marshalJNodeTree(buffer, jj[i], numericKeys); err != nil {
 				return err
 			}
 		}
@@ -421,7 +593,7 @@ func unmarshalJSONListNode(parent DataNode, cschema *SchemaNode, kname []string,
 		idBuilder.WriteString("[")
 		idBuilder.WriteString(kname[i])
 		idBuilder.WriteString("=")
-		idBuilder.WriteString(ValueToValueString(kval[i]))
+		idBuilder.WriteString(escapeKeyValue(ValueToValueString(kval[i])))
 		idBuilder.WriteString("]")
 	}
 	var child, found DataNode
@@ -468,10 +640,35 @@ func unmarshalJSONListableNode(parent DataNode, cschema *SchemaNode, kname []str
 		}
 		return nil
 	}
+	// the new list entries are inserted at once to avoid sorting for each entry.
+	created := make([]DataNode, 0, len(arrary))
+	createdByID := make(map[string]DataNode, len(arrary))
+	insertCreated := func() error {
+		if branch, ok := parent.(*DataBranch); ok {
+			if err := branch.BulkInsert(created); err != nil {
+				return err
+			}
+		} else {
+			for i := range created {
+				if _, err := parent.Insert(created[i], nil); err != nil {
+					return err
+				}
+			}
+		}
+		return setCreatedDefaults(created)
+	}
+	// failed() inserts the list entries created before the error and returns the error
+	// combined with the insertion error.
+	failed := func(err error) error {
+		if ierr := insertCreated(); ierr != nil {
+			return MultipleError{err, ierr}
+		}
+		return err
+	}
 	for i := range arrary {
 		entry, ok := arrary[i].(map[string]interface{})
 		if !ok {
-			return fmt.Errorf("unexpected yaml value %T for %s", arrary[i], cschema.Name)
+			return failed(fmt.Errorf("unexpected yaml value %T for %s", arrary[i], cschema.Name))
 		}
 
 		var err error
@@ -483,13 +680,13 @@ func unmarshalJSONListableNode(parent DataNode, cschema *SchemaNode, kname []str
 				kcschema := cschema.GetSchema(kname[i])
 				qname, _ := kcschema.GetQName(true)
 				if kvalue = entry[qname]; kvalue == nil {
-					return fmt.Errorf("not found key data node %s from %v", kname[i], entry)
+					return failed(fmt.Errorf("not found key data node %s from %v", kname[i], entry))
 				}
 			}
 			idBuilder.WriteString("[")
 			idBuilder.WriteString(kname[i])
 			idBuilder.WriteString("=")
-			idBuilder.WriteString(fmt.Sprint(kvalue))
+			idBuilder.WriteString(escapeKeyValue(fmt.Sprint(kvalue)))
 			idBuilder.WriteString("]")
 		}
 		id := idBuilder.String()
@@ -498,24 +695,26 @@ func unmarshalJSONListableNode(parent DataNode, cschema *SchemaNode, kname []str
 			child = nil
 		} else if found = parent.Get(id); found != nil {
 			child = found
+		} else if found = createdByID[id]; found != nil {
+			child = found
 		}
 		if child == nil {
 			child, err = NewWithID(cschema, id)
 			if err != nil {
-				return err
-			}
-			if _, err = parent.Insert(child, nil); err != nil {
-				return err
+				return failed(err)
 			}
 		}
 		if err := unmarshalJSON(child, cschema, arrary[i]); err != nil {
-			if found == nil {
-				parent.Delete(child)
+			return failed(err)
+		}
+		if found == nil {
+			created = append(created, child)
+			if !cschema.IsDuplicatableList() {
+				createdByID[id] = child
 			}
-			return err
 		}
 	}
-	return nil
+	return insertCreated()
 }
 
 // func unmarshalJSONMeta(node DataNode, metakey string, jval interface{}) error {
@@ -635,6 +834,12 @@ func unmarshalJSON(node DataNode, schema *SchemaNode, jval interface{}) error {
 					}
 				}
 			}
+			if branch, ok := node.(*DataBranch); ok && IsCreatedWithDefault(schema) {
+				// re-evaluate the conditional defaults with the updated data.
+				if err := branch.setDefaults(); err != nil {
+					return Error(EAppTagJSONParsing, err)
+				}
+			}
 			return nil
 		case []interface{}:
 			for i := range entry {
@@ -683,7 +888,7 @@ func MarshalJSON(node DataNode, option ...Option) ([]byte, error) {
 	var representItself bool
 	jnode := &jsonNode{DataNode: node}
 	for i := range option {
-		switch option[i].(type) {
+		switch o := option[i].(type) {
 		case HasState:
 			return nil, fmt.Errorf("%v is not allowed for marshaling", option[i])
 		case ConfigOnly:
@@ -696,6 +901,20 @@ func MarshalJSON(node DataNode, option ...Option) ([]byte, error) {
 			representItself = true
 		case Metadata:
 			jnode.printMeta = true
+		case OmitKeyLeaves:
+			jnode.omitKeyLeaves = true
+		case SkipEmpty:
+			jnode.skipEmpty = true
+		case NamespaceMode:
+			jnode.nsMode = o
+		case NumbersAsStrings:
+			jnode.numbersAsStr = true
+		case DirtyOnly:
+			jnode.dirtyOnly = true
+		case WithDefaults:
+			jnode.withDefaults = true
+		case NumericKeyAware:
+			jnode.numericKeys = true
 		}
 	}
 	skipRoot := false
@@ -721,7 +940,7 @@ func MarshalJSONIndent(node DataNode, prefix, indent string, option ...Option) (
 	var representItself bool
 	jnode := &jsonNode{DataNode: node}
 	for i := range option {
-		switch option[i].(type) {
+		switch o := option[i].(type) {
 		case HasState:
 			return nil, fmt.Errorf("%v is not allowed for marshaling", option[i])
 		case ConfigOnly:
@@ -734,6 +953,20 @@ func MarshalJSONIndent(node DataNode, prefix, indent string, option ...Option) (
 			representItself = true
 		case Metadata:
 			jnode.printMeta = true
+		case OmitKeyLeaves:
+			jnode.omitKeyLeaves = true
+		case SkipEmpty:
+			jnode.skipEmpty = true
+		case NamespaceMode:
+			jnode.nsMode = o
+		case NumbersAsStrings:
+			jnode.numbersAsStr = true
+		case DirtyOnly:
+			jnode.dirtyOnly = true
+		case WithDefaults:
+			jnode.withDefaults = true
+		case NumericKeyAware:
+			jnode.numericKeys = true
 		}
 	}
 	skipRoot := false
@@ -786,6 +1019,217 @@ func UnmarshalJSON(node DataNode, jbytes []byte, option ...Option) error {
 	return unmarshalJSON(node, node.Schema(), jval)
 }
 
+// UnmarshalJSONGzip() decompresses the gzip-compressed JSON data and then stores the result
+// in the data node like UnmarshalJSON().
+func UnmarshalJSONGzip(node DataNode, gz []byte, option ...Option) error {
+	jbytes, err := Unzip(gz)
+	if err != nil {
+		return Errorf(EAppTagJSONParsing, "gzip decompression failed: %v", err)
+	}
+	return UnmarshalJSON(node, jbytes, option...)
+}
+
+// ConvertJSON() converts the JSON-encoded data of the schema to RFC 7951 JSON if toRFC7951 is set
+// or to the plain JSON if not. The data is loaded into a temporal data tree and then re-marshalled.
+func ConvertJSON(schema *SchemaNode, in []byte, toRFC7951 bool) ([]byte, error) {
+	node, err := New(schema)
+	if err != nil {
+		return nil, err
+	}
+	if err := UnmarshalJSON(node, in); err != nil {
+		return nil, err
+	}
+	if toRFC7951 {
+		return MarshalJSON(node, RFC7951Format{})
+	}
+	return MarshalJSON(node)
+}
+
+// UnmarshalJSONChanges() merges the JSON bytes to the data node like UnmarshalJSON()
+// and returns the created or updated leaf nodes. The leaf-list nodes are only reported if created.
+func UnmarshalJSONChanges(node DataNode, jbytes []byte) ([]DataNode, error) {
+	if !IsValid(node) {
+		return nil, fmt.Errorf("invalid data node")
+	}
+	backup := Clone(node)
+	if err := UnmarshalJSON(node, jbytes); err != nil {
+		return nil, err
+	}
+	created, replaced := DiffUpdated(backup, node, false)
+	changes := make([]DataNode, 0, len(created)+len(replaced))
+	for _, n := range append(created, replaced...) {
+		if n.IsLeafNode() {
+			changes = append(changes, n)
+		}
+	}
+	return changes, nil
+}
+
+// MergeJSON() merges the JSON-encoded data to the data node in the path.
+// The data node in the path is created if it doesn't exist.
+func MergeJSON(root DataNode, path string, jbytes []byte) error {
+	node, created, err := GetOrNew(root, path)
+	if err != nil {
+		return err
+	}
+	if err := UnmarshalJSON(node, jbytes); err != nil {
+		if created != nil {
+			detach(created)
+		}
+		return err
+	}
+	return nil
+}
+
+// ApplyMergePatch() applies the JSON merge patch (RFC7396) to the data node.
+// The members of the patch object are merged to the data node recursively and
+// the members having null are deleted. The arrays (lists and leaf-lists in the array format)
+// replace the existing data nodes. The list entries in the object format (keyed by the key values)
+// are patched respectively and the list entries having null are deleted.
+func ApplyMergePatch(node DataNode, patch []byte) error {
+	if !IsValid(node) {
+		return Errorf(EAppTagInvalidArg, "invalid data node")
+	}
+	var jval interface{}
+	if err := json.Unmarshal(patch, &jval); err != nil {
+		return Error(EAppTagJSONParsing, err)
+	}
+	return applyMergePatch(node, jval)
+}
+
+func applyMergePatch(node DataNode, jval interface{}) error {
+	branch, ok := node.(*DataBranch)
+	if !ok {
+		// a non-object patch replaces the target value.
+		return unmarshalJSON(node, node.Schema(), jval)
+	}
+	patch, ok := jval.(map[string]interface{})
+	if !ok {
+		return Errorf(EAppTagJSONParsing, "merge patch for %s must be an object", branch)
+	}
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.HasPrefix(k, "@") {
+			continue // metadata
+		}
+		cschema := branch.schema.GetSchema(k)
+		if cschema == nil {
+			return Errorf(ETagUnknownElement, "schema %s not found from %s", k, branch.schema.Name)
+		}
+		v := patch[k]
+		switch vv := v.(type) {
+		case nil:
+			if err := removeChildrenBySchema(branch, cschema); err != nil {
+				return err
+			}
+		case []interface{}:
+			if cschema.IsListable() {
+				if err := removeChildrenBySchema(branch, cschema); err != nil {
+					return err
+				}
+			}
+			if err := unmarshalJSON(branch, branch.schema, map[string]interface{}{cschema.Name: vv}); err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			switch {
+			case cschema.IsListHasKey():
+				if err := applyMergePatchList(branch, cschema, nil, vv); err != nil {
+					return err
+				}
+			case cschema.IsDir():
+				child := branch.Get(cschema.Name)
+				if child == nil {
+					var err error
+					if child, err = New(cschema); err != nil {
+						return err
+					}
+					if _, err = branch.Insert(child, nil); err != nil {
+						return err
+					}
+				}
+				if err := applyMergePatch(child, vv); err != nil {
+					return err
+				}
+			default:
+				return Errorf(EAppTagJSONParsing, "unexpected json object for %s", cschema.Name)
+			}
+		default:
+			if err := unmarshalJSON(branch, branch.schema, map[string]interface{}{cschema.Name: vv}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyMergePatchList() applies the merge patch of the list entries in the object format.
+// The kval is the key values of the upper levels of the object for the multiple keys list.
+func applyMergePatchList(branch *DataBranch, cschema *SchemaNode, kval []string, patch map[string]interface{}) error {
+	keys := make([]string, 0, len(patch))
+	for k := range patch {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kname := cschema.Keyname
+	for _, k := range keys {
+		kv := append(append([]string{}, kval...), k)
+		if len(kv) < len(kname) {
+			next, ok := patch[k].(map[string]interface{})
+			if !ok {
+				return Errorf(EAppTagJSONParsing, "unexpected json value for the key %s of %s", kname[len(kv)-1], cschema.Name)
+			}
+			if err := applyMergePatchList(branch, cschema, kv, next); err != nil {
+				return err
+			}
+			continue
+		}
+		pmap := make(map[string]interface{}, len(kname))
+		for i := range kname {
+			pmap[kname[i]] = kv[i]
+		}
+		id, _, _ := cschema.GenerateID(pmap)
+		entry := branch.Get(id)
+		if patch[k] == nil {
+			if entry != nil {
+				if err := entry.Remove(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if entry == nil {
+			var err error
+			if entry, err = NewWithID(cschema, id); err != nil {
+				return err
+			}
+			if _, err = branch.Insert(entry, nil); err != nil {
+				return err
+			}
+		}
+		if err := applyMergePatch(entry, patch[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeChildrenBySchema() removes all children of the branch having the schema.
+func removeChildrenBySchema(branch *DataBranch, schema *SchemaNode) error {
+	i, max := indexRangeBySchema(branch, schema)
+	children := copyDataNodeList(branch.children[i:max])
+	for j := range children {
+		if err := children[j].Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func isIntegral(val float64) bool {
 	return val == float64(int(val))
 }
@@ -814,3 +1258,87 @@ func JSONValueToString(jval interface{}) (string, error) {
 	}
 	return "", fmt.Errorf("unexpected json-value %v (%T)", jval, jval)
 }
+
+// unmarshalJSONAll() is like unmarshalJSON(), but it continues to unmarshal the other
+// children of the branch nodes when a child is failed and then returns all errors found.
+func unmarshalJSONAll(node DataNode, schema *SchemaNode, jval interface{}) []error {
+	entry, ok := jval.(map[string]interface{})
+	if !ok || !node.IsBranchNode() {
+		if err := unmarshalJSON(node, schema, jval); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+	keys := make([]string, 0, len(entry))
+	for k := range entry {
+		if k == "@" || !strings.HasPrefix(k, "@") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var errors []error
+	for _, k := range keys {
+		cschema := schema.GetSchema(k)
+		if k == "@" || cschema == nil || cschema.IsListable() || !cschema.IsDir() {
+			// unmarshal the child with its metadata.
+			partial := map[string]interface{}{k: entry[k]}
+			if cschema != nil {
+				if meta, ok := entry["@"+cschema.Name]; ok {
+					partial["@"+cschema.Name] = meta
+				}
+			}
+			if err := unmarshalJSON(node, schema, partial); err != nil {
+				errors = append(errors, err)
+			}
+			continue
+		}
+		child := node.Get(cschema.Name)
+		if child == nil {
+			var err error
+			if child, err = New(cschema); err != nil {
+				errors = append(errors, Error(EAppTagJSONParsing, err))
+				continue
+			}
+			if _, err = node.Insert(child, nil); err != nil {
+				errors = append(errors, Error(EAppTagJSONParsing, err))
+				continue
+			}
+		}
+		errors = append(errors, unmarshalJSONAll(child, cschema, entry[k])...)
+		if meta := entry["@"+cschema.Name]; meta != nil {
+			if err := unmarshalJSONUpdateMetadata(child, cschema, meta); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+	if branch, ok := node.(*DataBranch); ok && IsCreatedWithDefault(schema) {
+		if err := branch.setDefaults(); err != nil {
+			errors = append(errors, Error(EAppTagJSONParsing, err))
+		}
+	}
+	return errors
+}
+
+// ValidateJSON() validates the JSON document against the schema before it is applied.
+// The JSON document is unmarshalled to a temporary data tree discarded after the validation,
+// and all errors found in the unmarshalling and ValidateAll() are returned as a MultipleError.
+//   if err := ValidateJSON(schema, jbytes); err != nil { // reject the JSON document }
+func ValidateJSON(schema *SchemaNode, jbytes []byte) error {
+	if schema == nil {
+		return Errorf(EAppTagInvalidArg, "schema is nil")
+	}
+	var jval interface{}
+	if err := json.Unmarshal(jbytes, &jval); err != nil {
+		return Error(EAppTagJSONParsing, err)
+	}
+	node, err := New(schema)
+	if err != nil {
+		return err
+	}
+	errors := unmarshalJSONAll(node, schema, jval)
+	errors = append(errors, ValidateAll(node)...)
+	if len(errors) > 0 {
+		return MultipleError(errors)
+	}
+	return nil
+}
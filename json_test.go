@@ -1,7 +1,15 @@
 package yangtree
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/goccy/go-json"
@@ -291,3 +299,965 @@ func TestDataNode_FindState(t *testing.T) {
 	}
 	// gdump.ValueDump(RootData, 12, func(a ...interface{}) { fmt.Print(a...) }, "schema", "parent")
 }
+
+func TestOmitKeyLeaves(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=A]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"list-key":"A"`) {
+		t.Errorf("key leaf must be included by default: %s", b)
+	}
+	b, err = MarshalJSON(root, OmitKeyLeaves{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), `"list-key"`) || !strings.Contains(string(b), `"A":{`) {
+		t.Errorf("key leaf must be omitted with OmitKeyLeaves: %s", b)
+	}
+	b, err = MarshalJSON(root, RFC7951Format{}, OmitKeyLeaves{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"list-key":"A"`) {
+		t.Errorf("OmitKeyLeaves must be ignored in RFC7951 format: %s", b)
+	}
+}
+
+func TestMergeJSON(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/enum-val", nil, "enum1"); err != nil {
+		t.Fatal(err)
+	}
+	// merge partial json into the existing container
+	if err := MergeJSON(root, "/sample/container-val", []byte(`{"a":"A","leaf-list-val":["first"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	// merge json into a new list entry
+	if err := MergeJSON(root, "/sample/single-key-list[list-key=AAA]", []byte(`{"country-code":"KR"}`)); err != nil {
+		t.Fatal(err)
+	}
+	// merge json into a leaf
+	if err := MergeJSON(root, "/sample/str-val", []byte(`"abc"`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := MergeJSON(root, "/sample/container-val/enum-val", []byte(`"enum4"`)); err == nil {
+		t.Errorf("merging an invalid value must fail")
+	}
+	expected := map[string]string{
+		"/sample/container-val/enum-val":                     "enum1",
+		"/sample/container-val/a":                            "A",
+		"/sample/container-val/leaf-list-val[.=first]":       "first",
+		"/sample/single-key-list[list-key=AAA]/country-code": "KR",
+		"/sample/str-val":                                    "abc",
+	}
+	for path, value := range expected {
+		v, err := FindValueString(root, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(v) != 1 || v[0] != value {
+			t.Errorf("unexpected value of %s: %v", path, v)
+		}
+	}
+}
+
+func TestUnmarshalJSONChanges(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/str-val", nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/country-code", nil, "KR"); err != nil {
+		t.Fatal(err)
+	}
+	sample := root.Get("sample").(*DataBranch)
+	jbytes := `{"str-val":"abc","container-val":{"a":"B"},"single-key-list":[{"list-key":"AAA","country-code":"KR","int8-range":10}]}`
+	changes, err := UnmarshalJSONChanges(sample, []byte(jbytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for i := range changes {
+		paths = append(paths, changes[i].Path())
+	}
+	sort.Strings(paths)
+	expected := []string{
+		"/sample/container-val/a",
+		"/sample/single-key-list[list-key=AAA]/int8-range",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("UnmarshalJSONChanges() = %v, want %v", paths, expected)
+	}
+	if v, err := FindValueString(root, "/sample/container-val/a"); err != nil || len(v) != 1 || v[0] != "B" {
+		t.Errorf("unexpected value after UnmarshalJSONChanges(): %v", v)
+	}
+	if _, err := UnmarshalJSONChanges(sample, []byte(`{"str-val":`)); err == nil {
+		t.Errorf("UnmarshalJSONChanges() must fail for an invalid json")
+	}
+}
+
+func TestNamespaceMode(t *testing.T) {
+	schema, err := Load([]string{
+		"testdata/modules/openconfig-simple-target.yang",
+		"testdata/modules/openconfig-simple-augment.yang",
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := NewWithValueString(schema, `{"target":{"foo":{"config":{"a":"x"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mode    NamespaceMode
+		want    string
+		wantKey []string
+	}{
+		{
+			mode: NamespaceBoundary,
+			want: `{"openconfig-simple-target:target":{"openconfig-simple-augment:foo":{"config":{"a":"x"}}}}`,
+			wantKey: []string{"openconfig-simple-target:target",
+				"openconfig-simple-augment:foo", "config", "a"},
+		},
+		{
+			mode: NamespaceAlways,
+			want: `{"openconfig-simple-target:target":{"openconfig-simple-augment:foo":` +
+				`{"openconfig-simple-augment:config":{"openconfig-simple-augment:a":"x"}}}}`,
+			wantKey: []string{"openconfig-simple-target:target",
+				"openconfig-simple-augment:foo", "openconfig-simple-augment:config", "openconfig-simple-augment:a"},
+		},
+		{
+			mode:    NamespaceTopOnly,
+			want:    `{"openconfig-simple-target:target":{"foo":{"config":{"a":"x"}}}}`,
+			wantKey: []string{"openconfig-simple-target:target", "foo", "config", "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			j, err := MarshalJSON(root, RFC7951Format{}, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(j) != tt.want {
+				t.Errorf("unexpected json:\n got: %s\nwant: %s", j, tt.want)
+			}
+			m, err := ToMap(root, RFC7951Format{}, tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var v interface{} = m
+			for _, key := range tt.wantKey {
+				mm, ok := v.(map[string]interface{})
+				if !ok {
+					t.Fatalf("unexpected map value %v", v)
+				}
+				if v, ok = mm[key]; !ok {
+					t.Fatalf("key %s not found in %v", key, mm)
+				}
+			}
+			if v != "x" {
+				t.Errorf("unexpected value %v", v)
+			}
+		})
+	}
+	// The names are not qualified without RFC7951Format.
+	j, err := MarshalJSON(root, NamespaceAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(j) != `{"target":{"foo":{"config":{"a":"x"}}}}` {
+		t.Errorf("unexpected json: %s", j)
+	}
+}
+
+// TestConcurrentMarshal marshals the same data tree in multiple goroutines.
+// Run it with "go test -race" to detect data races.
+func TestConcurrentMarshal(t *testing.T) {
+	schema, err := Load([]string{
+		"testdata/sample/sample.yang",
+		"testdata/modules/openconfig-simple-target.yang",
+		"testdata/modules/openconfig-simple-augment.yang",
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := NewWithValueString(schema, `{
+		"sample":{
+			"str-val":"abc",
+			"container-val":{"a":"A","leaf-list-val":["x","y"]},
+			"single-key-list":[{"list-key":"AAA","country-code":"KR","uint32-range":100}],
+			"multiple-key-list":[{"str":"first","integer":1}]
+		},
+		"target":{"foo":{"config":{"a":"x"},"state":{"a":"y"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshallers := map[string]func() ([]byte, error){
+		"json": func() ([]byte, error) { return MarshalJSON(root) },
+		"json-rfc7951": func() ([]byte, error) {
+			return MarshalJSONIndent(root, "", " ", RFC7951Format{}, ConfigOnly{})
+		},
+		"yaml":         func() ([]byte, error) { return MarshalYAML(root) },
+		"yaml-rfc7951": func() ([]byte, error) { return MarshalYAML(root, RFC7951Format{}, StateOnly{}) },
+		"xml":          func() ([]byte, error) { return MarshalXML(root.Get("sample")) },
+	}
+	want := map[string]string{}
+	for name, marshal := range marshallers {
+		b, err := marshal()
+		if err != nil {
+			t.Fatalf("%s marshalling error: %v", name, err)
+		}
+		want[name] = string(b)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 10; i++ {
+		for name, marshal := range marshallers {
+			wg.Add(1)
+			go func(name string, marshal func() ([]byte, error)) {
+				defer wg.Done()
+				b, err := marshal()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if string(b) != want[name] {
+					errs <- fmt.Errorf("different %s result:\n%s", name, b)
+				}
+			}(name, marshal)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestNumbersAsStrings(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := NewWithValueString(schema, `{"sample":{
+		"container-val":{"test-must":5},
+		"single-key-list":[{"list-key":"AAA","int8-range":-8,"uint32-range":100,
+			"uint64-node":"1234567890","decimal-range":1.01}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		option []Option
+		want   []string
+	}{
+		{
+			option: []Option{RFC7951Format{}},
+			want: []string{`"test-must":5`, `"int8-range":-8`, `"uint32-range":100`,
+				`"uint64-node":"1234567890"`, `"decimal-range":1.01`},
+		},
+		{
+			option: []Option{RFC7951Format{}, NumbersAsStrings{}},
+			want: []string{`"test-must":"5"`, `"int8-range":"-8"`, `"uint32-range":"100"`,
+				`"uint64-node":"1234567890"`, `"decimal-range":"1.01"`},
+		},
+		{
+			option: []Option{NumbersAsStrings{}},
+			want: []string{`"test-must":5`, `"int8-range":-8`, `"uint32-range":100`,
+				`"uint64-node":1234567890`, `"decimal-range":1.01`},
+		},
+	}
+	for _, tt := range tests {
+		j, err := MarshalJSON(root, tt.option...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(j), want) {
+				t.Errorf("%s not found in %s", want, j)
+			}
+		}
+		// quoted numbers must be unmarshalled.
+		root2, err := NewWithValueString(schema, string(j))
+		if err != nil {
+			t.Fatalf("unmarshalling error: %v", err)
+		}
+		if !Equal(root, root2) {
+			t.Errorf("different data tree unmarshalled from %s", j)
+		}
+	}
+}
+
+func TestUnmarshalJSONGzip(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jbytes, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	if _, err := gzw.Write(jbytes); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalJSON(expected, jbytes); err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalJSONGzip(root, buf.Bytes()); err != nil {
+		t.Fatalf("UnmarshalJSONGzip() error: %v", err)
+	}
+	if !Equal(expected, root) {
+		t.Errorf("gzip-compressed json data is not loaded equally")
+	}
+	if err := UnmarshalJSONGzip(root, jbytes); err == nil {
+		t.Errorf("uncompressed data must be rejected")
+	}
+}
+
+func TestConvertJSON(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jbytes, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalJSON(root, jbytes); err != nil {
+		t.Fatal(err)
+	}
+	plain, err := MarshalJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rfc7951, err := MarshalJSON(root, RFC7951Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	converted, err := ConvertJSON(RootSchema, jbytes, true)
+	if err != nil {
+		t.Fatalf("ConvertJSON() to RFC7951 error: %v", err)
+	}
+	if string(converted) != string(rfc7951) {
+		t.Errorf("unexpected RFC7951 conversion:\n%s\n%s", converted, rfc7951)
+	}
+	if !strings.Contains(string(converted), `"sample:sample"`) {
+		t.Errorf("RFC7951 json must have the module-qualified names: %s", converted)
+	}
+	converted, err = ConvertJSON(RootSchema, rfc7951, false)
+	if err != nil {
+		t.Fatalf("ConvertJSON() from RFC7951 error: %v", err)
+	}
+	if string(converted) != string(plain) {
+		t.Errorf("unexpected plain json conversion:\n%s\n%s", converted, plain)
+	}
+	if _, err := ConvertJSON(RootSchema, []byte(`{"unknown":1}`), true); err == nil {
+		t.Errorf("invalid json data must be rejected")
+	}
+}
+
+// checkJSONSchema() validates the json value using the JSON Schema keywords generated by GenerateJSONSchema().
+func checkJSONSchema(path string, value interface{}, jschema map[string]interface{}) []string {
+	var errs []string
+	if anyOf, ok := jschema["anyOf"].([]interface{}); ok {
+		matched := false
+		for i := range anyOf {
+			if len(checkJSONSchema(path, value, anyOf[i].(map[string]interface{}))) == 0 {
+				matched = true
+			}
+		}
+		if !matched {
+			errs = append(errs, path+": no anyOf schema matched")
+		}
+	}
+	if typ, ok := jschema["type"]; ok {
+		var types []interface{}
+		if t, ok := typ.([]interface{}); ok {
+			types = t
+		} else {
+			types = []interface{}{typ}
+		}
+		matched := false
+		for _, t := range types {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				matched = matched || t == "object"
+			case []interface{}:
+				matched = matched || t == "array"
+			case string:
+				matched = matched || t == "string"
+			case bool:
+				matched = matched || t == "boolean"
+			case nil:
+				matched = matched || t == "null"
+			case float64:
+				matched = matched || t == "number" || (t == "integer" && v == float64(int64(v)))
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s: %v is not %v", path, value, typ))
+		}
+	}
+	if enum, ok := jschema["enum"].([]interface{}); ok {
+		found := false
+		for i := range enum {
+			found = found || enum[i] == value
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v is not in %v", path, value, enum))
+		}
+	}
+	if allOf, ok := jschema["allOf"].([]interface{}); ok {
+		for i := range allOf {
+			errs = append(errs, checkJSONSchema(path, value, allOf[i].(map[string]interface{}))...)
+		}
+	}
+	if v, ok := value.(string); ok {
+		if pattern, ok := jschema["pattern"].(string); ok {
+			if matched, err := regexp.MatchString(pattern, v); err != nil || !matched {
+				errs = append(errs, fmt.Sprintf("%s: %v is not matched to %v", path, v, pattern))
+			}
+		}
+		length := float64(len([]rune(v)))
+		if min, ok := jschema["minLength"].(float64); ok && length < min {
+			errs = append(errs, fmt.Sprintf("%s: %v is shorter than %v", path, v, min))
+		}
+		if max, ok := jschema["maxLength"].(float64); ok && length > max {
+			errs = append(errs, fmt.Sprintf("%s: %v is longer than %v", path, v, max))
+		}
+	}
+	if v, ok := value.(float64); ok {
+		if min, ok := jschema["minimum"].(float64); ok && v < min {
+			errs = append(errs, fmt.Sprintf("%s: %v is less than %v", path, v, min))
+		}
+		if max, ok := jschema["maximum"].(float64); ok && v > max {
+			errs = append(errs, fmt.Sprintf("%s: %v is greater than %v", path, v, max))
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := jschema["properties"].(map[string]interface{})
+		for name, child := range v {
+			if cschema, ok := properties[name].(map[string]interface{}); ok {
+				errs = append(errs, checkJSONSchema(path+"/"+name, child, cschema)...)
+			} else {
+				errs = append(errs, path+"/"+name+": unknown property")
+			}
+		}
+		required, _ := jschema["required"].([]interface{})
+		for i := range required {
+			if _, ok := v[required[i].(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: %v required", path, required[i]))
+			}
+		}
+	case []interface{}:
+		if items, ok := jschema["items"].(map[string]interface{}); ok {
+			for i := range v {
+				errs = append(errs, checkJSONSchema(fmt.Sprintf("%s[%d]", path, i), v[i], items)...)
+			}
+		}
+	}
+	return errs
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateJSONSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jschema map[string]interface{}
+	if err := json.Unmarshal(b, &jschema); err != nil {
+		t.Fatalf("invalid json schema generated: %v", err)
+	}
+	if jschema["$schema"] != "http://json-schema.org/draft-07/schema#" {
+		t.Errorf("unexpected $schema: %v", jschema["$schema"])
+	}
+	data, err := ioutil.ReadFile("testdata/json/sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jdata interface{}
+	if err := json.Unmarshal(data, &jdata); err != nil {
+		t.Fatal(err)
+	}
+	if errs := checkJSONSchema("", jdata, jschema); len(errs) > 0 {
+		t.Errorf("sample.json not validated by the generated json schema:\n%s", strings.Join(errs, "\n"))
+	}
+
+	invalid := `{"sample:sample": {"container-val": {"enum-val": "enum9"},
+		"single-key-list": [{"list-key": "AAA", "uint32-range": 1000}]}}`
+	if err := json.Unmarshal([]byte(invalid), &jdata); err != nil {
+		t.Fatal(err)
+	}
+	if errs := checkJSONSchema("", jdata, jschema); len(errs) != 2 {
+		t.Errorf("invalid json must not be validated by the generated json schema: %v", errs)
+	}
+}
+
+func TestGenerateJSONSchemaKeywords(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/jsonschema-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := SaveSchemaCache(schema, &buf); err != nil {
+		t.Fatal(err)
+	}
+	cached, err := LoadSchemaCache(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		json  string
+		valid bool
+	}{
+		{json: `{"jsonschema-example:config": {"name": "abc1", "code": "KR", "mode": "auto", "protocol": "tcp"}}`, valid: true},
+		{json: `{"jsonschema-example:config": {"protocol": "jsonschema-example:udp"}}`, valid: true},
+		{json: `{"jsonschema-example:config": {"name": "a1"}}`, valid: true},
+		{json: `{"jsonschema-example:config": {"name": "1"}}`},
+		{json: `{"jsonschema-example:config": {"name": "abcdefgh1"}}`},
+		{json: `{"jsonschema-example:config": {"name": "abc"}}`},
+		{json: `{"jsonschema-example:config": {"code": "kr"}}`},
+		{json: `{"jsonschema-example:config": {"mode": "off"}}`},
+		{json: `{"jsonschema-example:config": {"protocol": "sctp"}}`},
+	}
+	for _, s := range []*SchemaNode{schema, cached} {
+		b, err := GenerateJSONSchema(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jschema map[string]interface{}
+		if err := json.Unmarshal(b, &jschema); err != nil {
+			t.Fatalf("invalid json schema generated: %v", err)
+		}
+		for _, tt := range tests {
+			var jdata interface{}
+			if err := json.Unmarshal([]byte(tt.json), &jdata); err != nil {
+				t.Fatal(err)
+			}
+			if errs := checkJSONSchema("", jdata, jschema); (len(errs) == 0) != tt.valid {
+				t.Errorf("unexpected json schema validation for %s: %v", tt.json, errs)
+			}
+		}
+	}
+}
+
+func TestMarshalJSONDirtyOnly(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{TrackDirty: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"str-val-ext": "ext",
+		"container-val": {"a": "A"},
+		"single-key-list": [
+			{"list-key": "AAA", "country-code": "KR"},
+			{"list-key": "BBB", "country-code": "JP"}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsDirty(root) {
+		t.Errorf("the created data node must be dirty")
+	}
+	MarkClean(root)
+	if IsDirty(root) {
+		t.Errorf("the data node must be clean after MarkClean()")
+	}
+	b, err := MarshalJSON(root, DirtyOnly{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{}` {
+		t.Errorf("unexpected dirty-only json of the clean data node: %s", b)
+	}
+
+	if err := SetValueString(root, "/sample/str-val", nil, "xyz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/country-code", nil, "US"); err != nil {
+		t.Fatal(err)
+	}
+	b, err = MarshalJSON(root, DirtyOnly{}, RFC7951Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, expected interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"sample:sample": {
+		"str-val": "xyz",
+		"single-key-list": [{"list-key": "AAA", "country-code": "US"}]
+	}}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected dirty-only json: %s", b)
+	}
+
+	MarkClean(root)
+	if err := Delete(root, "/sample/str-val"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Delete(root, "/sample/single-key-list[list-key=BBB]"); err != nil {
+		t.Fatal(err)
+	}
+	b, err = MarshalJSON(root, DirtyOnly{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, expected = nil, nil
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"sample": {
+		"str-val": null,
+		"single-key-list": {"BBB": null}
+	}}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected dirty-only json of the removed nodes: %s", b)
+	}
+	MarkClean(root)
+	if b, err = MarshalJSON(root, DirtyOnly{}); err != nil || string(b) != `{}` {
+		t.Errorf("the removed nodes must be cleared by MarkClean(): %s, %v", b, err)
+	}
+
+	sample := root.Get("sample").(*DataBranch)
+	if _, err := sample.Rekey("single-key-list[list-key=AAA]", map[string]string{"list-key": "CCC"}); err != nil {
+		t.Fatal(err)
+	}
+	if !IsDirty(sample.Get("single-key-list[list-key=CCC]")) {
+		t.Errorf("the rekeyed list entry must be dirty")
+	}
+	b, err = MarshalJSON(root, DirtyOnly{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"AAA":null`) {
+		t.Errorf("the old list entry of the rekeyed entry must be encoded to null: %s", b)
+	}
+
+	if err := SetValueString(root, "/sample/container-val/leaf-list-val", nil, "x"); err != nil {
+		t.Fatal(err)
+	}
+	MarkClean(root)
+	leaflist := sample.Get("container-val").Get("leaf-list-val").(*DataLeafList)
+	if err := leaflist.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if !IsDirty(leaflist) || !IsDirty(root) {
+		t.Errorf("the cleared leaf-list and its ancestors must be dirty")
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"str-val-ext": "ext",
+		"container-val": {"a": "A", "leaf-list-val": ["x"]},
+		"single-key-list": [
+			{"list-key": "AAA", "country-code": "KR"},
+			{"list-key": "BBB", "country-code": "US"}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "set, nested update and delete",
+			patch: `{"sample": {
+				"str-val": "xyz",
+				"str-val-ext": null,
+				"container-val": {"leaf-list-val": ["y", "z"]},
+				"single-key-list": {
+					"AAA": {"country-code": "JP"},
+					"BBB": null,
+					"CCC": {"country-code": "CN"}
+				}
+			}}`,
+			expected: `{"sample": {
+				"str-val": "xyz",
+				"container-val": {"a": "A", "leaf-list-val": ["y", "z"]},
+				"single-key-list": [
+					{"list-key": "AAA", "country-code": "JP"},
+					{"list-key": "CCC", "country-code": "CN"}
+				]
+			}}`,
+		},
+		{
+			name:  "delete container",
+			patch: `{"sample": {"container-val": null, "single-key-list": {"AAA": {"country-code": null}}}}`,
+			expected: `{"sample": {
+				"str-val": "xyz",
+				"single-key-list": [
+					{"list-key": "AAA"},
+					{"list-key": "CCC", "country-code": "CN"}
+				]
+			}}`,
+		},
+		{
+			name:    "unknown member",
+			patch:   `{"sample": {"unknown": 1}}`,
+			wantErr: true,
+		},
+		{
+			name:    "non-object patch for container",
+			patch:   `{"sample": "abc"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ApplyMergePatch(root, []byte(tt.patch))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyMergePatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			expected, err := NewWithValueString(schema, tt.expected)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !Equal(root, expected) {
+				got, _ := MarshalJSON(root)
+				t.Errorf("ApplyMergePatch() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOutputTransform(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "secret",
+		"container-val": {"leaf-list-val": ["a", "b"]},
+		"single-key-list": [{"list-key": "AAA", "uint32-range": 100}]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := func(string) string { return "****" }
+	if err := RegisterOutputTransform(schema, "/sample/str-val", mask); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveOutputTransform(schema, "/sample/str-val")
+	if err := RegisterOutputTransform(schema, "/sample/single-key-list[list-key=AAA]/uint32-range", mask); err == nil {
+		t.Errorf("RegisterOutputTransform() must fail with the predicates")
+	}
+	if err := RegisterOutputTransform(schema, "/sample/single-key-list/uint32-range", mask); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveOutputTransform(schema, "/sample/single-key-list/uint32-range")
+	if err := RegisterOutputTransform(schema, "/sample/container-val/leaf-list-val", strings.ToUpper); err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveOutputTransform(schema, "/sample/container-val/leaf-list-val")
+
+	j, err := MarshalJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := MarshalYAML(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := MarshalXML(root.Get("sample"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputs := map[string]struct {
+		output   string
+		expected []string
+	}{
+		"json": {output: string(j), expected: []string{`"str-val":"****"`, `"uint32-range":"****"`, `["A","B"]`}},
+		"yaml": {output: string(y), expected: []string{`str-val: '****'`, `uint32-range: '****'`, `- A`}},
+		"xml":  {output: string(x), expected: []string{`<str-val>****</str-val>`, `<uint32-range>****</uint32-range>`, `<leaf-list-val>A</leaf-list-val>`}},
+	}
+	for format, o := range outputs {
+		for _, expected := range o.expected {
+			if !strings.Contains(o.output, expected) {
+				t.Errorf("%s output must contain %s:\n%s", format, expected, o.output)
+			}
+		}
+		if strings.Contains(o.output, "secret") {
+			t.Errorf("%s output must not contain the masked value:\n%s", format, o.output)
+		}
+	}
+	if v, _ := GetValueStringAt(root, "/sample/str-val"); v != "secret" {
+		t.Errorf("the stored value must not be changed: %s", v)
+	}
+	if err := RegisterOutputTransform(schema, "/sample/str-val", nil); err == nil {
+		t.Errorf("RegisterOutputTransform() must fail without a transform function")
+	}
+
+	// the transform functions are only registered to the schema tree.
+	other, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err = NewWithValueString(other, `{"sample": {"str-val": "secret"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, err := MarshalJSON(root); err != nil || !strings.Contains(string(j), "secret") {
+		t.Errorf("the transform function of other schema tree must not be applied: %s, %v", j, err)
+	}
+}
+
+func TestNumericKeyAware(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample": {
+		"multiple-key-list": [
+			{"str": "a", "integer": 100},
+			{"str": "a", "integer": 2},
+			{"str": "a", "integer": 10}
+		]
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intkey := regexp.MustCompile(`integer\D{1,3}(\d+)`)
+	order := func(b []byte) []string {
+		var keys []string
+		for _, m := range intkey.FindAllSubmatch(b, -1) {
+			keys = append(keys, string(m[1]))
+		}
+		return keys
+	}
+	marshal := map[string]func(option ...Option) ([]byte, error){
+		"json": func(option ...Option) ([]byte, error) { return MarshalJSON(root, option...) },
+		"json-rfc7951": func(option ...Option) ([]byte, error) {
+			return MarshalJSON(root, append(option, RFC7951Format{})...)
+		},
+		"yaml": func(option ...Option) ([]byte, error) { return MarshalYAML(root, option...) },
+		"xml":  func(option ...Option) ([]byte, error) { return MarshalXML(root, option...) },
+	}
+	entries, _ := Find(root, "/sample/multiple-key-list")
+	for name, fn := range marshal {
+		b, err := fn()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := order(b); len(got) != 3 || reflect.DeepEqual(got, []string{"2", "10", "100"}) {
+			t.Errorf("%s: unexpected lexical order: %v", name, got)
+		}
+		b, err = fn(NumericKeyAware{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := order(b); !reflect.DeepEqual(got, []string{"2", "10", "100"}) {
+			t.Errorf("%s: unexpected numeric order: %v\n%s", name, got, string(b))
+		}
+	}
+	// the data tree is not reordered.
+	if found, _ := Find(root, "/sample/multiple-key-list"); !reflect.DeepEqual(found, entries) {
+		t.Errorf("the list entries must not be reordered: %v", found)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample", "testdata/modules/presence-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateJSON(schema, []byte(`{
+		"sample": {"str-val": "abc", "single-key-list": [{"list-key": "A", "uint32-range": 100}]},
+		"system": {"tls": {"cert": "server.crt"}}
+	}`)); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	err = ValidateJSON(schema, []byte(`{
+		"sample": {"str-val": "abc", "single-key-list": [{"list-key": "A", "uint32-range": 1000}]},
+		"system": {"tls": {}}
+	}`))
+	errs, ok := err.(MultipleError)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("2 validation errors expected, but got %v", err)
+	}
+	for _, keyword := range []string{"1000", "cert"} {
+		found := false
+		for i := range errs {
+			if strings.Contains(errs[i].Error(), keyword) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("violation of %s not reported: %v", keyword, errs)
+		}
+	}
+	if err := ValidateJSON(schema, []byte(`{"sample": `)); err == nil {
+		t.Errorf("invalid json document must be rejected")
+	}
+}
@@ -0,0 +1,194 @@
+package yangtree
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/goccy/go-json"
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/openconfig/ygot/util"
+)
+
+// jsonSchemaDraft07 is the meta-schema URI of the JSON Schema draft-07.
+const jsonSchemaDraft07 = "http://json-schema.org/draft-07/schema#"
+
+// GenerateJSONSchema() returns a JSON Schema (draft-07) document describing the data nodes
+// of the schema node and its descendants encoded in the RFC7951 JSON format.
+// The types, enums, ranges (minimum and maximum), lengths, patterns and mandatory nodes
+// of the schema nodes are represented in the JSON Schema.
+func GenerateJSONSchema(schema *SchemaNode) ([]byte, error) {
+	if schema == nil {
+		return nil, Errorf(EAppTagInvalidArg, "no schema for json schema")
+	}
+	var jschema map[string]interface{}
+	if schema.IsRoot || schema.IsDir() {
+		jschema = jsonSchemaObject(schema)
+	} else {
+		jschema = jsonSchemaOf(schema)
+	}
+	jschema["$schema"] = jsonSchemaDraft07
+	if schema.IsRoot {
+		jschema["title"] = "yangtree"
+	} else {
+		qname, _ := schema.GetQName(true)
+		jschema["title"] = qname
+	}
+	return json.Marshal(jschema)
+}
+
+// jsonSchemaOf() returns the JSON Schema of a schema node.
+func jsonSchemaOf(schema *SchemaNode) map[string]interface{} {
+	var jschema map[string]interface{}
+	switch {
+	case schema.IsAnyData() || schema.IsAnyXML():
+		jschema = map[string]interface{}{}
+	case schema.IsList():
+		jschema = map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchemaObject(schema),
+		}
+	case schema.IsDir():
+		jschema = jsonSchemaObject(schema)
+	case schema.IsLeafList():
+		jschema = map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchemaType(schema, schema.Type),
+		}
+	default:
+		jschema = jsonSchemaType(schema, schema.Type)
+	}
+	if schema.Description != "" {
+		jschema["description"] = schema.Description
+	}
+	return jschema
+}
+
+// jsonSchemaObject() returns the JSON Schema object of a container, a list entry or the root.
+func jsonSchemaObject(schema *SchemaNode) map[string]interface{} {
+	properties := make(map[string]interface{}, len(schema.Children))
+	var required []string
+	for _, cschema := range schema.Children {
+		if cschema.IsRPC() {
+			continue
+		}
+		name := cschema.Name
+		if qname, boundary := cschema.GetQName(true); boundary {
+			name = qname
+		}
+		properties[name] = jsonSchemaOf(cschema)
+		if cschema.IsKey || (cschema.Mandatory == yang.TSTrue && !cschema.IsState) {
+			required = append(required, name)
+		}
+	}
+	jschema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		jschema["required"] = required
+	}
+	return jschema
+}
+
+// jsonSchemaType() returns the JSON Schema of the type of a leaf or leaf-list node.
+func jsonSchemaType(schema *SchemaNode, typ *yang.YangType) map[string]interface{} {
+	jschema := map[string]interface{}{}
+	if typ == nil {
+		return jschema
+	}
+	switch typ.Kind {
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yuint8, yang.Yuint16, yang.Yuint32:
+		jschema["type"] = "integer"
+		jsonSchemaRange(jschema, typ)
+	case yang.Yint64, yang.Yuint64:
+		// 64-bit integers are encoded to strings in RFC7951.
+		jschema["type"] = []string{"integer", "string"}
+		jsonSchemaRange(jschema, typ)
+	case yang.Ydecimal64:
+		jschema["type"] = []string{"number", "string"}
+		jsonSchemaRange(jschema, typ)
+	case yang.Ybool:
+		jschema["type"] = "boolean"
+	case yang.Yempty:
+		jschema["type"] = "array"
+		jschema["items"] = map[string]interface{}{"type": "null"}
+	case yang.Yenum:
+		var enum []string
+		if typ.Enum != nil {
+			enum = append(enum, typ.Enum.Names()...)
+		}
+		sort.Strings(enum)
+		jschema["type"] = "string"
+		jschema["enum"] = enum
+	case yang.Yidentityref:
+		var enum []string
+		if typ.IdentityBase != nil {
+			for _, value := range typ.IdentityBase.Values {
+				name := value.NName()
+				enum = append(enum, name)
+				if m := yang.RootNode(value); m != nil {
+					enum = append(enum, m.Name+":"+name)
+				}
+			}
+		}
+		sort.Strings(enum)
+		jschema["type"] = "string"
+		jschema["enum"] = enum
+	case yang.Yunion:
+		anyOf := make([]interface{}, 0, len(typ.Type))
+		for i := range typ.Type {
+			anyOf = append(anyOf, jsonSchemaType(schema, typ.Type[i]))
+		}
+		jschema["anyOf"] = anyOf
+	case yang.Ystring, yang.Ybinary:
+		jschema["type"] = "string"
+		if len(typ.Length) > 0 {
+			if min, err := strconv.ParseUint(typ.Length[0].Min.String(), 10, 64); err == nil && min > 0 {
+				jschema["minLength"] = min
+			}
+			if max, err := strconv.ParseUint(typ.Length[len(typ.Length)-1].Max.String(), 10, 64); err == nil {
+				jschema["maxLength"] = max
+			}
+		}
+		if typ.Kind == yang.Ystring {
+			if patterns, isPOSIX := util.SanitizedPattern(typ); !isPOSIX {
+				switch len(patterns) {
+				case 0:
+				case 1:
+					jschema["pattern"] = patterns[0]
+				default:
+					allOf := make([]interface{}, 0, len(patterns))
+					for i := range patterns {
+						allOf = append(allOf, map[string]interface{}{"pattern": patterns[i]})
+					}
+					jschema["allOf"] = allOf
+				}
+			}
+		}
+	case yang.Ybits, yang.YinstanceIdentifier:
+		jschema["type"] = "string"
+	case yang.Yleafref:
+		// the leafref value follows the type of the referred node.
+		if target := schema.FindSchema(typ.Path); target != nil && target != schema && target.Type != nil {
+			return jsonSchemaType(target, target.Type)
+		}
+	default:
+		jschema["description"] = fmt.Sprintf("unsupported type %s", typ.Name)
+	}
+	return jschema
+}
+
+// jsonSchemaRange() updates the minimum and maximum of the JSON Schema using the range of the type.
+func jsonSchemaRange(jschema map[string]interface{}, typ *yang.YangType) {
+	if len(typ.Range) == 0 || isBuiltinRange(typ) {
+		return
+	}
+	if min, err := strconv.ParseFloat(typ.Range[0].Min.String(), 64); err == nil {
+		jschema["minimum"] = min
+	}
+	if max, err := strconv.ParseFloat(typ.Range[len(typ.Range)-1].Max.String(), 64); err == nil {
+		jschema["maximum"] = max
+	}
+}
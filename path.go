@@ -63,6 +63,13 @@ var (
 	funcXPath map[string]interface{} = map[string]interface{}{
 		"count":   funcXPathCount,
 		"current": "node.Value",
+		"not":     "!result",
+	}
+
+	// XPath functions having no argument and returning a constant.
+	literalFuncXPath map[string]string = map[string]string{
+		"true":  "true",
+		"false": "false",
 	}
 )
 
@@ -102,10 +109,10 @@ LOOP:
 		// if cschema := schema.GetSchema(name); cschema == nil {
 		// 	return nil, fmt.Errorf("child schema %s doesn't exist", name)
 		// }
-		if strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
-			value = strings.Trim(value, "'")
-		} else if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
-			value = strings.Trim(value, "\"")
+		if quoted, ok := trimQuotes(value); ok {
+			value = quoted
+		} else {
+			value = unescapeKeyValue(value)
 		}
 
 		switch name {
@@ -160,11 +167,8 @@ LOOP:
 		var value string
 		if len(token) > 2 {
 			value = token[2]
-			if strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
-				value = strings.Trim(value, "'")
-			}
-			if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
-				value = strings.Trim(value, "\"")
+			if quoted, ok := trimQuotes(value); ok {
+				value = quoted
 			}
 		}
 		switch token[0] {
@@ -188,6 +192,120 @@ LOOP:
 	return pmap, nil
 }
 
+// PredicateTerm is a term of a path predicate structured by ParsePredicate().
+// e.g. name='eth0' is represented to {Left: "name", Op: "=", Right: "eth0", Literal: true}.
+// The term without a comparison (e.g. 1, last()) has only the Left.
+type PredicateTerm struct {
+	Left    string // The left operand such as a node name, "." or a function. e.g. count(../a)
+	Op      string // The comparison operator (=, !=, <=, >=) or empty if not compared.
+	Right   string // The right operand. The quotation marks of a literal are removed.
+	Literal bool   // True if the right operand is an xpath literal.
+}
+
+func (term PredicateTerm) String() string {
+	if term.Op == "" {
+		return term.Left
+	}
+	if term.Literal {
+		return term.Left + term.Op + strconv.Quote(term.Right)
+	}
+	return term.Left + term.Op + term.Right
+}
+
+// ParsePredicate() parses the path predicates (e.g. [name='eth0'][.=1] or name='eth0' and mtu>1500)
+// to the structured terms using the xpath tokenizer of the path. The terms of a predicate
+// combined by "and" are returned in order and the terms of all predicates are returned together
+// because they are all required. The predicates having "or" or nested parentheses are not
+// simple predicates and are not supported.
+func ParsePredicate(expr string) ([]PredicateTerm, error) {
+	expr = strings.TrimSpace(expr)
+	predicates := []string{expr}
+	if strings.HasPrefix(expr, "[") {
+		path := "predicate" + expr
+		pathnode, err := ParsePath(&path)
+		if err != nil {
+			return nil, err
+		}
+		if len(pathnode) != 1 {
+			return nil, Errorf(EAppTagInvalidArg, "invalid predicate %s", expr)
+		}
+		predicates = pathnode[0].Predicates
+	}
+	var terms []PredicateTerm
+	for i := range predicates {
+		token, _, err := TokenizeXPathExpr(nil, &(predicates[i]), 0)
+		if err != nil {
+			return nil, err
+		}
+		for len(token) > 0 {
+			end := len(token)
+			op := -1
+			depth := 0
+			for j := range token {
+				switch token[j] {
+				case "(":
+					depth++
+					if depth > 1 {
+						return nil, Errorf(ETagOperationNotSupported,
+							"nested parentheses in %s not supported", predicates[i])
+					}
+				case ")":
+					depth--
+				case "or":
+					if depth == 0 {
+						return nil, Errorf(ETagOperationNotSupported,
+							"'or' in %s not supported", predicates[i])
+					}
+				case "=", "!=", "<=", ">=", "<", ">":
+					if depth == 0 && op < 0 {
+						op = j
+					}
+				}
+				if token[j] == "and" && depth == 0 {
+					end = j
+					break
+				}
+			}
+			term, err := newPredicateTerm(token[:end], op)
+			if err != nil {
+				return nil, Errorf(EAppTagInvalidArg, "invalid predicate %s: %v", predicates[i], err)
+			}
+			terms = append(terms, term)
+			if end < len(token) {
+				end++ // skip "and"
+			}
+			token = token[end:]
+		}
+	}
+	return terms, nil
+}
+
+// newPredicateTerm() returns the predicate term built from the tokens of a comparison.
+// The op is the index of the comparison operator in the tokens or -1 if not compared.
+func newPredicateTerm(token []string, op int) (PredicateTerm, error) {
+	if len(token) == 0 {
+		return PredicateTerm{}, fmt.Errorf("empty term")
+	}
+	if op < 0 || op >= len(token) {
+		return PredicateTerm{Left: strings.Join(token, "")}, nil
+	}
+	if op == 0 || op == len(token)-1 {
+		return PredicateTerm{}, fmt.Errorf("missing operand of %s", token[op])
+	}
+	term := PredicateTerm{
+		Left:  strings.Join(token[:op], ""),
+		Op:    token[op],
+		Right: strings.Join(token[op+1:], ""),
+	}
+	if op+2 == len(token) && strings.HasPrefix(token[op+1], `"`) {
+		if literal, ok := trimQuotes(token[op+1]); ok {
+			term.Right = literal
+			term.Literal = true
+		}
+	}
+	return term, nil
+}
+
 func updateNodeSelect(pathnode *PathNode) *PathNode {
 	if s, ok := pathNodeKeyword[pathnode.Name]; ok {
 		pathnode.Select = s
@@ -195,6 +313,76 @@ func updateNodeSelect(pathnode *PathNode) *PathNode {
 	return pathnode
 }
 
+// escapeKeyValue() escapes the special characters ('[', ']' and '\') of a key value
+// used in the path predicates and data node IDs.
+func escapeKeyValue(value string) string {
+	if !strings.ContainsAny(value, "[]\\") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// EscapeKeyValue() escapes the special characters of a key value to build the key predicates
+// of a data path (e.g. from the keys of a gNMI path element).
+func EscapeKeyValue(value string) string {
+	return escapeKeyValue(value)
+}
+
+// unescapeKeyValue() removes the escape characters from a key value escaped by escapeKeyValue().
+func unescapeKeyValue(value string) string {
+	if !strings.Contains(value, "\\") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
+
+// trimQuotes() removes the quotation marks enclosing the xpath literal.
+// The literal is opaque so that the quotation marks, spaces and operator-like
+// words (e.g. and, or) inside the literal are kept as they are.
+func trimQuotes(value string) (string, bool) {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1], true
+	}
+	return value, false
+}
+
+// isLiteralStart() returns true if the quotation mark at the pos starts an xpath literal.
+// The quotation marks inside of a word (e.g. O'Brien) are not the start of a literal.
+func isLiteralStart(s string, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	switch s[pos-1] {
+	case '=', '[', '(', ',', ' ', '\t', '\n', '\r', '<', '>':
+		return true
+	}
+	return false
+}
+
+// isEscaped() returns true if the character at the pos is escaped by a backslash.
+func isEscaped(s string, pos int) bool {
+	n := 0
+	for i := pos - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
 // ParsePath parses the input xpath and return a single element with its attrs.
 func ParsePath(path *string) ([]*PathNode, error) {
 	node := make([]*PathNode, 0, 8)
@@ -208,6 +396,8 @@ func ParsePath(path *string) ([]*PathNode, error) {
 	// insideBrackets is counted up when at least one '[' has been found.
 	// It is counted down when a closing ']' has been found.
 	insideBrackets := 0
+	// quote is the quotation mark of the xpath literal in the predicates.
+	var quote byte
 	switch (*path)[end] {
 	case '/':
 		pathnode.Select = NodeSelectFromRoot
@@ -220,7 +410,19 @@ func ParsePath(path *string) ([]*PathNode, error) {
 	}
 	end++
 	for end < length {
+		if quote != 0 {
+			// the literal is opaque.
+			if (*path)[end] == quote {
+				quote = 0
+			}
+			end++
+			continue
+		}
 		switch (*path)[end] {
+		case '\'', '"':
+			if insideBrackets > 0 && isLiteralStart(*path, end) {
+				quote = (*path)[end]
+			}
 		case '/':
 			if insideBrackets <= 0 {
 				if (*path)[end-1] == '/' {
@@ -236,7 +438,7 @@ func ParsePath(path *string) ([]*PathNode, error) {
 				pathnode = &PathNode{}
 			}
 		case '[':
-			if (*path)[end-1] != '\\' {
+			if !isEscaped(*path, end) {
 				if insideBrackets <= 0 {
 					if begin < end {
 						pathnode.Name = (*path)[begin:end]
@@ -246,7 +448,7 @@ func ParsePath(path *string) ([]*PathNode, error) {
 				insideBrackets++
 			}
 		case ']':
-			if (*path)[end-1] != '\\' {
+			if !isEscaped(*path, end) {
 				insideBrackets--
 				if insideBrackets <= 0 {
 					// if end < 2 || (*path)[end-2:end+1] != "=*]" { // * wildcard inside predicates
@@ -272,7 +474,7 @@ func ParsePath(path *string) ([]*PathNode, error) {
 		}
 		end++
 	}
-	if insideBrackets > 0 {
+	if insideBrackets > 0 || quote != 0 {
 		return nil, fmt.Errorf("invalid path format %s", *path)
 	}
 
@@ -286,6 +488,86 @@ func ParsePath(path *string) ([]*PathNode, error) {
 	return node, nil
 }
 
+// gNMI path strings (https://github.com/openconfig/reference/blob/master/rpc/gnmi/gnmi-path-strings.md)
+// are similar to the yangtree path, but the key values are handled differently.
+//  - The key values are not xpath literals. The quotation marks are a part of the key values.
+//  - Only ']' and '\' are escaped by '\' in the key values. '[' is not escaped.
+//  - Each key predicate must be the 'name=value' form.
+
+// gnmiKeyValueToPredicate() converts the unescaped key value of a gNMI path to
+// the key value of the yangtree path predicate.
+func gnmiKeyValueToPredicate(value string) (string, error) {
+	switch {
+	case !strings.ContainsAny(value, `'"`):
+		return escapeKeyValue(value), nil
+	case !strings.Contains(value, `'`):
+		return `'` + value + `'`, nil
+	case !strings.Contains(value, `"`):
+		return `"` + value + `"`, nil
+	}
+	return "", fmt.Errorf("key value %s having both quotation marks is not supported", value)
+}
+
+// ConvertGNMIStylePath() converts the path written in gNMI path string conventions
+// to the yangtree path. The key values copied from gNMI tools are escaped or quoted
+// to be used in the yangtree path predicates.
+//   ConvertGNMIStylePath(`/interfaces/interface[name=Ethernet1/1]/config/description`)
+//   ConvertGNMIStylePath(`/network-instances/network-instance[name=vrf[1\]]/config`)
+//     => `/network-instances/network-instance[name=vrf\[1\]]/config`
+func ConvertGNMIStylePath(path string) (string, error) {
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] != '[' {
+			if path[i] == ']' {
+				return "", fmt.Errorf("unexpected ']' at %d in gNMI path %s", i, path)
+			}
+			b.WriteByte(path[i])
+			continue
+		}
+		eq := strings.IndexAny(path[i+1:], "=]")
+		if eq < 0 || path[i+1+eq] != '=' || eq == 0 {
+			return "", fmt.Errorf("invalid key predicate at %d in gNMI path %s", i, path)
+		}
+		name := path[i+1 : i+1+eq]
+		var value strings.Builder
+		closed := false
+		j := i + 1 + eq + 1
+		for ; j < len(path); j++ {
+			if path[j] == '\\' && j+1 < len(path) {
+				j++
+				value.WriteByte(path[j])
+				continue
+			}
+			if path[j] == ']' {
+				closed = true
+				break
+			}
+			value.WriteByte(path[j])
+		}
+		if !closed {
+			return "", fmt.Errorf("unterminated key predicate at %d in gNMI path %s", i, path)
+		}
+		v, err := gnmiKeyValueToPredicate(value.String())
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("[" + name + "=" + v + "]")
+		i = j
+	}
+	return b.String(), nil
+}
+
+// ParseGNMIStylePath() parses the path written in gNMI path string conventions.
+// It is the same as ParsePath() except the key values are handled as gNMI does.
+func ParseGNMIStylePath(path string) ([]*PathNode, error) {
+	p, err := ConvertGNMIStylePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePath(&p)
+}
+
 func TokenizeXPathExpr(token []string, s *string, pos int) ([]string, int, error) {
 	var err error
 	length := len((*s))
@@ -308,13 +590,17 @@ func TokenizeXPathExpr(token []string, s *string, pos int) ([]string, int, error
 		}
 		switch (*s)[pos] {
 		case '\'', '"': // xpath literal
+			if w.Len() > 0 {
+				// a quotation mark inside of a word
+				w.WriteByte((*s)[pos])
+				continue
+			}
 			isLiteral = rune((*s)[pos])
 			w.WriteByte('"')
 		case '@':
 			return nil, 0, fmt.Errorf("xml attr in %s not supported", *s)
 		case ' ', '\t', '\n', '\r':
-			e := w.String()
-			if _, ok := opToGoExpr[e]; ok {
+			if w.Len() > 0 {
 				token = append(token, w.String())
 				w.Reset()
 			}
@@ -377,7 +663,15 @@ func TokenizeXPathExpr(token []string, s *string, pos int) ([]string, int, error
 				token = append(token, (*s)[pos:pos+2])
 				pos++
 			default:
-				return nil, 0, fmt.Errorf("invalid syntax %s", (*s))
+				if (*s)[pos] == '!' {
+					return nil, 0, fmt.Errorf("invalid syntax %s", (*s))
+				}
+				// "<" or ">"
+				if w.Len() > 0 {
+					token = append(token, w.String())
+					w.Reset()
+				}
+				token = append(token, (*s)[pos:pos+1])
 			}
 		default:
 			w.WriteByte((*s)[pos])
@@ -417,6 +711,11 @@ func convertToGoExpr(goExpr *strings.Builder, env map[string]interface{}, token
 				break
 			} else if i < length-1 {
 				if token[i+1] == "(" {
+					if l, ok := literalFuncXPath[token[i]]; ok && i+2 < length && token[i+2] == ")" {
+						goExpr.WriteString(l)
+						i += 2
+						break
+					}
 					if f, ok := funcXPath[token[i]]; ok {
 						if fs, ok := f.(string); ok {
 							goExpr.WriteString(fs)
@@ -428,9 +727,9 @@ func convertToGoExpr(goExpr *strings.Builder, env map[string]interface{}, token
 					break
 				}
 			}
-			if strings.HasPrefix(token[i], "\"") &&
-				strings.HasSuffix(token[i], "\"") {
-				goExpr.WriteString(token[i])
+			if literal, ok := trimQuotes(token[i]); ok && token[i][0] == '"' {
+				// the literal may have quotation marks inside.
+				goExpr.WriteString(strconv.Quote(literal))
 			} else if _, err := strconv.ParseBool(token[i]); err == nil {
 				goExpr.WriteString(token[i])
 			} else if _, err := strconv.ParseFloat(token[i], 64); err == nil {
@@ -108,3 +108,282 @@ func TestParsePath(t *testing.T) {
 		})
 	}
 }
+
+func TestEscapedKeyValue(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample":{
+		"single-key-list":[{"list-key":"a]b/c[d","country-code":"KR"}],
+		"multiple-key-list":[{"str":"x\\y=]","integer":1}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		search string
+		path   string
+		keyval string
+	}{
+		{
+			search: "/sample/single-key-list/list-key",
+			path:   `/sample/single-key-list[list-key=a\]b/c\[d]`,
+			keyval: "a]b/c[d",
+		},
+		{
+			search: "/sample/multiple-key-list/str",
+			path:   `/sample/multiple-key-list[str=x\\y=\]][integer=1]`,
+			keyval: `x\y=]`,
+		},
+	}
+	for _, tt := range tests {
+		key, err := Find(root, tt.search)
+		if err != nil || len(key) != 1 {
+			t.Fatalf("key node %s not found: %v", tt.search, err)
+		}
+		if key[0].ValueString() != tt.keyval {
+			t.Errorf("unexpected key value: %s", key[0].ValueString())
+		}
+		node := key[0].Parent()
+		if node.Path() != tt.path {
+			t.Errorf("unexpected path: %s, expected: %s", node.Path(), tt.path)
+		}
+		// Path() must be round-tripped through ParsePath().
+		path := node.Path()
+		pathnode, err := ParsePath(&path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pmap, err := pathnode[len(pathnode)-1].ToMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pmap[key[0].Name()] != tt.keyval {
+			t.Errorf("unexpected key value in the path: %v", pmap[key[0].Name()])
+		}
+		found, err := Find(root, path)
+		if err != nil || len(found) != 1 || found[0] != node {
+			t.Errorf("%s not found: %v", path, err)
+		}
+	}
+	if err := SetValueString(root, `/sample/single-key-list[list-key=a\]b/c\[d]/country-code`, nil, "US"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := Find(root, "/sample/single-key-list"); len(n) != 1 {
+		t.Errorf("the list entry must be updated: %d entries", len(n))
+	}
+	if v, _ := FindValueString(root, `/sample/single-key-list[list-key=a\]b/c\[d]/country-code`); len(v) != 1 || v[0] != "US" {
+		t.Errorf("unexpected country-code: %v", v)
+	}
+}
+
+func TestQuotedPredicateValue(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := NewWithValueString(schema, `{"sample":{"single-key-list":[
+		{"list-key":"a b or c","country-code":"1"},
+		{"list-key":"say \"hi\"","country-code":"2"},
+		{"list-key":"x and y","country-code":"3"},
+		{"list-key":"it's","country-code":"4"},
+		{"list-key":"a]b [c]","country-code":"5"}]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		keyval   string
+		country  string
+		unquoted bool // the key value is not an xpath literal.
+	}{
+		{path: `/sample/single-key-list[list-key='a b or c']`, keyval: "a b or c", country: "1"},
+		{path: `/sample/single-key-list[list-key="a b or c"]`, keyval: "a b or c", country: "1"},
+		{path: `/sample/single-key-list[list-key='say "hi"']`, keyval: `say "hi"`, country: "2"},
+		{path: `/sample/single-key-list[list-key='x and y']`, keyval: "x and y", country: "3"},
+		{path: `/sample/single-key-list[list-key="it's"]`, keyval: "it's", country: "4"},
+		{path: `/sample/single-key-list[list-key=it's]`, keyval: "it's", country: "4", unquoted: true},
+		{path: `/sample/single-key-list[list-key='a]b [c]']`, keyval: "a]b [c]", country: "5"},
+	}
+	for _, tt := range tests {
+		path := tt.path
+		pathnode, err := ParsePath(&path)
+		if err != nil {
+			t.Errorf("ParsePath(%s) error: %v", tt.path, err)
+			continue
+		}
+		pmap, err := pathnode[len(pathnode)-1].ToMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pmap["list-key"] != tt.keyval {
+			t.Errorf("unexpected key value of %s: %v", tt.path, pmap["list-key"])
+		}
+		for _, option := range [][]Option{nil, {UseXPath{}}} {
+			if tt.unquoted && option != nil {
+				continue
+			}
+			found, err := Find(root, tt.path+"/country-code", option...)
+			if err != nil || len(found) != 1 || found[0].ValueString() != tt.country {
+				t.Errorf("Find(%s) with %v = %v, %v", tt.path, option, found, err)
+			}
+		}
+	}
+	token, _, err := TokenizeXPathExpr(nil, &[]string{`list-key = 'a b or c' and country-code != "x"`}[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"list-key", "=", `"a b or c"`, "and", "country-code", "!=", `"x"`}
+	if !reflect.DeepEqual(token, expected) {
+		t.Errorf("unexpected tokens: %q", token)
+	}
+}
+
+func TestParseGNMIStylePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []*PathNode
+		keyval  map[string]interface{}
+		wantErr bool
+	}{
+		{
+			path: "/interfaces/interface[name=Ethernet1/1]/subinterfaces/subinterface[index=0]",
+			want: []*PathNode{
+				{Name: "interfaces", Select: NodeSelectFromRoot},
+				{Name: "interface", Select: NodeSelectChild, Predicates: []string{"name=Ethernet1/1"}},
+				{Name: "subinterfaces", Select: NodeSelectChild},
+				{Name: "subinterface", Select: NodeSelectChild, Predicates: []string{"index=0"}},
+			},
+			keyval: map[string]interface{}{"index": "0"},
+		},
+		{
+			path: "/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp 1]",
+			want: []*PathNode{
+				{Name: "network-instances", Select: NodeSelectFromRoot},
+				{Name: "network-instance", Select: NodeSelectChild, Predicates: []string{"name=default"}},
+				{Name: "protocols", Select: NodeSelectChild},
+				{Name: "protocol", Select: NodeSelectChild, Predicates: []string{"identifier=BGP", "name=bgp 1"}},
+			},
+			keyval: map[string]interface{}{"identifier": "BGP", "name": "bgp 1"},
+		},
+		{
+			path: `/acl/acl-sets/acl-set[name=vrf[1\]][type=ACL_IPV4]`,
+			want: []*PathNode{
+				{Name: "acl", Select: NodeSelectFromRoot},
+				{Name: "acl-sets", Select: NodeSelectChild},
+				{Name: "acl-set", Select: NodeSelectChild, Predicates: []string{`name=vrf\[1\]`, "type=ACL_IPV4"}},
+			},
+			keyval: map[string]interface{}{"name": "vrf[1]", "type": "ACL_IPV4"},
+		},
+		{
+			path: `/a/b[key=c:\\d=e's]`,
+			want: []*PathNode{
+				{Name: "a", Select: NodeSelectFromRoot},
+				{Name: "b", Select: NodeSelectChild, Predicates: []string{`key="c:\d=e's"`}},
+			},
+			keyval: map[string]interface{}{"key": `c:\d=e's`},
+		},
+		{path: "/a/b[key]", wantErr: true},
+		{path: "/a/b[key=c", wantErr: true},
+		{path: "/a/b]", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGNMIStylePath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseGNMIStylePath(%s) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			for i := range got {
+				t.Logf("%d: %+v", i, got[i])
+			}
+			t.Errorf("unexpected result of ParseGNMIStylePath(%s)", tt.path)
+			continue
+		}
+		pmap, err := got[len(got)-1].ToMap()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(pmap, tt.keyval) {
+			t.Errorf("unexpected key values of %s: %v", tt.path, pmap)
+		}
+	}
+}
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    []PredicateTerm
+		wantErr bool
+	}{
+		{
+			expr: "[name='eth0']",
+			want: []PredicateTerm{{Left: "name", Op: "=", Right: "eth0", Literal: true}},
+		},
+		{
+			expr: `[name="eth0"][mtu>=1500]`,
+			want: []PredicateTerm{
+				{Left: "name", Op: "=", Right: "eth0", Literal: true},
+				{Left: "mtu", Op: ">=", Right: "1500"},
+			},
+		},
+		{
+			expr: "name='a and b' and enabled!=false",
+			want: []PredicateTerm{
+				{Left: "name", Op: "=", Right: "a and b", Literal: true},
+				{Left: "enabled", Op: "!=", Right: "false"},
+			},
+		},
+		{
+			expr: "[mtu>1500]",
+			want: []PredicateTerm{{Left: "mtu", Op: ">", Right: "1500"}},
+		},
+		{
+			expr: "mtu<9000 and name!='lo'",
+			want: []PredicateTerm{
+				{Left: "mtu", Op: "<", Right: "9000"},
+				{Left: "name", Op: "!=", Right: "lo", Literal: true},
+			},
+		},
+		{
+			expr: "[.='x']",
+			want: []PredicateTerm{{Left: ".", Op: "=", Right: "x", Literal: true}},
+		},
+		{
+			expr: "[ex:id=current()/../id]",
+			want: []PredicateTerm{{Left: "ex:id", Op: "=", Right: "current()/../id"}},
+		},
+		{
+			expr: "[2]",
+			want: []PredicateTerm{{Left: "2"}},
+		},
+		{
+			expr: "[last()]",
+			want: []PredicateTerm{{Left: "last()"}},
+		},
+		{
+			expr: "[count(../a)=2]",
+			want: []PredicateTerm{{Left: "count(../a)", Op: "=", Right: "2"}},
+		},
+		{expr: "[a=1 or b=2]", wantErr: true},
+		{expr: "[=1]", wantErr: true},
+		{expr: "[a='1]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParsePredicate(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePredicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePredicate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
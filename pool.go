@@ -0,0 +1,118 @@
+package yangtree
+
+import "sync"
+
+// The pools of the data nodes used if YANGTreeOption.UsePool is enabled.
+//
+// The data node allocated from the pool is released to the pool only when it is removed from its
+// parent by an explicit Remove() of the user. All descendants of the removed branch are released
+// together. So the removed data node and the descendants must not be referred after Remove(),
+// including the data nodes returned by Find() before the removal.
+// The data nodes moved to another parent (e.g. by Insert()), detached internally (e.g. the rollback
+// of a failed edit) or deleted by SetValue() and SetValueString() are not released, because
+// they may be kept by the edit callbacks.
+var (
+	branchPool = sync.Pool{New: func() interface{} {
+		return &DataBranch{children: []DataNode{}}
+	}}
+	leafPool     = sync.Pool{New: func() interface{} { return &DataLeaf{} }}
+	leafListPool = sync.Pool{New: func() interface{} { return &DataLeafList{} }}
+)
+
+func usePool(schema *SchemaNode) bool {
+	return schema != nil && schema.Option != nil && schema.Option.UsePool
+}
+
+// allocBranch() returns a new branch from the pool if the pool is used.
+func allocBranch(schema *SchemaNode) *DataBranch {
+	if !usePool(schema) {
+		return &DataBranch{schema: schema, children: []DataNode{}}
+	}
+	branch := branchPool.Get().(*DataBranch)
+	branch.schema = schema
+	return branch
+}
+
+// allocLeaf() returns a new leaf from the pool if the pool is used.
+func allocLeaf(schema *SchemaNode) *DataLeaf {
+	if !usePool(schema) {
+		return &DataLeaf{schema: schema}
+	}
+	leaf := leafPool.Get().(*DataLeaf)
+	leaf.schema = schema
+	return leaf
+}
+
+// allocLeafList() returns a new leaf-list from the pool if the pool is used.
+func allocLeafList(schema *SchemaNode) *DataLeafList {
+	if !usePool(schema) {
+		return &DataLeafList{schema: schema}
+	}
+	leaflist := leafListPool.Get().(*DataLeafList)
+	leaflist.schema = schema
+	return leaflist
+}
+
+// detach() removes the data node from its parent without releasing it to the pool.
+// It is used to move the data node to another parent.
+func detach(node DataNode) error {
+	switch n := node.(type) {
+	case *DataBranch:
+		return n.detach()
+	case *DataLeaf:
+		if n.parent != nil {
+			return n.parent.Delete(n)
+		}
+	case *DataLeafList:
+		if n.parent != nil {
+			return n.parent.Delete(n)
+		}
+	}
+	return nil
+}
+
+// removeNode() removes the data node from its parent like Remove() without releasing it to the pool.
+// It is used for the data nodes deleted by the edits that may be kept by the edit callbacks.
+func removeNode(node DataNode) error {
+	if branch, ok := node.(*DataBranch); ok {
+		if branch.parent == nil {
+			return nil
+		}
+		parent, id := branch.parent, branch.ID()
+		if err := branch.detach(); err != nil {
+			return err
+		}
+		markRemoved(parent, branch.schema, id)
+		return nil
+	}
+	return detach(node)
+}
+
+// releaseNode() releases the data node detached from the tree by Remove() and its descendants
+// to the pools. The data node still having the parent is not released. The parentless data
+// nodes (e.g. the root or the data node not inserted yet) are never removed and released.
+func releaseNode(node DataNode) {
+	if node.Parent() != nil || !usePool(node.Schema()) {
+		return
+	}
+	releaseTree(node)
+}
+
+func releaseTree(node DataNode) {
+	switch n := node.(type) {
+	case *DataBranch:
+		children := n.children
+		for i := range children {
+			releaseTree(children[i])
+			children[i] = nil
+		}
+		*n = DataBranch{children: children[:0]}
+		branchPool.Put(n)
+	case *DataLeaf:
+		*n = DataLeaf{}
+		leafPool.Put(n)
+	case *DataLeafList:
+		*n = DataLeafList{}
+		leafListPool.Put(n)
+	}
+}
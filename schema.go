@@ -11,6 +11,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/goccy/go-json"
 
@@ -37,12 +39,20 @@ type SchemaNode struct {
 	IsState       bool                    // used to indicate the schema node is state node.
 	HasState      bool                    // used to indicate the schema node has a state node at least.
 	OrderedByUser bool                    // used to indicate the ordering of the list or the leaf-list nodes.
+	Choice        *SchemaNode             // The nearest choice schema node containing the schema node
+	Case          *SchemaNode             // The case of the Choice containing the schema node (itself if it is a case or a short-hand case)
 	Option        *YANGTreeOption
 	Modules       *yang.Modules
 	*Extension
 
 	// Set option
 	ContainAny bool
+
+	Mounted map[string]*SchemaNode // used to store the mounted schema trees by the mount-point label (RFC 8528)
+
+	when       string                 // The when XPath of the schema node loaded from the schema cache
+	validators []func(DataNode) error // The validator functions registered by AddValidator()
+	transform  func(string) string    // The transform function registered by RegisterOutputTransform()
 }
 
 type Extension struct {
@@ -110,6 +120,115 @@ func (schema *SchemaNode) QualifiedPath(RFC7951format bool) string {
 	return ""
 }
 
+// CanonicalPath() returns the unambiguous module-qualified schema path of the schema node.
+// Like RFC 7951, a node name is qualified by its module name only if the module of the node is
+// different from the parent's. e.g. /ietf-interfaces:interfaces/interface/ietf-ip:ipv4
+func (schema *SchemaNode) CanonicalPath() string {
+	var path []string
+	for e := schema; e != nil && e.Parent != nil; e = e.Parent {
+		if e.IsCase() || e.IsChoice() {
+			continue
+		}
+		path = append(path, e.canonicalName())
+	}
+	var b strings.Builder
+	for i := len(path) - 1; i >= 0; i-- {
+		b.WriteString("/")
+		b.WriteString(path[i])
+	}
+	return b.String()
+}
+
+// canonicalName() returns the schema node name used for the canonical path.
+func (schema *SchemaNode) canonicalName() string {
+	if schema.Qboundary && schema.Module != nil {
+		return schema.Module.Name + ":" + schema.Name
+	}
+	return schema.Name
+}
+
+// NormalizePath() converts the schema path consisting of any mix of prefixed, module-qualified
+// and unprefixed node names to the canonical form of CanonicalPath(). The relative path is resolved
+// from the schema node. The predicates of the path are kept with their key names unprefixed.
+//   e.g. "/sample:sample/simple:container-val" ==> "/sample:sample/container-val"
+func NormalizePath(schema *SchemaNode, path string) (string, error) {
+	if schema == nil {
+		return "", fmt.Errorf("nil schema")
+	}
+	pathnode, err := ParsePath(&path)
+	if err != nil {
+		return "", err
+	}
+	type element struct {
+		schema     *SchemaNode
+		predicates []string
+	}
+	var elements []element
+	for e := schema; e != nil && e.Parent != nil; e = e.Parent {
+		if e.IsCase() || e.IsChoice() {
+			continue
+		}
+		elements = append([]element{{schema: e}}, elements...)
+	}
+	target := schema
+	for i := range pathnode {
+		switch pathnode[i].Select {
+		case NodeSelectSelf:
+		case NodeSelectParent:
+			if len(elements) == 0 {
+				return "", fmt.Errorf("no parent schema found in %s", path)
+			}
+			elements = elements[:len(elements)-1]
+			target = target.GetRootSchema()
+			if len(elements) > 0 {
+				target = elements[len(elements)-1].schema
+			}
+		case NodeSelectFromRoot:
+			elements = nil
+			target = target.GetRootSchema()
+		case NodeSelectAllChildren, NodeSelectAll:
+			return "", fmt.Errorf("wildcard %s not supported in %s", pathnode[i].Name, path)
+		}
+		if pathnode[i].Value != "" {
+			return "", fmt.Errorf("value %s not allowed in %s", pathnode[i].Value, path)
+		}
+		if pathnode[i].Name == "" || pathnode[i].Select == NodeSelectSelf ||
+			pathnode[i].Select == NodeSelectParent {
+			continue
+		}
+		name := pathnode[i].Name
+		if pathnode[i].Prefix != "" {
+			name = pathnode[i].Prefix + ":" + name
+		}
+		child := target.GetSchema(name)
+		if child == nil {
+			return "", fmt.Errorf("schema %s not found from %s", name, target)
+		}
+		predicates := make([]string, 0, len(pathnode[i].Predicates))
+		for _, p := range pathnode[i].Predicates {
+			if j := strings.Index(p, "="); j > 0 {
+				if k := strings.Index(p[:j], ":"); k >= 0 && child.GetSchema(p[:j]) != nil {
+					p = p[k+1:]
+				}
+			}
+			predicates = append(predicates, p)
+		}
+		elements = append(elements, element{schema: child, predicates: predicates})
+		target = child
+	}
+	var b strings.Builder
+	for i := range elements {
+		b.WriteString("/")
+		b.WriteString(elements[i].schema.canonicalName())
+		for _, p := range elements[i].predicates {
+			b.WriteString("[")
+			b.WriteString(p)
+			b.WriteString("]")
+		}
+	}
+	return b.String(), nil
+}
+
 // Append() adds the nodes to the schema node as child nodes.
 // If the changeParent is set, the Parent of each child becomes the scema node.
 // This affacts the schema tree of a yangtree.
@@ -137,7 +256,66 @@ func (schema *SchemaNode) Append(changeParent bool, nodes ...*SchemaNode) error
 	return nil
 }
 
+// IsMountPoint() returns true if the schema node is the mount point having the label.
+func (schema *SchemaNode) IsMountPoint(label string) bool {
+	for _, ext := range schema.Exts {
+		keyword := strings.SplitN(ext.Keyword, ":", 2)
+		if keyword[len(keyword)-1] == "mount-point" && ext.Argument == label {
+			return true
+		}
+	}
+	return false
+}
+
+// MountSchema() mounts the schema tree to the mount point (yangmnt:mount-point) of the parent schema node.
+// The top-level schema nodes of the mounted schema tree become the children of the parent schema node
+// so that the data nodes of the mounted schema tree can be created, found and marshalled under the
+// data node of the mount point. The mounted schema tree must not be used for other yangtree.
+func MountSchema(parent *SchemaNode, mountLabel string, mounted *SchemaNode) error {
+	if parent == nil || mounted == nil {
+		return fmt.Errorf("nil schema")
+	}
+	if !parent.IsContainer() && !parent.IsList() {
+		return fmt.Errorf("unable to mount the schema to non-container or non-list node %s", parent)
+	}
+	if !parent.IsMountPoint(mountLabel) {
+		return fmt.Errorf("%s is not the mount point labeled %q", parent, mountLabel)
+	}
+	if _, ok := parent.Mounted[mountLabel]; ok {
+		return fmt.Errorf("a schema tree is already mounted to %s labeled %q", parent, mountLabel)
+	}
+	children := []*SchemaNode{mounted}
+	if mounted.IsRoot {
+		children = mounted.Children
+	}
+	if err := parent.Append(true, children...); err != nil {
+		return err
+	}
+	for i := range children {
+		children[i].Qboundary = children[i].Module != parent.Module
+		for _, s := range CollectSchemaEntries(children[i], false) {
+			if parent.IsState {
+				s.IsState = true
+			}
+			if s.IsState {
+				for p := s.Parent; p != nil && !p.HasState; p = p.Parent {
+					p.HasState = true
+				}
+			}
+		}
+	}
+	if parent.Mounted == nil {
+		parent.Mounted = map[string]*SchemaNode{}
+	}
+	parent.Mounted[mountLabel] = mounted
+	return nil
+}
+
 func buildSchemaNode(e *yang.Entry, baseModule *yang.Module, parent *SchemaNode, option *YANGTreeOption, ext *Extension, ms *yang.Modules) (*SchemaNode, error) {
+	if !isFeatureEnabled(e, parent, option) {
+		// excluded from the schema tree
+		return nil, nil
+	}
 	n := &SchemaNode{
 		Entry:     e,
 		Parent:    parent,
@@ -180,6 +358,13 @@ func buildSchemaNode(e *yang.Entry, baseModule *yang.Module, parent *SchemaNode,
 	}
 
 	if parent != nil {
+		switch {
+		case parent.IsChoice():
+			// the case or the data node of the short-hand case
+			n.Choice, n.Case = parent, n
+		case parent.IsCase():
+			n.Choice, n.Case = parent.Choice, parent
+		}
 		switch {
 		case parent.IsChoice(), parent.IsCase():
 			for parent.Parent != nil {
@@ -208,15 +393,22 @@ func buildSchemaNode(e *yang.Entry, baseModule *yang.Module, parent *SchemaNode,
 				n.IsKey = true
 			}
 		}
-		var isconfig yang.TriState
-		for s := e; s != nil; s = s.Parent {
+		// The config statement is inherited from the parent schema node.
+		// The entry chain is only checked up to the parent schema node (through choice and case)
+		// because the parent entry of the augmented node can be the augment statement,
+		// not the augmented target node.
+		isconfig := yang.TSUnset
+		for s := e; s != nil && s != parent.Entry; s = s.Parent {
 			isconfig = s.Config
 			if isconfig != yang.TSUnset {
 				break
 			}
 		}
-		if isconfig == yang.TSFalse {
+		switch isconfig {
+		case yang.TSFalse:
 			n.IsState = true
+		case yang.TSUnset:
+			n.IsState = parent.IsState
 		}
 		if e.Config == yang.TSFalse {
 			for p := parent; p != nil; p = p.Parent {
@@ -224,6 +416,10 @@ func buildSchemaNode(e *yang.Entry, baseModule *yang.Module, parent *SchemaNode,
 			}
 		}
 	}
+	if e.Kind == yang.AnyXMLEntry && e.Type == nil {
+		// anyxml content is handled as an opaque string.
+		e.Type = &yang.YangType{Name: "anyxml", Kind: yang.Ystring}
+	}
 	if err := updatType(n, e.Type); err != nil {
 		return nil, err
 	}
@@ -494,6 +690,84 @@ func (schema *SchemaNode) IsAnyData() bool {
 	return schema.Kind == yang.AnyDataEntry
 }
 
+// IsAnyXML() returns true if the schema node is anyxml.
+// The content of anyxml is not navigable and is handled as an opaque string.
+func (schema *SchemaNode) IsAnyXML() bool {
+	return schema.Kind == yang.AnyXMLEntry
+}
+
+// GetWhenXPath() returns the when XPath statement of the schema node if able.
+func (schema *SchemaNode) GetWhenXPath() (string, bool) {
+	if schema.when != "" {
+		return schema.when, true
+	}
+	return schema.Entry.GetWhenXPath()
+}
+
+// getCaseWhenXPath() returns the when XPath statements of the choice and case
+// statements placed between the schema node and its parent schema node.
+// They are evaluated in the context of the parent data node.
+func (schema *SchemaNode) getCaseWhenXPath() []string {
+	var whens []string
+	for s := schema; s.Choice != nil; s = s.Choice {
+		if s.Case != s {
+			if when, ok := s.Case.GetWhenXPath(); ok {
+				whens = append(whens, when)
+			}
+		}
+		if when, ok := s.Choice.GetWhenXPath(); ok {
+			whens = append(whens, when)
+		}
+	}
+	return whens
+}
+
+// hasWhen() returns true if the schema node or its choice and case statements
+// have when statements.
+func (schema *SchemaNode) hasWhen() bool {
+	if _, ok := schema.GetWhenXPath(); ok {
+		return true
+	}
+	return len(schema.getCaseWhenXPath()) > 0
+}
+
+// cases() returns the case schema nodes of the choice schema node in name order.
+// The data node of the short-hand case is returned as the case.
+func (schema *SchemaNode) cases() []*SchemaNode {
+	if !schema.IsChoice() || schema.Parent == nil {
+		return nil
+	}
+	var cases []*SchemaNode
+	for _, s := range schema.Parent.Children {
+		if s.Choice == schema && s.Case == s {
+			cases = append(cases, s)
+		}
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases
+}
+
+// isPlacedIn() returns true if the schema node is placed in the case schema node.
+func (schema *SchemaNode) isPlacedIn(c *SchemaNode) bool {
+	for s := schema; s.Choice != nil; s = s.Choice {
+		if s.Case == c {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPresence() returns true if the schema node is a presence container.
+func (schema *SchemaNode) IsPresence() bool {
+	if !schema.IsContainer() {
+		return false
+	}
+	if c, ok := schema.Node.(*yang.Container); ok && c.Presence != nil {
+		return true
+	}
+	return false
+}
+
 // IsSingleLeafList() returns true if the schema node is single leaf-list schema.
 func (schema *SchemaNode) IsSingleLeafList() bool {
 	return schema.Option.SingleLeafList && schema.IsLeafList()
@@ -534,6 +808,22 @@ func (schema *SchemaNode) GetQName(rfc7951 bool) (string, bool) {
 	return schema.Name, schema.Qboundary
 }
 
+// GetModuleName() returns the name of the module that defines the schema node.
+func (schema *SchemaNode) GetModuleName() string {
+	if schema.Module == nil {
+		return ""
+	}
+	return schema.Module.Name
+}
+
+// GetNamespace() returns the namespace URI of the module that defines the schema node.
+func (schema *SchemaNode) GetNamespace() string {
+	if schema.Module == nil || schema.Module.Namespace == nil {
+		return ""
+	}
+	return schema.Module.Namespace.Name
+}
+
 func (schema *SchemaNode) GetNamespaceAndPrefix() (string, string) {
 	ns := schema.Module.Namespace
 	prefix := schema.Module.Prefix
@@ -546,6 +836,161 @@ func (schema *SchemaNode) GetNamespaceAndPrefix() (string, string) {
 	return ns.Name, prefix.Name
 }
 
+// ifFeatures() returns the if-feature statements of the yang node.
+func ifFeatures(n yang.Node) []*yang.Value {
+	switch node := n.(type) {
+	case *yang.Container:
+		return node.IfFeature
+	case *yang.Leaf:
+		return node.IfFeature
+	case *yang.LeafList:
+		return node.IfFeature
+	case *yang.List:
+		return node.IfFeature
+	case *yang.Choice:
+		return node.IfFeature
+	case *yang.Case:
+		return node.IfFeature
+	case *yang.AnyData:
+		return node.IfFeature
+	case *yang.AnyXML:
+		return node.IfFeature
+	case *yang.Augment:
+		return node.IfFeature
+	case *yang.Uses:
+		return node.IfFeature
+	case *yang.RPC:
+		return node.IfFeature
+	case *yang.Notification:
+		return node.IfFeature
+	}
+	return nil
+}
+
+// isRootSelected() returns true if the top-level schema entry is selected by the root filter
+// of the option. The top-level path of the filter can be qualified by the module name or prefix.
+// e.g. "/interfaces", "/ietf-interfaces:interfaces", "/if:interfaces/interface"
+func isRootSelected(e *yang.Entry, module *yang.Module, option *YANGTreeOption) bool {
+	if option == nil || len(option.RootFilter) == 0 {
+		return true
+	}
+	// ietf-yang-library is always required to build the yang library data.
+	if module.Name == "ietf-yang-library" {
+		return true
+	}
+	for _, p := range option.RootFilter {
+		top := strings.TrimPrefix(p, "/")
+		if i := strings.IndexAny(top, "/["); i >= 0 {
+			top = top[:i]
+		}
+		if i := strings.Index(top, ":"); i >= 0 {
+			qualifier := top[:i]
+			if qualifier != module.Name && (module.Prefix == nil || qualifier != module.Prefix.Name) {
+				continue
+			}
+			top = top[i+1:]
+		}
+		if top == e.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// isFeatureEnabled() returns true if all if-feature statements of the entry are satisfied
+// by the enabled features of the option. The entry chain is checked up to the parent
+// schema node to include the if-feature statements of the choice, case and augment.
+func isFeatureEnabled(e *yang.Entry, parent *SchemaNode, option *YANGTreeOption) bool {
+	if option == nil || option.Features == nil {
+		return true
+	}
+	for s := e; s != nil; s = s.Parent {
+		if parent != nil && s == parent.Entry {
+			break
+		}
+		if s.Node == nil {
+			continue
+		}
+		for _, iffeature := range ifFeatures(s.Node) {
+			if !evaluateIfFeature(iffeature.Name, yang.RootNode(iffeature), option.Features) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// evaluateIfFeature() evaluates the if-feature expression (e.g. "a and (b or not c)")
+// of the module using the enabled features. The feature of the expression is resolved to
+// its module by the prefix and matched with the enabled feature qualified by the module
+// name (e.g. "module-name:feature-name"). The enabled feature without the module name
+// is matched with the features of all modules.
+func evaluateIfFeature(expr string, module *yang.Module, features []string) bool {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	tokens := strings.Fields(expr)
+	enabled := func(name string) bool {
+		var mname string
+		prefix, name := SplitQName(&name)
+		if m := module; m != nil {
+			if prefix != "" {
+				m = yang.FindModuleByPrefix(module, prefix)
+			}
+			if m != nil {
+				mname = m.Name
+				if m.BelongsTo != nil {
+					mname = m.BelongsTo.Name
+				}
+			}
+		}
+		for i := range features {
+			fmodule, f := SplitQName(&features[i])
+			if f != name {
+				continue
+			}
+			if fmodule == "" || mname == "" || fmodule == mname {
+				return true
+			}
+		}
+		return false
+	}
+	var parseOr, parseFactor func() bool
+	parseFactor = func() bool {
+		if len(tokens) == 0 {
+			return false
+		}
+		token := tokens[0]
+		tokens = tokens[1:]
+		switch token {
+		case "not":
+			return !parseFactor()
+		case "(":
+			v := parseOr()
+			if len(tokens) > 0 && tokens[0] == ")" {
+				tokens = tokens[1:]
+			}
+			return v
+		}
+		return enabled(token)
+	}
+	parseAnd := func() bool {
+		v := parseFactor()
+		for len(tokens) > 0 && tokens[0] == "and" {
+			tokens = tokens[1:]
+			v = parseFactor() && v
+		}
+		return v
+	}
+	parseOr = func() bool {
+		v := parseAnd()
+		for len(tokens) > 0 && tokens[0] == "or" {
+			tokens = tokens[1:]
+			v = parseAnd() || v
+		}
+		return v
+	}
+	return parseOr()
+}
+
 // getModule() returns the module strcture of the schema node.
 func getModule(e *yang.Entry, base *yang.Module, ms *yang.Modules) *yang.Module {
 	var m *yang.Module
@@ -554,6 +999,13 @@ func getModule(e *yang.Entry, base *yang.Module, ms *yang.Modules) *yang.Module
 		if len(nname) > 1 {
 			return yang.FindModuleByPrefix(base, nname[0])
 		} else if base != nil {
+			// The augmented node is placed in the namespace of the augmenting module,
+			// not in the namespace of the parent (augmented) node.
+			if ns := e.Namespace(); ns.Name != "" && base.Namespace != nil && ns.Name != base.Namespace.Name {
+				if m, _ = ms.FindModuleByNamespace(ns.Name); m != nil {
+					return m
+				}
+			}
 			return base
 		}
 	}
@@ -680,6 +1132,7 @@ func generateSchemaTree(d, f, e []string, option ...Option) (*SchemaNode, error)
 			schemaOption = o
 		}
 	}
+	schemaOption.warnings = &warnings{}
 	ext := &Extension{
 		ExtSchema:      make(map[string]*SchemaNode),
 		MetadataSchema: make(map[string]*SchemaNode),
@@ -746,6 +1199,10 @@ func generateSchemaTree(d, f, e []string, option ...Option) (*SchemaNode, error)
 	// Those are the only modules we want to print below.
 	var modnames []string
 	root := buildRootSchema(ms.Modules["yangtree"], &schemaOption, ext, ms)
+	// the built-in annotations (e.g. yangtree:modified)
+	if err := collectExtension(ms.Modules["yangtree"], &schemaOption, ext, ms); err != nil {
+		return nil, err
+	}
 	for modname := range ms.Modules {
 		if strings.HasPrefix(modname, "yangtree") ||
 			strings.Contains(modname, "@") {
@@ -766,9 +1223,24 @@ func generateSchemaTree(d, f, e []string, option ...Option) (*SchemaNode, error)
 			collectExtension(ms.Modules[modname], &schemaOption, ext, ms)
 			entry := yang.ToEntry(ms.Modules[modname])
 			for _, schema := range entry.Dir {
-				if _, ok := root.Entry.Dir[schema.Name]; ok {
-					return nil, fmt.Errorf(
-						"duplicated schema %s found", entry.Name)
+				if !isRootSelected(schema, ms.Modules[modname], &schemaOption) {
+					continue
+				}
+				if existing, ok := root.Entry.Dir[schema.Name]; ok {
+					if !schemaOption.PrefixDuplicatedTopNodes {
+						return nil, fmt.Errorf("duplicated schema %s found in module %s and %s",
+							schema.Name, entryModuleName(existing), modname)
+					}
+					prefix := modname
+					if module := ms.Modules[modname]; module.Prefix != nil {
+						prefix = module.Prefix.Name
+					}
+					renamed := prefix + "_" + schema.Name
+					if _, ok := root.Entry.Dir[renamed]; ok {
+						return nil, fmt.Errorf("duplicated schema %s (renamed from %s) found in module %s",
+							renamed, schema.Name, modname)
+					}
+					schema.Name = renamed
 				}
 				schema.Parent = root.Entry
 				root.Entry.Dir[schema.Name] = schema
@@ -786,6 +1258,14 @@ func generateSchemaTree(d, f, e []string, option ...Option) (*SchemaNode, error)
 	return root, nil
 }
 
+// entryModuleName() returns the name of the module defining the top-level entry.
+func entryModuleName(e *yang.Entry) string {
+	if name, err := e.InstantiatingModule(); err == nil {
+		return name
+	}
+	return "unknown"
+}
+
 // Load() loads all yang files and then build the schema tree of the files.
 // dir is reference directories for imported or included yang files.
 // excluded is yang module names to be excluded.
@@ -886,6 +1366,85 @@ func (schema *SchemaNode) FindSchema(path string) *SchemaNode {
 	return target
 }
 
+// IsConfigPath() returns true if the schema node addressed by the path is a config node.
+// It returns false if the path addresses a state (config false) node.
+func (schema *SchemaNode) IsConfigPath(path string) (bool, error) {
+	target := schema.FindSchema(path)
+	if target == nil {
+		return false, Errorf(ETagUnknownElement, "schema node %s not found from %s", path, schema.Name)
+	}
+	return !target.IsState, nil
+}
+
+// Complete() returns the possible next path elements of the partial path for the autocompletion.
+// The partial path is split into the complete path resolved by FindSchema() and the last
+// incomplete element, and the children of the resolved schema node starting with the incomplete
+// element are returned in order. The keyed list is also suggested with its key placeholders.
+// e.g. Complete(schema, "/sample/sin") returns
+//   []string{"single-key-list", "single-key-list[list-key=<list-key>]"}
+func Complete(schema *SchemaNode, partial string) []string {
+	if schema == nil {
+		return nil
+	}
+	// find the last path separator outside of the predicates.
+	sep := -1
+	var quote byte
+	insideBrackets := 0
+	for i := 0; i < len(partial); i++ {
+		switch c := partial[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			if insideBrackets > 0 {
+				quote = c
+			}
+		case c == '[':
+			insideBrackets++
+		case c == ']':
+			insideBrackets--
+		case c == '/' && insideBrackets <= 0:
+			sep = i
+		}
+	}
+	if insideBrackets > 0 || quote != 0 {
+		return nil // the predicates are not completed.
+	}
+	base, prefix := "", partial
+	if sep >= 0 {
+		base, prefix = partial[:sep+1], partial[sep+1:]
+	}
+	parent := schema
+	if base != "" {
+		parent = schema.FindSchema(base)
+		if parent == nil {
+			return nil
+		}
+	}
+	var suggestions []string
+	for _, cschema := range parent.Children {
+		name := cschema.Name
+		if strings.Contains(prefix, ":") && cschema.Module != nil {
+			name = cschema.Module.Name + ":" + cschema.Name
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		suggestions = append(suggestions, name)
+		if cschema.IsListHasKey() {
+			var keys strings.Builder
+			keys.WriteString(name)
+			for _, kname := range cschema.Keyname {
+				keys.WriteString("[" + kname + "=<" + kname + ">]")
+			}
+			suggestions = append(suggestions, keys.String())
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
 // extractSchemaName extracts the schema name from the keystr.
 func extractSchemaName(keystr *string) (string, bool, error) {
 	i := strings.IndexAny(*keystr, "[=]")
@@ -935,7 +1494,7 @@ func extractKeyValues(keys []string, keystr *string) ([]string, error) {
 			}
 			end++
 		case '[':
-			if (*keystr)[end-1] != '\\' {
+			if !isEscaped(*keystr, end) {
 				if insideBrackets <= 0 {
 					begin = end + 1
 				}
@@ -943,11 +1502,11 @@ func extractKeyValues(keys []string, keystr *string) ([]string, error) {
 			}
 			end++
 		case ']':
-			if (*keystr)[end-1] != '\\' {
+			if !isEscaped(*keystr, end) {
 				insideBrackets--
 				if insideBrackets <= 0 {
 					// fmt.Println((*keystr)[begin:end])
-					keyval[index-1] = (*keystr)[begin:end]
+					keyval[index-1] = unescapeKeyValue((*keystr)[begin:end])
 					begin = end + 1
 				}
 			}
@@ -977,24 +1536,24 @@ func extractKeyValues(keys []string, keystr *string) ([]string, error) {
 
 // ValueToValidTypeValue() check the range, length and pattern of the schema.
 func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interface{}) (interface{}, error) {
+	v, err := valueToValidTypeValue(schema, typ, value)
+	if err != nil {
+		if s, ok := value.(string); ok {
+			return schema.lenientEnumValue(typ, s, err)
+		}
+	}
+	return v, err
+}
+
+func valueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interface{}) (interface{}, error) {
 	switch typ.Kind {
 	case yang.Ystring, yang.Ybinary:
 		v, ok := value.(string)
 		if !ok {
 			return nil, fmt.Errorf("invalid value type \"%T\" inserted for %s", value, schema)
 		}
-		if len(typ.Range) > 0 {
-			length := yang.FromInt(int64(len(v)))
-			inrange := false
-			for i := range typ.Range {
-				if !(typ.Range[i].Max.Less(length) || length.Less(typ.Range[i].Min)) {
-					inrange = true
-				}
-			}
-			if inrange {
-				return value, nil
-			}
-			return nil, fmt.Errorf("%s is out of the range, %v", value, typ.Range)
+		if err := checkStringLength(typ, v); err != nil {
+			return nil, err
 		}
 
 		// Check that the value satisfies any regex patterns.
@@ -1094,8 +1653,8 @@ func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interfa
 		if !ok {
 			return nil, fmt.Errorf("invalid value type \"%T\" inserted for %s", value, schema)
 		}
-		if _, ok := schema.Enum[v]; ok {
-			return value, nil
+		if e, ok := schema.lookupEnum(v); ok {
+			return e, nil
 		}
 		return nil, fmt.Errorf("enum %s not found", value)
 	case yang.Ybits:
@@ -1107,7 +1666,7 @@ func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interfa
 		if len(bits) > 0 {
 			bitlist := make([]int64, 0, len(bits))
 			for i := range bits {
-				v, ok := schema.Bits[bits[i]]
+				v, ok := schema.lookupBits(bits[i])
 				if !ok {
 					return nil, fmt.Errorf("bits %s not found", bits[i])
 				}
@@ -1126,13 +1685,12 @@ func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interfa
 			return nil, fmt.Errorf("invalid value type \"%T\" inserted for %s", value, schema)
 		}
 		if i := strings.Index(v, ":"); i >= 0 {
-			iref := v[i+1:]
-			if _, ok := schema.Identityref[iref]; ok {
+			if iref, ok := schema.lookupIdentityref(v[i+1:]); ok {
 				return iref, nil
 			}
 		} else {
-			if _, ok := schema.Identityref[v]; ok {
-				return value, nil
+			if iref, ok := schema.lookupIdentityref(v); ok {
+				return iref, nil
 			}
 		}
 		return nil, fmt.Errorf("identityref %s not found", value)
@@ -1146,14 +1704,20 @@ func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interfa
 		return value, nil
 	case yang.Ydecimal64:
 		var number yang.Number
+		var vstr string
 		switch v := value.(type) {
 		case float32:
 			number = yang.FromFloat(float64(v))
+			vstr = strconv.FormatFloat(float64(v), 'f', -1, 32)
 		case float64:
 			number = yang.FromFloat(float64(v))
+			vstr = strconv.FormatFloat(v, 'f', -1, 64)
 		default:
 			return nil, fmt.Errorf("invalid value type \"%T\" inserted for %s", value, schema)
 		}
+		if err := checkFractionDigits(typ, vstr); err != nil {
+			return nil, err
+		}
 		if len(typ.Range) > 0 {
 			inrange := false
 			for i := range typ.Range {
@@ -1170,7 +1734,7 @@ func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interfa
 		}
 	case yang.Yunion:
 		for i := range typ.Type {
-			v, err := ValueToValidTypeValue(schema, typ.Type[i], value)
+			v, err := valueToValidTypeValue(schema, typ.Type[i], value)
 			if err == nil {
 				return v, nil
 			}
@@ -1187,23 +1751,209 @@ func ValueToValidTypeValue(schema *SchemaNode, typ *yang.YangType, value interfa
 	return nil, fmt.Errorf("invalid value %v (%T) inserted for %s", value, value, schema)
 }
 
+// isCaseInsensitiveEnum() returns true if enum, bits and identityref values are
+// matched case-insensitively.
+func (schema *SchemaNode) isCaseInsensitiveEnum() bool {
+	return schema.Option != nil && schema.Option.CaseInsensitiveEnum
+}
+
+// isLenientEnum() returns true if unknown enum values are stored and reported as warnings.
+func (schema *SchemaNode) isLenientEnum() bool {
+	return schema.Option != nil && schema.Option.LenientEnum
+}
+
+// lenientEnumValue() returns the unknown enum value as it is with a warning if the type is
+// an enumeration or a union having an enumeration and the lenient enum option is set.
+// It is only used after the value is not converted to any type of the union.
+// Otherwise, the error is returned.
+func (schema *SchemaNode) lenientEnumValue(typ *yang.YangType, value string, err error) (interface{}, error) {
+	if !schema.isLenientEnum() || !hasEnumType(typ) {
+		return nil, err
+	}
+	schema.addWarning(fmt.Errorf("unknown enum %s stored to %s", value, schema))
+	return value, nil
+}
+
+// hasEnumType() returns true if the type is an enumeration or a union having an enumeration.
+func hasEnumType(typ *yang.YangType) bool {
+	switch typ.Kind {
+	case yang.Yenum:
+		return true
+	case yang.Yunion:
+		for i := range typ.Type {
+			if hasEnumType(typ.Type[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// maxWarnings is the maximum number of the warnings kept in a schema tree.
+// The oldest warnings are dropped if the warnings exceed it.
+const maxWarnings = 1024
+
+// warnings is used to accumulate the warnings of a schema tree.
+type warnings struct {
+	mutex sync.Mutex
+	list  []error
+}
+
+// addWarning() adds a warning to the schema tree.
+func (schema *SchemaNode) addWarning(err error) {
+	if schema.Option == nil || schema.Option.warnings == nil {
+		return
+	}
+	w := schema.Option.warnings
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.list) >= maxWarnings {
+		n := copy(w.list, w.list[len(w.list)-maxWarnings+1:])
+		w.list = w.list[:n]
+	}
+	w.list = append(w.list, err)
+}
+
+// Warnings() returns the warnings accumulated in the schema tree while data nodes are
+// created or updated. e.g. unknown enum values stored with YANGTreeOption.LenientEnum.
+func (schema *SchemaNode) Warnings() []error {
+	if schema.Option == nil || schema.Option.warnings == nil {
+		return nil
+	}
+	w := schema.Option.warnings
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.list) == 0 {
+		return nil
+	}
+	list := make([]error, len(w.list))
+	copy(list, w.list)
+	return list
+}
+
+// ClearWarnings() clears the warnings accumulated in the schema tree.
+func (schema *SchemaNode) ClearWarnings() {
+	if schema.Option == nil || schema.Option.warnings == nil {
+		return
+	}
+	w := schema.Option.warnings
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.list = nil
+}
+
+// lookupEnum() returns the enum name declared in the schema for the name.
+func (schema *SchemaNode) lookupEnum(name string) (string, bool) {
+	if _, ok := schema.Enum[name]; ok {
+		return name, true
+	}
+	if schema.isCaseInsensitiveEnum() {
+		var matched []string
+		for e := range schema.Enum {
+			if strings.EqualFold(e, name) {
+				matched = append(matched, e)
+			}
+		}
+		return firstFoldMatched(matched)
+	}
+	return "", false
+}
+
+// lookupBits() returns the bit position declared in the schema for the name.
+func (schema *SchemaNode) lookupBits(name string) (int64, bool) {
+	if v, ok := schema.Bits[name]; ok {
+		return v, true
+	}
+	if schema.isCaseInsensitiveEnum() {
+		var matched []string
+		for b := range schema.Bits {
+			if strings.EqualFold(b, name) {
+				matched = append(matched, b)
+			}
+		}
+		if b, ok := firstFoldMatched(matched); ok {
+			return schema.Bits[b], true
+		}
+	}
+	return 0, false
+}
+
+// lookupIdentityref() returns the identity name declared in the schema for the name.
+func (schema *SchemaNode) lookupIdentityref(name string) (string, bool) {
+	if _, ok := schema.Identityref[name]; ok {
+		return name, true
+	}
+	if schema.isCaseInsensitiveEnum() {
+		var matched []string
+		for iref := range schema.Identityref {
+			if strings.EqualFold(iref, name) {
+				matched = append(matched, iref)
+			}
+		}
+		return firstFoldMatched(matched)
+	}
+	return "", false
+}
+
+// firstFoldMatched() returns the first name in the sorted order among the names matched
+// case-insensitively. So the same name is always selected if the schema declares several
+// names only different in case (e.g. "up" and "UP") and none of them exactly matches.
+func firstFoldMatched(matched []string) (string, bool) {
+	if len(matched) == 0 {
+		return "", false
+	}
+	sort.Strings(matched)
+	return matched[0], true
+}
+
+// checkFractionDigits() returns an error if the decimal64 value has more fraction digits
+// than the fraction-digits of the type. The trailing zeros are not counted.
+func checkFractionDigits(typ *yang.YangType, value string) error {
+	i := strings.IndexByte(value, '.')
+	if i < 0 {
+		return nil
+	}
+	if digits := strings.TrimRight(value[i+1:], "0"); len(digits) > typ.FractionDigits {
+		return fmt.Errorf("%s has more fraction digits than the allowed fraction-digits %d", value, typ.FractionDigits)
+	}
+	return nil
+}
+
+// checkStringLength() checks the length of the string value is in the length restriction of the type.
+// The length of the string type is counted in characters, not bytes.
+func checkStringLength(typ *yang.YangType, value string) error {
+	if len(typ.Length) == 0 {
+		return nil
+	}
+	var length yang.Number
+	if typ.Kind == yang.Ybinary {
+		length = yang.FromInt(int64(len(value)))
+	} else {
+		length = yang.FromInt(int64(utf8.RuneCountInString(value)))
+	}
+	for i := range typ.Length {
+		if !(typ.Length[i].Max.Less(length) || length.Less(typ.Length[i].Min)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the length of %s is out of the range, %v", value, typ.Length)
+}
+
 // ValueStringToValue() converts a string value to an yangtree value
 // It also check the range, length and pattern of the schema.
 func ValueStringToValue(schema *SchemaNode, typ *yang.YangType, value string) (interface{}, error) {
+	v, err := valueStringToValue(schema, typ, value)
+	if err != nil {
+		return schema.lenientEnumValue(typ, value, err)
+	}
+	return v, err
+}
+
+func valueStringToValue(schema *SchemaNode, typ *yang.YangType, value string) (interface{}, error) {
 	switch typ.Kind {
 	case yang.Ystring, yang.Ybinary:
-		if len(typ.Range) > 0 {
-			length := yang.FromInt(int64(len(value)))
-			inrange := false
-			for i := range typ.Range {
-				if !(typ.Range[i].Max.Less(length) || length.Less(typ.Range[i].Min)) {
-					inrange = true
-				}
-			}
-			if inrange {
-				return value, nil
-			}
-			return nil, fmt.Errorf("%s is out of the range, %v", value, typ.Range)
+		if err := checkStringLength(typ, value); err != nil {
+			return nil, err
 		}
 
 		// Check that the value satisfies any regex patterns.
@@ -1278,15 +2028,15 @@ func ValueStringToValue(schema *SchemaNode, typ *yang.YangType, value string) (i
 		}
 		return number, nil
 	case yang.Yenum:
-		if _, ok := schema.Enum[value]; ok {
-			return value, nil
+		if e, ok := schema.lookupEnum(value); ok {
+			return e, nil
 		}
 	case yang.Ybits:
 		bits := strings.Split(value, " ")
 		if len(bits) > 0 {
 			bitlist := make([]int64, 0, len(bits))
 			for i := range bits {
-				v, ok := schema.Bits[bits[i]]
+				v, ok := schema.lookupBits(bits[i])
 				if !ok {
 					return nil, fmt.Errorf("bits %s not found", bits[i])
 				}
@@ -1301,13 +2051,12 @@ func ValueStringToValue(schema *SchemaNode, typ *yang.YangType, value string) (i
 		return value, nil
 	case yang.Yidentityref:
 		if i := strings.Index(value, ":"); i >= 0 {
-			iref := value[i+1:]
-			if _, ok := schema.Identityref[iref]; ok {
+			if iref, ok := schema.lookupIdentityref(value[i+1:]); ok {
 				return iref, nil
 			}
 		} else {
-			if _, ok := schema.Identityref[value]; ok {
-				return value, nil
+			if iref, ok := schema.lookupIdentityref(value); ok {
+				return iref, nil
 			}
 		}
 		return nil, fmt.Errorf("identityref %s not found", value)
@@ -1316,6 +2065,9 @@ func ValueStringToValue(schema *SchemaNode, typ *yang.YangType, value string) (i
 		// [FIXME] check the path refered
 		return value, nil
 	case yang.Ydecimal64:
+		if err := checkFractionDigits(typ, value); err != nil {
+			return nil, err
+		}
 		number, err := yang.ParseDecimal(value, uint8(typ.FractionDigits))
 		if err != nil {
 			return nil, err
@@ -1336,7 +2088,7 @@ func ValueStringToValue(schema *SchemaNode, typ *yang.YangType, value string) (i
 		}
 	case yang.Yunion:
 		for i := range typ.Type {
-			v, err := ValueStringToValue(schema, typ.Type[i], value)
+			v, err := valueStringToValue(schema, typ.Type[i], value)
 			if err == nil {
 				return v, nil
 			}
@@ -1387,6 +2139,23 @@ func ValueToValueString(value interface{}) string {
 	return fmt.Sprint(value)
 }
 
+// ParseLeafValue() parses the input value string of the leaf or leaf-list schema node.
+// It returns the typed value and the canonical string of the value that is the same as
+// the value string of the data node. e.g. "20" for the decimal64 value 20.0
+func ParseLeafValue(schema *SchemaNode, input string) (interface{}, string, error) {
+	if schema == nil {
+		return nil, "", fmt.Errorf("nil schema")
+	}
+	if !schema.IsLeaf() && !schema.IsLeafList() {
+		return nil, "", fmt.Errorf("%s is not a leaf or leaf-list schema", schema)
+	}
+	typed, err := ValueStringToValue(schema, schema.Type, input)
+	if err != nil {
+		return nil, "", err
+	}
+	return typed, ValueToValueString(typed), nil
+}
+
 // GetMust() returns the "must" statements of the schema node.
 func (schema *SchemaNode) GetMust() []*yang.Must {
 	switch n := schema.Node.(type) {
@@ -1417,6 +2186,37 @@ func (schema *SchemaNode) GetMust() []*yang.Must {
 	return nil
 }
 
+// GetUnique() returns the unique statements of the list schema node.
+// Each unique statement is represented to the descendant schema node identifiers. e.g. ["ip", "port"]
+func (schema *SchemaNode) GetUnique() [][]string {
+	n, ok := schema.Node.(*yang.List)
+	if !ok {
+		return nil
+	}
+	unique := make([][]string, 0, len(n.Unique))
+	for i := range n.Unique {
+		unique = append(unique, strings.Fields(n.Unique[i].Name))
+	}
+	return unique
+}
+
+// AllowedValues() returns the sorted enum names or identity names allowed for the schema node.
+// It returns nil if the schema node is not an enumeration or identityref typed node.
+func (schema *SchemaNode) AllowedValues() []string {
+	if len(schema.Enum) == 0 && len(schema.Identityref) == 0 {
+		return nil
+	}
+	values := make([]string, 0, len(schema.Enum)+len(schema.Identityref))
+	for name := range schema.Enum {
+		values = append(values, name)
+	}
+	for name := range schema.Identityref {
+		values = append(values, name)
+	}
+	sort.Strings(values)
+	return values
+}
+
 // SplitQName splits the namespace qualified name to prefix and node name.
 func SplitQName(qname *string) (string, string) {
 	if i := strings.Index(*qname, ":"); i >= 0 {
@@ -1484,12 +2284,83 @@ func (schema *SchemaNode) ValueToQValue(typ *yang.YangType, value interface{}, r
 	return v, nil
 }
 
+// RegisterOutputTransform() registers a transform function for the leaf and leaf-list values
+// of the schema path found from the schema node (e.g. the root schema returned by Load()).
+// The value strings of the data nodes are replaced with the result of the transform function
+// while they are marshalled to JSON, YAML and XML documents. The stored values are not changed.
+// It can be used to mask the secrets. The schema path must not have predicates.
+//   RegisterOutputTransform(schema, "/users/user/password", func(string) string { return "****" })
+func RegisterOutputTransform(schema *SchemaNode, schemaPath string, fn func(string) string) error {
+	if fn == nil {
+		return Errorf(EAppTagInvalidArg, "no transform function for %s", schemaPath)
+	}
+	target, err := findTransformSchema(schema, schemaPath)
+	if err != nil {
+		return err
+	}
+	target.transform = fn
+	return nil
+}
+
+// RemoveOutputTransform() removes the transform function registered for the schema path.
+func RemoveOutputTransform(schema *SchemaNode, schemaPath string) error {
+	target, err := findTransformSchema(schema, schemaPath)
+	if err != nil {
+		return err
+	}
+	target.transform = nil
+	return nil
+}
+
+// findTransformSchema() returns the leaf or leaf-list schema node of the schema path
+// used to register the transform function.
+func findTransformSchema(schema *SchemaNode, schemaPath string) (*SchemaNode, error) {
+	if schema == nil {
+		return nil, Errorf(EAppTagInvalidArg, "no schema for the transform of %s", schemaPath)
+	}
+	path := schemaPath
+	pathnode, err := ParsePath(&path)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pathnode {
+		if len(pathnode[i].Predicates) > 0 {
+			return nil, Errorf(EAppTagInvalidArg, "predicates not allowed in the schema path %s", schemaPath)
+		}
+	}
+	target := schema.FindSchema(schemaPath)
+	if target == nil {
+		return nil, Errorf(EAppTagInvalidArg, "schema %s not found", schemaPath)
+	}
+	if !target.IsLeaf() && !target.IsLeafList() {
+		return nil, Errorf(EAppTagInvalidArg, "%s is not a leaf or leaf-list schema", schemaPath)
+	}
+	return target, nil
+}
+
+// outputTransform() returns the transform function registered for the schema.
+func outputTransform(schema *SchemaNode) func(string) string {
+	if schema == nil {
+		return nil
+	}
+	return schema.transform
+}
+
 // ValueToJSONBytes() marshals a value based on its schema, type and representing format.
 func (schema *SchemaNode) ValueToJSONBytes(typ *yang.YangType, value interface{}, rfc7951format bool) ([]byte, error) {
+	return schema.valueToJSONBytes(typ, value, rfc7951format, false)
+}
+
+// valueToJSONBytes() encodes the value to a JSON-encoded data. All integer and decimal64 values
+// are encoded to JSON strings if numbersAsStrings is set in RFC7951 format.
+func (schema *SchemaNode) valueToJSONBytes(typ *yang.YangType, value interface{}, rfc7951format, numbersAsStrings bool) ([]byte, error) {
+	if fn := outputTransform(schema); fn != nil {
+		return json.Marshal(fn(ValueToValueString(value)))
+	}
 	switch typ.Kind {
 	case yang.Yunion:
 		for i := range typ.Type {
-			v, err := schema.ValueToJSONBytes(typ.Type[i], value, rfc7951format)
+			v, err := schema.valueToJSONBytes(typ.Type[i], value, rfc7951format, numbersAsStrings)
 			if err == nil {
 				return v, nil
 			}
@@ -1498,7 +2369,15 @@ func (schema *SchemaNode) ValueToJSONBytes(typ *yang.YangType, value interface{}
 	case yang.YinstanceIdentifier:
 		// [FIXME] The leftmost (top-level) data node name is always in the
 		//   namespace-qualified form (qname).
+	case yang.Yint8, yang.Yint16, yang.Yint32, yang.Yint64,
+		yang.Yuint8, yang.Yuint16, yang.Yuint32, yang.Yuint64:
+		if rfc7951format && numbersAsStrings {
+			return json.Marshal(ValueToValueString(value))
+		}
 	case yang.Ydecimal64:
+		if rfc7951format && numbersAsStrings {
+			return json.Marshal(ValueToValueString(value))
+		}
 		switch v := value.(type) {
 		case yang.Number:
 			return []byte(v.String()), nil
@@ -1547,6 +2426,10 @@ func (schema *SchemaNode) ValueToJSONBytes(typ *yang.YangType, value interface{}
 
 // ValueToYAMLBytes encodes the value to a YAML-encoded data. the schema and the type of the value must be set.
 func (schema *SchemaNode) ValueToYAMLBytes(typ *yang.YangType, value interface{}, rfc7951 bool) ([]byte, error) {
+	if fn := outputTransform(schema); fn != nil {
+		value = fn(ValueToValueString(value))
+		typ = &yang.YangType{Kind: yang.Ystring}
+	}
 	switch typ.Kind {
 	case yang.Yunion:
 		for i := range typ.Type {
@@ -1646,7 +2529,7 @@ func (schema *SchemaNode) GenerateID(pmap map[string]interface{}) (string, bool,
 				id.WriteString("[")
 				id.WriteString(keyname[i])
 				id.WriteString("=")
-				id.WriteString(value)
+				id.WriteString(escapeKeyValue(value))
 				id.WriteString("]")
 			}
 		}
@@ -1662,7 +2545,7 @@ func (schema *SchemaNode) GenerateID(pmap map[string]interface{}) (string, bool,
 			var id bytes.Buffer
 			id.WriteString(schema.Name)
 			id.WriteString("[.=")
-			id.WriteString(v.(string))
+			id.WriteString(escapeKeyValue(v.(string)))
 			id.WriteString("]")
 			return id.String(), false, false
 		}
@@ -1670,3 +2553,144 @@ func (schema *SchemaNode) GenerateID(pmap map[string]interface{}) (string, bool,
 	}
 	return schema.Name, false, false // container, leaf
 }
+
+// TreeOption is used to configure the schema tree output of SchemaNode.Tree().
+type TreeOption struct {
+	Depth         int  // The depth of the schema tree to be printed. All nodes are printed if it is zero or less.
+	PrefixTagging bool // The schema node name is printed with the module prefix if it is set.
+}
+
+// Tree() returns the schema tree of the schema node in the pyang tree format.
+//   +--rw sample
+//      +--rw single-key-list* [list-key]
+//      |  +--rw list-key   string
+//      |  +--ro uint32-range?   uint32 <1..492>
+func (schema *SchemaNode) Tree(opt TreeOption) string {
+	var b strings.Builder
+	if schema.IsRoot {
+		children := sortedSchemaChildren(schema)
+		for i := range children {
+			children[i].writeTree(&b, "", i == len(children)-1, 1, opt)
+		}
+		return b.String()
+	}
+	schema.writeTree(&b, "", true, 1, opt)
+	return b.String()
+}
+
+func sortedSchemaChildren(schema *SchemaNode) []*SchemaNode {
+	children := make([]*SchemaNode, len(schema.Children))
+	copy(children, schema.Children)
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name < children[j].Name
+	})
+	return children
+}
+
+func (schema *SchemaNode) writeTree(b *strings.Builder, indent string, last bool, depth int, opt TreeOption) {
+	b.WriteString(indent)
+	b.WriteString("+--")
+	switch {
+	case schema.IsRPC():
+		b.WriteString("-x")
+	case schema.Kind == yang.NotificationEntry:
+		b.WriteString("-n")
+	case schema.IsState:
+		b.WriteString("ro")
+	default:
+		b.WriteString("rw")
+	}
+	b.WriteString(" ")
+	if opt.PrefixTagging && schema.Prefix != nil {
+		b.WriteString(schema.Prefix.Name + ":")
+	}
+	b.WriteString(schema.Name)
+	switch {
+	case schema.IsListable():
+		b.WriteString("*")
+		if len(schema.Keyname) > 0 {
+			b.WriteString(" [" + strings.Join(schema.Keyname, " ") + "]")
+		}
+	case schema.IsDir():
+	case !schema.IsKey && schema.Mandatory != yang.TSTrue:
+		b.WriteString("?")
+	}
+	if !schema.IsDir() && schema.Type != nil {
+		b.WriteString("   ")
+		b.WriteString(schemaTypeString(schema.Type))
+		if schema.Default != "" {
+			b.WriteString(" = " + schema.Default)
+		}
+	}
+	b.WriteString("\n")
+	if opt.Depth > 0 && depth >= opt.Depth {
+		return
+	}
+	if last {
+		indent = indent + "   "
+	} else {
+		indent = indent + "|  "
+	}
+	children := sortedSchemaChildren(schema)
+	for i := range children {
+		children[i].writeTree(b, indent, i == len(children)-1, depth+1, opt)
+	}
+}
+
+// schemaTypeString() returns the type name with its range and length restrictions.
+func schemaTypeString(typ *yang.YangType) string {
+	var b strings.Builder
+	switch typ.Kind {
+	case yang.Yleafref:
+		b.WriteString("-> " + typ.Path)
+	case yang.Yunion:
+		b.WriteString(typ.Name)
+		if typ.Name == "union" {
+			b.WriteString(" {")
+			for i := range typ.Type {
+				if i > 0 {
+					b.WriteString(" | ")
+				}
+				b.WriteString(schemaTypeString(typ.Type[i]))
+			}
+			b.WriteString("}")
+		}
+	default:
+		b.WriteString(typ.Name)
+	}
+	if len(typ.Range) > 0 && !isBuiltinRange(typ) {
+		b.WriteString(" <" + typ.Range.String() + ">")
+	}
+	if len(typ.Length) > 0 {
+		b.WriteString(" <" + typ.Length.String() + ">")
+	}
+	return b.String()
+}
+
+// isBuiltinRange() returns true if the range of the type is the default range of the built-in type.
+func isBuiltinRange(typ *yang.YangType) bool {
+	var builtin yang.YangRange
+	switch typ.Kind {
+	case yang.Yint8:
+		builtin = yang.Int8Range
+	case yang.Yint16:
+		builtin = yang.Int16Range
+	case yang.Yint32:
+		builtin = yang.Int32Range
+	case yang.Yint64:
+		builtin = yang.Int64Range
+	case yang.Yuint8:
+		builtin = yang.Uint8Range
+	case yang.Yuint16:
+		builtin = yang.Uint16Range
+	case yang.Yuint32:
+		builtin = yang.Uint32Range
+	case yang.Yuint64:
+		builtin = yang.Uint64Range
+	case yang.Ydecimal64:
+		return typ.Base == nil || typ.Base.Range == nil
+	default:
+		return false
+	}
+	return typ.Range.String() == builtin.String()
+}
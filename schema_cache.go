@@ -0,0 +1,555 @@
+package yangtree
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// The schema cache keeps the minimal fields of the schema tree that are used by yangtree.
+// It is used to skip the YANG parsing of goyang when the same YANG files are loaded.
+
+type cachedModule struct {
+	Name      string
+	Namespace string
+	Prefix    string
+}
+
+type cachedType struct {
+	Name           string
+	Kind           yang.TypeKind
+	Base           string
+	Range          yang.YangRange
+	Length         yang.YangRange
+	Pattern        []string
+	POSIXPattern   []string
+	FractionDigits int
+	Path           string
+	Default        string
+	Enum           map[string]int64
+	Bit            map[string]int64
+	IdentityBase   *cachedIdentity
+	Type           []*cachedType
+}
+
+type cachedIdentity struct {
+	Name   string
+	Module string
+	Values []cachedIdentity // The identities derived from the identity
+}
+
+type cachedMust struct {
+	XPath        string
+	ErrorMessage string
+}
+
+type cachedSchemaNode struct {
+	Name          string
+	Kind          yang.EntryKind
+	Config        yang.TriState
+	Mandatory     yang.TriState
+	Key           string
+	Default       string
+	Prefix        string
+	Module        string
+	IsDir         bool
+	IsList        bool
+	IsRPC         bool
+	ContainAny    bool
+	OrderedByUser bool
+	IsState       bool
+	HasState      bool
+	Presence      bool
+	When          string
+	Choice        string // The name of the choice containing the node
+	Case          string // The name of the case of the choice containing the node
+	Must          []cachedMust
+	Unique        []string
+	Type          *cachedType
+	Enum          map[string]int64
+	Bits          map[string]int64
+	Identityref   map[string]string // identity name to module name
+	IfFeature     []string          // The if-feature statements of the node and its choice, case and augment
+	Children      []*cachedSchemaNode
+}
+
+type schemaCache struct {
+	Option     YANGTreeOption
+	Modules    []cachedModule
+	Nodes      []*cachedSchemaNode // The top-level schema nodes of the root schema
+	Ext        []*cachedSchemaNode // The extension schema nodes
+	ExtSchema  map[string]int      // The name of the extension schema to the index of Ext
+	MetaSchema map[string]int      // The name of the metadata schema to the index of Ext
+}
+
+func newCachedType(typ *yang.YangType, modules map[string]*yang.Module) *cachedType {
+	if typ == nil {
+		return nil
+	}
+	ctyp := &cachedType{
+		Name:           typ.Name,
+		Kind:           typ.Kind,
+		Range:          typ.Range,
+		Length:         typ.Length,
+		Pattern:        typ.Pattern,
+		POSIXPattern:   typ.POSIXPattern,
+		FractionDigits: typ.FractionDigits,
+		Path:           typ.Path,
+		Default:        typ.Default,
+	}
+	if typ.Base != nil {
+		ctyp.Base = typ.Base.Name
+	}
+	if typ.Enum != nil {
+		ctyp.Enum = typ.Enum.NameMap()
+	}
+	if typ.Bit != nil {
+		ctyp.Bit = typ.Bit.NameMap()
+	}
+	if typ.IdentityBase != nil {
+		ctyp.IdentityBase = newCachedIdentity(typ.IdentityBase, modules)
+		for i := range typ.IdentityBase.Values {
+			ctyp.IdentityBase.Values = append(ctyp.IdentityBase.Values,
+				*newCachedIdentity(typ.IdentityBase.Values[i], modules))
+		}
+	}
+	for i := range typ.Type {
+		ctyp.Type = append(ctyp.Type, newCachedType(typ.Type[i], modules))
+	}
+	return ctyp
+}
+
+func newCachedIdentity(identity *yang.Identity, modules map[string]*yang.Module) *cachedIdentity {
+	c := &cachedIdentity{Name: identity.Name}
+	if m := yang.RootNode(identity); m != nil {
+		modules[m.Name] = m
+		c.Module = m.Name
+	}
+	return c
+}
+
+// identity() rebuilds the identity placed in the module of the modules.
+func (c *cachedIdentity) identity(ms *yang.Modules) *yang.Identity {
+	identity := &yang.Identity{Name: c.Name}
+	if m := ms.Modules[c.Module]; m != nil {
+		identity.Parent = m
+	}
+	for i := range c.Values {
+		identity.Values = append(identity.Values, c.Values[i].identity(ms))
+	}
+	return identity
+}
+
+func (ctyp *cachedType) yangType(ms *yang.Modules) *yang.YangType {
+	if ctyp == nil {
+		return nil
+	}
+	typ := &yang.YangType{
+		Name:           ctyp.Name,
+		Kind:           ctyp.Kind,
+		Range:          ctyp.Range,
+		Length:         ctyp.Length,
+		Pattern:        ctyp.Pattern,
+		POSIXPattern:   ctyp.POSIXPattern,
+		FractionDigits: ctyp.FractionDigits,
+		Path:           ctyp.Path,
+		Default:        ctyp.Default,
+	}
+	if ctyp.Base != "" {
+		typ.Base = &yang.Type{Name: ctyp.Base}
+	}
+	if ctyp.Enum != nil {
+		typ.Enum = yang.NewEnumType()
+		for name, v := range ctyp.Enum {
+			typ.Enum.Set(name, v)
+		}
+	}
+	if ctyp.Bit != nil {
+		typ.Bit = yang.NewBitfield()
+		for name, v := range ctyp.Bit {
+			typ.Bit.Set(name, v)
+		}
+	}
+	if ctyp.IdentityBase != nil {
+		typ.IdentityBase = ctyp.IdentityBase.identity(ms)
+	}
+	for i := range ctyp.Type {
+		typ.Type = append(typ.Type, ctyp.Type[i].yangType(ms))
+	}
+	return typ
+}
+
+func newCachedSchemaNode(schema *SchemaNode, modules map[string]*yang.Module) *cachedSchemaNode {
+	if schema.Module != nil {
+		modules[schema.Module.Name] = schema.Module
+	}
+	c := &cachedSchemaNode{
+		Name:          schema.Name,
+		Kind:          schema.Kind,
+		Config:        schema.Config,
+		Mandatory:     schema.Mandatory,
+		Key:           schema.Key,
+		Default:       schema.Default,
+		IsDir:         schema.Dir != nil,
+		IsList:        schema.ListAttr != nil,
+		IsRPC:         schema.IsRPC(),
+		ContainAny:    schema.ContainAny,
+		OrderedByUser: schema.OrderedByUser,
+		IsState:       schema.IsState,
+		HasState:      schema.HasState,
+		Presence:      schema.IsPresence(),
+		Type:          newCachedType(schema.Type, modules),
+		Enum:          schema.Enum,
+		Bits:          schema.Bits,
+	}
+	if schema.Prefix != nil {
+		c.Prefix = schema.Prefix.Name
+	}
+	if schema.Module != nil {
+		c.Module = schema.Module.Name
+	}
+	if when, ok := schema.GetWhenXPath(); ok {
+		c.When = when
+	}
+	if schema.Choice != nil {
+		c.Choice = schema.Choice.Name
+		c.Case = schema.Case.Name
+	}
+	// the if-feature statements are collected in the same way as isFeatureEnabled().
+	for e := schema.Entry; e != nil; e = e.Parent {
+		if schema.Parent != nil && e == schema.Parent.Entry {
+			break
+		}
+		if e.Node == nil {
+			continue
+		}
+		for _, iffeature := range ifFeatures(e.Node) {
+			c.IfFeature = append(c.IfFeature, iffeature.Name)
+		}
+	}
+	mustlist := schema.GetMust()
+	for i := range mustlist {
+		xpath, ok := mustlist[i].Source.Arg()
+		if !ok {
+			continue
+		}
+		must := cachedMust{XPath: xpath}
+		if mustlist[i].ErrorMessage != nil {
+			must.ErrorMessage = mustlist[i].ErrorMessage.Name
+		}
+		c.Must = append(c.Must, must)
+	}
+	if list, ok := schema.Node.(*yang.List); ok {
+		for i := range list.Unique {
+			c.Unique = append(c.Unique, list.Unique[i].Name)
+		}
+	}
+	if len(schema.Identityref) > 0 {
+		c.Identityref = make(map[string]string, len(schema.Identityref))
+		for name, m := range schema.Identityref {
+			if m != nil {
+				modules[m.Name] = m
+				c.Identityref[name] = m.Name
+			} else {
+				c.Identityref[name] = ""
+			}
+		}
+	}
+	for i := range schema.Children {
+		c.Children = append(c.Children, newCachedSchemaNode(schema.Children[i], modules))
+	}
+	return c
+}
+
+// node() returns the yang statement node that keeps the must, unique, presence and if-feature statements.
+func (c *cachedSchemaNode) node() yang.Node {
+	var iffeature []*yang.Value
+	for i := range c.IfFeature {
+		iffeature = append(iffeature, &yang.Value{Name: c.IfFeature[i]})
+	}
+	var must []*yang.Must
+	for i := range c.Must {
+		m := &yang.Must{
+			Name:         c.Must[i].XPath,
+			Source:       &yang.Statement{Keyword: "must", HasArgument: true, Argument: c.Must[i].XPath},
+			ErrorMessage: &yang.Value{Name: c.Must[i].ErrorMessage},
+		}
+		must = append(must, m)
+	}
+	switch {
+	case c.Kind == yang.LeafEntry && c.IsList:
+		return &yang.LeafList{Name: c.Name, Must: must, IfFeature: iffeature}
+	case c.Kind == yang.LeafEntry:
+		return &yang.Leaf{Name: c.Name, Must: must, IfFeature: iffeature}
+	case c.Kind == yang.AnyDataEntry:
+		return &yang.AnyData{Name: c.Name, Must: must, IfFeature: iffeature}
+	case c.Kind == yang.AnyXMLEntry:
+		return &yang.AnyXML{Name: c.Name, Must: must, IfFeature: iffeature}
+	case c.Kind == yang.ChoiceEntry:
+		return &yang.Choice{Name: c.Name, IfFeature: iffeature}
+	case c.Kind == yang.CaseEntry:
+		return &yang.Case{Name: c.Name, IfFeature: iffeature}
+	case c.Kind == yang.DirectoryEntry && c.IsList:
+		list := &yang.List{Name: c.Name, Must: must, IfFeature: iffeature}
+		for i := range c.Unique {
+			list.Unique = append(list.Unique, &yang.Value{Name: c.Unique[i]})
+		}
+		return list
+	case c.Kind == yang.DirectoryEntry && !c.IsRPC:
+		container := &yang.Container{Name: c.Name, Must: must, IfFeature: iffeature}
+		if c.Presence {
+			container.Presence = &yang.Value{Name: "true"}
+		}
+		return container
+	}
+	return nil
+}
+
+// schemaNode() rebuilds the schema node from the cached schema node.
+func (c *cachedSchemaNode) schemaNode(parent *SchemaNode, option *YANGTreeOption, ext *Extension,
+	ms *yang.Modules) *SchemaNode {
+	e := &yang.Entry{
+		Name:      c.Name,
+		Kind:      c.Kind,
+		Config:    c.Config,
+		Mandatory: c.Mandatory,
+		Key:       c.Key,
+		Default:   c.Default,
+		Type:      c.Type.yangType(ms),
+		Node:      c.node(),
+	}
+	if c.Prefix != "" {
+		e.Prefix = &yang.Value{Name: c.Prefix}
+	}
+	if c.IsList {
+		e.ListAttr = &yang.ListAttr{}
+		if c.OrderedByUser {
+			e.ListAttr.OrderedBy = &yang.Value{Name: "user"}
+		}
+	}
+	if c.IsDir {
+		e.Dir = map[string]*yang.Entry{}
+	}
+	if c.IsRPC {
+		e.RPC = &yang.RPCEntry{}
+	}
+	n := &SchemaNode{
+		Entry:         e,
+		Parent:        parent,
+		Directory:     map[string]*SchemaNode{},
+		Module:        ms.Modules[c.Module],
+		Enum:          c.Enum,
+		Bits:          c.Bits,
+		Option:        option,
+		Extension:     ext,
+		Modules:       ms,
+		IsState:       c.IsState,
+		HasState:      c.HasState,
+		OrderedByUser: c.OrderedByUser,
+		Qboundary:     true,
+		ContainAny:    c.ContainAny,
+		when:          c.When,
+	}
+	n.Directory["."] = n
+	if c.Key != "" {
+		n.Keyname = strings.Split(c.Key, " ")
+	}
+	if len(c.Bits) > 0 {
+		n.BitsR = make(map[int64]string, len(c.Bits))
+		for name, v := range c.Bits {
+			n.BitsR[v] = name
+		}
+	}
+	if len(c.Identityref) > 0 {
+		n.Identityref = make(map[string]*yang.Module, len(c.Identityref))
+		for name, mname := range c.Identityref {
+			n.Identityref[name] = ms.Modules[mname]
+		}
+	}
+	if parent != nil {
+		e.Parent = parent.Entry
+		if parent.Module == n.Module {
+			n.Qboundary = false
+		}
+		if e.Prefix != nil {
+			parent.Directory[e.Prefix.Name+":"+e.Name] = n
+		}
+		if n.Module != nil {
+			parent.Directory[n.Module.Name+":"+e.Name] = n
+		}
+		parent.Directory[e.Name] = n
+		parent.Children = append(parent.Children, n)
+		for i := range parent.Keyname {
+			if parent.Keyname[i] == e.Name {
+				n.IsKey = true
+			}
+		}
+		switch {
+		case parent.IsRPC() && e.Name == "input":
+			parent.RPC.Input = e
+		case parent.IsRPC() && e.Name == "output":
+			parent.RPC.Output = e
+		case parent.Entry.Dir != nil:
+			parent.Entry.Dir[e.Name] = e
+		}
+	}
+	children := make([]*SchemaNode, 0, len(c.Children))
+	for i := range c.Children {
+		children = append(children, c.Children[i].schemaNode(n, option, ext, ms))
+	}
+	rebuildChoiceCase(c.Children, children)
+	return n
+}
+
+// rebuildChoiceCase() rebuilds the choice and case of the schema nodes rebuilt from the cached schema nodes.
+// The choice and case schema nodes are placed together with the data nodes in the same children.
+func rebuildChoiceCase(cached []*cachedSchemaNode, nodes []*SchemaNode) {
+	for i := range cached {
+		if cached[i].Choice != "" {
+			nodes[i].Choice = findChoiceCase(nodes, cached[i].Choice, true, nil)
+		}
+	}
+	for i := range cached {
+		if nodes[i].Choice != nil {
+			nodes[i].Case = findChoiceCase(nodes, cached[i].Case, false, nodes[i].Choice)
+			if nodes[i].Case == nil { // short-hand case
+				nodes[i].Case = nodes[i]
+			}
+		}
+	}
+}
+
+// findChoiceCase() returns the choice or the case of the choice having the name in the schema nodes.
+func findChoiceCase(nodes []*SchemaNode, name string, isChoice bool, choice *SchemaNode) *SchemaNode {
+	for _, n := range nodes {
+		if n.Name != name {
+			continue
+		}
+		if isChoice && n.IsChoice() {
+			return n
+		}
+		if !isChoice && n.IsCase() && n.Choice == choice {
+			return n
+		}
+	}
+	return nil
+}
+
+// SaveSchemaCache() writes the schema tree loaded by Load() to the writer.
+// The schema cache only keeps the schema fields used by yangtree.
+// The schema tree loaded with the YANG library options cannot be cached.
+func SaveSchemaCache(root *SchemaNode, w io.Writer) error {
+	if root == nil || !root.IsRoot {
+		return Errorf(EAppTagInvalidArg, "root schema node must be inserted for schema cache")
+	}
+	if root.Option != nil && (root.Option.YANGLibrary2016 || root.Option.YANGLibrary2019) {
+		return Errorf(ETagOperationNotSupported, "schema cache not supported for YANG library")
+	}
+	cache := &schemaCache{
+		ExtSchema:  map[string]int{},
+		MetaSchema: map[string]int{},
+	}
+	if root.Option != nil {
+		cache.Option = *root.Option
+	}
+	modules := map[string]*yang.Module{}
+	for i := range root.Children {
+		cache.Nodes = append(cache.Nodes, newCachedSchemaNode(root.Children[i], modules))
+	}
+	if root.Extension != nil {
+		index := map[*SchemaNode]int{}
+		indexOf := func(s *SchemaNode) int {
+			if i, ok := index[s]; ok {
+				return i
+			}
+			index[s] = len(cache.Ext)
+			cache.Ext = append(cache.Ext, newCachedSchemaNode(s, modules))
+			return index[s]
+		}
+		for name, s := range root.ExtSchema {
+			cache.ExtSchema[name] = indexOf(s)
+		}
+		for name, s := range root.MetadataSchema {
+			cache.MetaSchema[name] = indexOf(s)
+		}
+	}
+	for _, m := range modules {
+		cm := cachedModule{Name: m.Name}
+		if m.Namespace != nil {
+			cm.Namespace = m.Namespace.Name
+		}
+		if m.Prefix != nil {
+			cm.Prefix = m.Prefix.Name
+		}
+		cache.Modules = append(cache.Modules, cm)
+	}
+	if err := gob.NewEncoder(w).Encode(cache); err != nil {
+		return fmt.Errorf("schema cache encoding failed: %v", err)
+	}
+	return nil
+}
+
+// LoadSchemaCache() reads the schema tree written by SaveSchemaCache() from the reader.
+func LoadSchemaCache(r io.Reader) (*SchemaNode, error) {
+	var cache schemaCache
+	if err := gob.NewDecoder(r).Decode(&cache); err != nil {
+		return nil, fmt.Errorf("schema cache decoding failed: %v", err)
+	}
+	// the built-in yangtree module is only parsed to build the root schema node.
+	ms := yang.NewModules()
+	yfile, err := Unzip(builtInYangtreeRoot)
+	if err != nil {
+		return nil, err
+	}
+	if err := ms.Parse(string(yfile), "yangtree.yang"); err != nil {
+		return nil, err
+	}
+	// ietf-yang-metadata is imported by the built-in yangtree module.
+	if yfile, err = Unzip(builtInYangMetadata); err != nil {
+		return nil, err
+	}
+	if err := ms.Parse(string(yfile), "ietf-yang-metadata@2016-08-05.yang"); err != nil {
+		return nil, err
+	}
+	if errors := ms.Process(); len(errors) > 0 {
+		return nil, MultipleError(errors)
+	}
+	for i := range cache.Modules {
+		m := &yang.Module{Name: cache.Modules[i].Name}
+		if cache.Modules[i].Namespace != "" {
+			m.Namespace = &yang.Value{Name: cache.Modules[i].Namespace}
+		}
+		if cache.Modules[i].Prefix != "" {
+			m.Prefix = &yang.Value{Name: cache.Modules[i].Prefix}
+		}
+		if _, ok := ms.Modules[m.Name]; !ok {
+			ms.Modules[m.Name] = m
+		}
+	}
+	option := cache.Option
+	option.warnings = &warnings{}
+	ext := &Extension{
+		ExtSchema:      make(map[string]*SchemaNode),
+		MetadataSchema: make(map[string]*SchemaNode),
+	}
+	root := buildRootSchema(ms.Modules["yangtree"], &option, ext, ms)
+	nodes := make([]*SchemaNode, 0, len(cache.Nodes))
+	for i := range cache.Nodes {
+		nodes = append(nodes, cache.Nodes[i].schemaNode(root, &option, ext, ms))
+	}
+	rebuildChoiceCase(cache.Nodes, nodes)
+	extNodes := make([]*SchemaNode, len(cache.Ext))
+	for i := range cache.Ext {
+		extNodes[i] = cache.Ext[i].schemaNode(nil, &option, ext, ms)
+	}
+	for name, i := range cache.ExtSchema {
+		ext.ExtSchema[name] = extNodes[i]
+	}
+	for name, i := range cache.MetaSchema {
+		ext.MetadataSchema[name] = extNodes[i]
+	}
+	return root, nil
+}
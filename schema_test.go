@@ -1,11 +1,16 @@
 package yangtree
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
 )
 
 func TestLoad(t *testing.T) {
@@ -370,3 +375,1000 @@ func TestRESTCONF(t *testing.T) {
 	// 	}
 	// }
 }
+
+func TestSchemaTree(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	sample := schema.FindSchema("/sample")
+	if sample == nil {
+		t.Fatal("sample schema not found")
+	}
+	tree := sample.Tree(TreeOption{})
+	for _, expected := range []string{
+		"+--rw sample\n",
+		"   +--rw single-key-list* [list-key]\n",
+		"   |  +--rw list-key   string\n",
+		"   |  +--ro uint32-range?   uint32 <1..492>\n",
+		"   |  +--rw uint64-node?   uint64\n",
+		"   +--rw multiple-key-list* [str integer]\n",
+		"   +--ro leaf-list-ro*   string\n",
+		"   |  +--rw test-default?   int8 = 1\n",
+	} {
+		if !strings.Contains(tree, expected) {
+			t.Errorf("%q not found in the schema tree:\n%s", expected, tree)
+		}
+	}
+	if tree := sample.Tree(TreeOption{Depth: 1}); tree != "+--rw sample\n" {
+		t.Errorf("unexpected schema tree with depth 1:\n%s", tree)
+	}
+	if tree := schema.Tree(TreeOption{Depth: 1, PrefixTagging: true}); !strings.Contains(tree, "+--rw simple:sample\n") {
+		t.Errorf("unexpected schema tree of the root:\n%s", tree)
+	}
+}
+
+func TestCaseInsensitiveEnum(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/enum-val", nil, "ENUM2"); err == nil {
+		t.Errorf("mixed-case enum must be rejected without CaseInsensitiveEnum")
+	}
+
+	schema, err = Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{CaseInsensitiveEnum: true})
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err = New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/enum-val", nil, "ENUM2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(root, "/sample/bits-val", nil, "Two ZERO"); err != nil {
+		t.Fatal(err)
+	}
+	if v := root.Get("sample").Get("container-val").Get("enum-val").ValueString(); v != "enum2" {
+		t.Errorf("enum must be stored with the schema-declared spelling: %s", v)
+	}
+	if v := root.Get("sample").Get("bits-val").ValueString(); v != "zero two" {
+		t.Errorf("bits must be stored with the schema-declared spelling: %s", v)
+	}
+	j, err := MarshalJSON(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(j), `"enum-val":"enum2"`) {
+		t.Errorf("enum must be emitted with the schema-declared spelling: %s", j)
+	}
+
+	// the enums only different in case are selected deterministically.
+	schema, err = Load([]string{"testdata/modules/enum-case.yang"}, nil, nil, YANGTreeOption{CaseInsensitiveEnum: true})
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err = New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ value, expected string }{
+		{value: "Up", expected: "Up"},
+		{value: "up", expected: "up"},
+		{value: "uP", expected: "UP"},
+	} {
+		for i := 0; i < 10; i++ {
+			if err := SetValueString(root, "/status", nil, tt.value); err != nil {
+				t.Fatal(err)
+			}
+			if v := root.Get("status").ValueString(); v != tt.expected {
+				t.Fatalf("enum %s must be stored as %s: %s", tt.value, tt.expected, v)
+			}
+		}
+	}
+}
+
+func TestLenientEnum(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{LenientEnum: true})
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/sample/container-val/enum-val", nil, "enum1"); err != nil {
+		t.Fatal(err)
+	}
+	if w := schema.Warnings(); len(w) != 0 {
+		t.Errorf("unexpected warnings for a known enum: %v", w)
+	}
+	if err := SetValueString(root, "/sample/container-val/enum-val", nil, "enum-unknown"); err != nil {
+		t.Fatalf("unknown enum must be stored in lenient mode: %v", err)
+	}
+	if err := UnmarshalJSON(root, []byte(`{"sample:sample":{"container-val":{"enum-val":"enum-other"}}}`)); err != nil {
+		t.Fatalf("unknown enum must be unmarshalled in lenient mode: %v", err)
+	}
+	if v := root.Get("sample").Get("container-val").Get("enum-val").ValueString(); v != "enum-other" {
+		t.Errorf("unknown enum must be stored as it is: %s", v)
+	}
+	warnings := schema.Warnings()
+	for _, token := range []string{"enum-unknown", "enum-other"} {
+		reported := false
+		for i := range warnings {
+			if strings.Contains(warnings[i].Error(), token) {
+				reported = true
+			}
+		}
+		if !reported {
+			t.Errorf("unknown enum %s must be reported: %v", token, warnings)
+		}
+	}
+	schema.ClearWarnings()
+	if w := schema.Warnings(); len(w) != 0 {
+		t.Errorf("warnings must be cleared: %v", w)
+	}
+}
+
+func TestLenientEnumUnion(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/lenient-enum-union.yang"}, nil, nil, YANGTreeOption{LenientEnum: true})
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	mode := schema.FindSchema("/mode")
+	if mode == nil {
+		t.Fatal("mode schema not found")
+	}
+	tests := []struct {
+		value   string
+		want    interface{}
+		warning bool
+	}{
+		{value: "auto", want: "auto"},
+		{value: "5", want: uint32(5)},
+		{value: "unknown", want: "unknown", warning: true},
+	}
+	for _, tt := range tests {
+		schema.ClearWarnings()
+		v, err := ValueStringToValue(mode, mode.Type, tt.value)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", tt.value, err)
+			continue
+		}
+		if v != tt.want {
+			t.Errorf("%s must be stored as %v (%T), got %v (%T)", tt.value, tt.want, tt.want, v, v)
+		}
+		if w := schema.Warnings(); (len(w) > 0) != tt.warning {
+			t.Errorf("unexpected warnings for %s: %v", tt.value, w)
+		}
+	}
+	if v, err := ValueToValidTypeValue(mode, mode.Type, "unknown"); err != nil || v != "unknown" {
+		t.Errorf("unknown enum must be stored in lenient mode: %v, %v", v, err)
+	}
+	schema.ClearWarnings()
+	for i := 0; i < maxWarnings+10; i++ {
+		if _, err := ValueStringToValue(mode, mode.Type, fmt.Sprintf("unknown%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := schema.Warnings()
+	if len(w) != maxWarnings {
+		t.Fatalf("the warnings must be limited to %d, got %d", maxWarnings, len(w))
+	}
+	if !strings.Contains(w[len(w)-1].Error(), fmt.Sprintf("unknown%d", maxWarnings+9)) {
+		t.Errorf("the latest warning must be kept: %v", w[len(w)-1])
+	}
+}
+
+func TestStringLength(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	lengthSchema := schema.FindSchema("/sample/length-val")
+	if lengthSchema == nil {
+		t.Fatal("error in finding a length-val schema")
+	}
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "abcd", wantErr: false},
+		{value: "abcde", wantErr: true},
+		{value: "한국어말", wantErr: false}, // 4 characters in 12 bytes
+		{value: "한국어말글", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := ValueStringToValue(lengthSchema, lengthSchema.Type, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("ValueStringToValue(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if _, err := ValueToValidTypeValue(lengthSchema, lengthSchema.Type, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("ValueToValidTypeValue(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDecimalFractionDigits(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	decimalSchema := schema.FindSchema("/sample/single-key-list/decimal-range")
+	if decimalSchema == nil {
+		t.Fatal("error in finding a decimal-range schema")
+	}
+	tests := []struct {
+		value   interface{}
+		wantErr bool
+	}{
+		{value: "1.01"},
+		{value: "1.010"}, // trailing zeros are allowed.
+		{value: "1.011", wantErr: true},
+		{value: "20.123456", wantErr: true},
+		{value: float64(2.5)},
+		{value: float64(2.555), wantErr: true},
+	}
+	for _, tt := range tests {
+		var err error
+		if s, ok := tt.value.(string); ok {
+			_, err = ValueStringToValue(decimalSchema, decimalSchema.Type, s)
+		} else {
+			_, err = ValueToValidTypeValue(decimalSchema, decimalSchema.Type, tt.value)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("decimal64 value %v error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if err != nil && tt.wantErr && !strings.Contains(err.Error(), "fraction-digits 2") {
+			t.Errorf("the error must name the allowed fraction-digits: %v", err)
+		}
+	}
+}
+
+func TestParseLeafValue(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	tests := []struct {
+		path      string
+		input     string
+		typed     interface{}
+		canonical string
+		wantErr   bool
+	}{
+		{path: "/sample/single-key-list/decimal-range", input: "10.10", typed: float64(10.1), canonical: "10.1"},
+		{path: "/sample/single-key-list/decimal-range", input: "20", typed: float64(20), canonical: "20"},
+		{path: "/sample/single-key-list/decimal-range", input: "5", wantErr: true},
+		{path: "/sample/single-key-list/int8-range", input: "-10", typed: int8(-10), canonical: "-10"},
+		{path: "/sample/single-key-list/uint32-range", input: "493", wantErr: true},
+		{path: "/sample/single-key-list", input: "A", wantErr: true},
+	}
+	for _, tt := range tests {
+		s := schema.FindSchema(tt.path)
+		if s == nil {
+			t.Fatalf("schema %s not found", tt.path)
+		}
+		typed, canonical, err := ParseLeafValue(s, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLeafValue(%s, %q) error = %v, wantErr %v", tt.path, tt.input, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if typed != tt.typed || canonical != tt.canonical {
+			t.Errorf("ParseLeafValue(%s, %q) = (%v, %q), want (%v, %q)", tt.path, tt.input, typed, canonical, tt.typed, tt.canonical)
+		}
+		if canonical != ValueToValueString(typed) {
+			t.Errorf("ParseLeafValue(%s, %q) canonical %q differs from the value string %q", tt.path, tt.input, canonical, ValueToValueString(typed))
+		}
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	yangfiles := []string{
+		"testdata/sample",
+		"testdata/modules/example-last-modified.yang",
+	}
+	dir := []string{"../../openconfig/public/", "../../YangModels/yang"}
+	schema, err := Load(yangfiles, dir, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := SaveSchemaCache(schema, &buf); err != nil {
+		t.Fatalf("error in saving schema cache: %v", err)
+	}
+	cached, err := LoadSchemaCache(&buf)
+	if err != nil {
+		t.Fatalf("error in loading schema cache: %v", err)
+	}
+	if GeneratePath(cached.FindSchema("/sample/single-key-list/list-key"), true, true) !=
+		GeneratePath(schema.FindSchema("/sample/single-key-list/list-key"), true, true) {
+		t.Errorf("unexpected schema path from the schema cache")
+	}
+	jbyte := `{
+		"sample": {
+			"container-val": {"a": "A", "enum-val": "enum3", "leaf-list-val": ["first", "second"], "test-must": 2},
+			"multiple-key-list": {"first": {"1": {"integer": 1, "ok": true, "str": "first"}}},
+			"non-key-list": [{"strval": "XYZ", "uintval": 10}],
+			"single-key-list": {"AAA": {"country-code": "KR", "decimal-range": 1.01, "list-key": "AAA", "uint32-range": 100}},
+			"bits-val": "zero two",
+			"str-val": "abc"
+		}
+	}`
+	for _, s := range []*SchemaNode{schema, cached} {
+		root, err := NewWithValueString(s, jbyte)
+		if err != nil {
+			t.Fatalf("error in creating data: %v", err)
+		}
+		if errs := Validate(root); len(errs) > 0 {
+			t.Errorf("unexpected validation error: %v", errs)
+		}
+		if err := SetValueString(root, "/sample/single-key-list[list-key=AAA]/uint32-range", nil, "1000"); err == nil {
+			t.Errorf("out of range value must be rejected")
+		}
+		if err := SetValueString(root, "/sample/container-val/enum-val", nil, "enum4"); err == nil {
+			t.Errorf("invalid enum value must be rejected")
+		}
+		if err := SetValueString(root, "/sample/container-val/test-must", nil, "3"); err != nil {
+			t.Fatal(err)
+		}
+		if errs := Validate(root); len(errs) == 0 {
+			t.Errorf("must statement must be validated")
+		}
+		if err := SetValueString(root, "/sample/@last-modified", nil, "2015-06-18T17:01:14+02:01"); err != nil {
+			t.Errorf("metadata must be set: %v", err)
+		}
+	}
+	root1, _ := NewWithValueString(schema, jbyte)
+	root2, _ := NewWithValueString(cached, jbyte)
+	for _, option := range [][]Option{nil, {RFC7951Format{}}, {ConfigOnly{}}} {
+		j1, err := MarshalJSON(root1, option...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j2, err := MarshalJSON(root2, option...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(j1) != string(j2) {
+			t.Errorf("different json output from the schema cache:\n%s\n%s", j1, j2)
+		}
+	}
+}
+
+func TestSchemaCacheUnique(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/unique-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := SaveSchemaCache(schema, &buf); err != nil {
+		t.Fatalf("error in saving schema cache: %v", err)
+	}
+	cached, err := LoadSchemaCache(&buf)
+	if err != nil {
+		t.Fatalf("error in loading schema cache: %v", err)
+	}
+	if unique := cached.FindSchema("/services/server").GetUnique(); !reflect.DeepEqual(unique, [][]string{{"ip", "port"}}) {
+		t.Errorf("unexpected unique statements from the schema cache: %v", unique)
+	}
+	root, err := NewWithValueString(cached, `{"services": {"server": [
+		{"name": "a", "ip": "10.0.0.1", "port": 80},
+		{"name": "b", "ip": "10.0.0.1", "port": 80}
+	]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := Validate(root); len(errs) != 1 {
+		t.Errorf("one unique violation expected from the schema cache: %v", errs)
+	}
+}
+
+func TestSchemaCacheNodes(t *testing.T) {
+	schema, err := Load([]string{
+		"testdata/sample",
+		"testdata/modules/choice-case-example.yang",
+		"testdata/modules/feature-example.yang",
+		"testdata/modules/jsonschema-example.yang",
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := SaveSchemaCache(schema, &buf); err != nil {
+		t.Fatalf("error in saving schema cache: %v", err)
+	}
+	cached, err := LoadSchemaCache(&buf)
+	if err != nil {
+		t.Fatalf("error in loading schema cache: %v", err)
+	}
+	features := func(s *SchemaNode) []string {
+		var f []string
+		for e := s.Entry; e != nil; e = e.Parent {
+			if s.Parent != nil && e == s.Parent.Entry {
+				break
+			}
+			if e.Node == nil {
+				continue
+			}
+			for _, iffeature := range ifFeatures(e.Node) {
+				f = append(f, iffeature.Name)
+			}
+		}
+		return f
+	}
+	identities := func(typ *yang.YangType) []string {
+		var names []string
+		if typ.IdentityBase != nil {
+			for _, value := range typ.IdentityBase.Values {
+				names = append(names, yang.RootNode(value).Name+":"+value.NName())
+			}
+		}
+		return names
+	}
+	var compareType func(path string, loaded, cached *yang.YangType)
+	compareType = func(path string, loaded, cached *yang.YangType) {
+		if (loaded == nil) != (cached == nil) {
+			t.Errorf("%s: type %v, cached %v", path, loaded, cached)
+			return
+		}
+		if loaded == nil {
+			return
+		}
+		if loaded.Name != cached.Name || loaded.Kind != cached.Kind || loaded.Path != cached.Path ||
+			loaded.Default != cached.Default || loaded.FractionDigits != cached.FractionDigits {
+			t.Errorf("%s: type %s (%s), cached %s (%s)", path, loaded.Name, loaded.Kind, cached.Name, cached.Kind)
+		}
+		if (loaded.Base == nil) != (cached.Base == nil) || (loaded.Base != nil && loaded.Base.Name != cached.Base.Name) {
+			t.Errorf("%s: different base type of %s", path, loaded.Name)
+		}
+		if (loaded.Enum == nil) != (cached.Enum == nil) ||
+			(loaded.Enum != nil && !reflect.DeepEqual(loaded.Enum.NameMap(), cached.Enum.NameMap())) {
+			t.Errorf("%s: different enum of %s", path, loaded.Name)
+		}
+		if (loaded.Bit == nil) != (cached.Bit == nil) ||
+			(loaded.Bit != nil && !reflect.DeepEqual(loaded.Bit.NameMap(), cached.Bit.NameMap())) {
+			t.Errorf("%s: different bits of %s", path, loaded.Name)
+		}
+		if !reflect.DeepEqual(identities(loaded), identities(cached)) {
+			t.Errorf("%s: identities %v, cached %v", path, identities(loaded), identities(cached))
+		}
+		if len(loaded.Type) != len(cached.Type) {
+			t.Errorf("%s: %d union member types, cached %d", path, len(loaded.Type), len(cached.Type))
+			return
+		}
+		for i := range loaded.Type {
+			compareType(path, loaded.Type[i], cached.Type[i])
+		}
+	}
+	var compare func(loaded, cached *SchemaNode)
+	compare = func(loaded, cached *SchemaNode) {
+		path := GeneratePath(loaded, false, false)
+		if loaded.Name != cached.Name || loaded.Kind != cached.Kind || loaded.Config != cached.Config ||
+			loaded.Mandatory != cached.Mandatory || loaded.Key != cached.Key || loaded.Default != cached.Default ||
+			loaded.IsKey != cached.IsKey || loaded.IsState != cached.IsState || loaded.HasState != cached.HasState ||
+			loaded.OrderedByUser != cached.OrderedByUser || loaded.IsPresence() != cached.IsPresence() {
+			t.Errorf("%s: different schema node from the schema cache", path)
+		}
+		if loaded.when != cached.when {
+			t.Errorf("%s: when %q, cached %q", path, loaded.when, cached.when)
+		}
+		if (loaded.Choice == nil) != (cached.Choice == nil) ||
+			(loaded.Choice != nil && (loaded.Choice.Name != cached.Choice.Name || loaded.Case.Name != cached.Case.Name)) {
+			t.Errorf("%s: different choice and case from the schema cache", path)
+		}
+		if len(loaded.GetMust()) != len(cached.GetMust()) || !reflect.DeepEqual(loaded.GetUnique(), cached.GetUnique()) {
+			t.Errorf("%s: different must or unique from the schema cache", path)
+		}
+		if !reflect.DeepEqual(loaded.Enum, cached.Enum) || !reflect.DeepEqual(loaded.Bits, cached.Bits) ||
+			len(loaded.Identityref) != len(cached.Identityref) {
+			t.Errorf("%s: different enum, bits or identityref from the schema cache", path)
+		}
+		if !reflect.DeepEqual(features(loaded), features(cached)) {
+			t.Errorf("%s: if-feature %v, cached %v", path, features(loaded), features(cached))
+		}
+		compareType(path, loaded.Type, cached.Type)
+		if len(loaded.Children) != len(cached.Children) {
+			t.Errorf("%s: %d children, cached %d", path, len(loaded.Children), len(cached.Children))
+			return
+		}
+		for i := range loaded.Children {
+			compare(loaded.Children[i], cached.Children[i])
+		}
+	}
+	compare(schema, cached)
+}
+
+func BenchmarkLoad(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Load([]string{"testdata/sample"}, nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadSchemaCache(b *testing.B) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := SaveSchemaCache(schema, &buf); err != nil {
+		b.Fatal(err)
+	}
+	cache := buf.Bytes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadSchemaCache(bytes.NewReader(cache)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAugmentedConfigState(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/augment-config-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	tests := []struct {
+		path     string
+		isState  bool
+		hasState bool
+	}{
+		{path: "/top/config", isState: false, hasState: true},
+		{path: "/top/config/enabled", isState: false},
+		{path: "/top/config/oper-status", isState: true},
+		{path: "/top/state", isState: true},
+		{path: "/top/state/counter", isState: true},
+		{path: "/top/state/detail", isState: true},
+		{path: "/top/state/detail/description", isState: true},
+	}
+	for _, tt := range tests {
+		s := schema.FindSchema(tt.path)
+		if s == nil {
+			t.Fatalf("schema %s not found", tt.path)
+		}
+		if s.IsState != tt.isState {
+			t.Errorf("unexpected IsState of %s: %v", tt.path, s.IsState)
+		}
+		if tt.hasState && !s.HasState {
+			t.Errorf("%s must have state nodes", tt.path)
+		}
+	}
+	root, err := NewWithValueString(schema, `{"top":{
+		"config":{"name":"n1","enabled":true,"oper-status":"up"},
+		"state":{"name":"n1","counter":10,"detail":{"description":"desc"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := MarshalJSON(root, ConfigOnly{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(j) != `{"top":{"config":{"enabled":true,"name":"n1"}}}` {
+		t.Errorf("unexpected config-only output: %s", j)
+	}
+	j, err = MarshalJSON(root, StateOnly{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(j), `"enabled"`) || !strings.Contains(string(j), `"oper-status":"up"`) ||
+		!strings.Contains(string(j), `"description":"desc"`) {
+		t.Errorf("unexpected state-only output: %s", j)
+	}
+}
+
+func TestMountSchema(t *testing.T) {
+	schema, err := Load([]string{"testdata/modules/schema-mount-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mounted, err := Load([]string{"testdata/modules/mounted-example.yang"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mountpoint := schema.FindSchema("/devices/device/root")
+	if mountpoint == nil {
+		t.Fatal("mount point schema not found")
+	}
+	if err := MountSchema(schema.FindSchema("/devices"), "device", mounted); err == nil {
+		t.Errorf("MountSchema() must fail for a non mount point")
+	}
+	if err := MountSchema(mountpoint, "unknown", mounted); err == nil {
+		t.Errorf("MountSchema() must fail for an unknown label")
+	}
+	if err := MountSchema(mountpoint, "device", mounted); err != nil {
+		t.Fatalf("MountSchema() error = %v", err)
+	}
+	if err := MountSchema(mountpoint, "device", mounted); err == nil {
+		t.Errorf("MountSchema() must fail for the mount point already mounted")
+	}
+	if s := mountpoint.GetSchema("mounted-example:system"); s == nil || s.Parent != mountpoint {
+		t.Fatalf("mounted schema not found from the mount point")
+	}
+	if s := schema.FindSchema("/devices/device/root/system/server/port"); s == nil {
+		t.Fatalf("mounted schema not found using the schema path")
+	}
+
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/devices/device[name=r1]/root/system/hostname", nil, "router1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValueString(root, "/devices/device[name=r1]/root/system/server[address=10.0.0.1]/port", nil, "830"); err != nil {
+		t.Fatal(err)
+	}
+	node, err := Find(root, "/devices/device[name=r1]/root/mounted-example:system/hostname")
+	if err != nil || len(node) != 1 || node[0].ValueString() != "router1" {
+		t.Fatalf("mounted data not found: %v, %v", node, err)
+	}
+	b, err := MarshalJSON(root, RFC7951Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"mounted-example:system":{`) {
+		t.Errorf("mounted data must be namespace-qualified: %s", b)
+	}
+	copied, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalJSON(copied, b); err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(root, copied) {
+		t.Errorf("mounted data is not round-tripped: %s", b)
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	file := []string{"testdata/modules/feature-example.yang"}
+	tests := []struct {
+		name     string
+		option   []Option
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "no feature filtering",
+			included: []string{"/settings/name", "/settings/mode", "/settings/debug", "/settings/lab/id"},
+		},
+		{
+			name:     "no feature enabled",
+			option:   []Option{YANGTreeOption{Features: []string{}}},
+			included: []string{"/settings/name"},
+			excluded: []string{"/settings/mode", "/settings/debug", "/settings/lab"},
+		},
+		{
+			name:     "advanced",
+			option:   []Option{YANGTreeOption{Features: []string{"feature-example:advanced"}}},
+			included: []string{"/settings/name", "/settings/mode", "/settings/debug"},
+			excluded: []string{"/settings/lab"},
+		},
+		{
+			name:     "advanced and experimental",
+			option:   []Option{YANGTreeOption{Features: []string{"advanced", "experimental"}}},
+			included: []string{"/settings/name", "/settings/mode", "/settings/lab/id"},
+			excluded: []string{"/settings/debug"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Load(file, nil, nil, tt.option...)
+			if err != nil {
+				t.Fatalf("error in loading: %v", err)
+			}
+			root, err := New(schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range tt.included {
+				if schema.FindSchema(p) == nil {
+					t.Errorf("%s must be included in the schema tree", p)
+				}
+			}
+			for _, p := range tt.excluded {
+				if schema.FindSchema(p) != nil {
+					t.Errorf("%s must be excluded from the schema tree", p)
+				}
+				if err := SetValueString(root, p, nil, "true"); err == nil {
+					t.Errorf("setting %s must be rejected", p)
+				}
+			}
+		})
+	}
+}
+
+func TestFeaturesModule(t *testing.T) {
+	file := []string{
+		"testdata/modules/feature-example.yang",
+		"testdata/modules/feature-other.yang",
+	}
+	tests := []struct {
+		name     string
+		features []string
+		included []string
+		excluded []string
+	}{
+		{
+			name:     "advanced of feature-example",
+			features: []string{"feature-example:advanced"},
+			included: []string{"/settings/mode", "/other/example-mode"},
+			excluded: []string{"/other/mode"},
+		},
+		{
+			name:     "advanced of feature-other",
+			features: []string{"feature-other:advanced"},
+			included: []string{"/other/mode"},
+			excluded: []string{"/settings/mode", "/other/example-mode"},
+		},
+		{
+			name:     "advanced of all modules",
+			features: []string{"advanced"},
+			included: []string{"/settings/mode", "/other/mode", "/other/example-mode"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := Load(file, nil, nil, YANGTreeOption{Features: tt.features})
+			if err != nil {
+				t.Fatalf("error in loading: %v", err)
+			}
+			for _, p := range tt.included {
+				if schema.FindSchema(p) == nil {
+					t.Errorf("%s must be included in the schema tree", p)
+				}
+			}
+			for _, p := range tt.excluded {
+				if schema.FindSchema(p) != nil {
+					t.Errorf("%s must be excluded from the schema tree", p)
+				}
+			}
+		})
+	}
+}
+
+func TestRootFilter(t *testing.T) {
+	for _, filter := range [][]string{
+		{"/sample"},
+		{"/sample:sample/container-val"},
+		{"/simple:sample"},
+	} {
+		schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{RootFilter: filter})
+		if err != nil {
+			t.Fatalf("error in loading with %v: %v", filter, err)
+		}
+		if schema.GetSchema("single-leaf-list-ro") != nil {
+			t.Errorf("single-leaf-list-ro must be excluded by the root filter %v", filter)
+		}
+		if schema.FindSchema("/sample/container-val/leaf-list-val") == nil {
+			t.Errorf("/sample subtree must be built with the root filter %v", filter)
+		}
+		root, err := New(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := SetValueString(root, "/sample/single-key-list[list-key=A]/country-code", nil, "KR"); err != nil {
+			t.Errorf("the filtered subtree must be usable: %v", err)
+		}
+		if err := SetValueString(root, "/single-leaf-list-rw-user", nil, "x"); err == nil {
+			t.Errorf("the excluded schema must not be used")
+		}
+	}
+	schema, err := Load([]string{"testdata/sample"}, nil, nil, YANGTreeOption{RootFilter: []string{"/other:sample"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.GetSchema("sample") != nil {
+		t.Errorf("sample qualified by the other module must be excluded")
+	}
+}
+
+func TestNormalizePath(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := schema.FindSchema("/sample/container-val/a").CanonicalPath(); p != "/sample:sample/container-val/a" {
+		t.Errorf("unexpected canonical path %s", p)
+	}
+	if p := schema.FindSchema("/single-leaf-list-ro").CanonicalPath(); p != "/leaf-list-test:single-leaf-list-ro" {
+		t.Errorf("unexpected canonical path %s", p)
+	}
+	sample := schema.FindSchema("/sample")
+	tests := []struct {
+		schema  *SchemaNode
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{schema: schema, path: "/sample/container-val/a", want: "/sample:sample/container-val/a"},
+		{schema: schema, path: "/sample:sample/container-val/a", want: "/sample:sample/container-val/a"},
+		{schema: schema, path: "/simple:sample/simple:container-val/sample:a", want: "/sample:sample/container-val/a"},
+		{schema: sample, path: "container-val/a", want: "/sample:sample/container-val/a"},
+		{schema: sample, path: "../sample/./container-val/a", want: "/sample:sample/container-val/a"},
+		{schema: sample, path: "/single-leaf-list-ro", want: "/leaf-list-test:single-leaf-list-ro"},
+		{schema: schema, path: "/simple:sample/single-key-list[simple:list-key=A]/country-code",
+			want: "/sample:sample/single-key-list[list-key=A]/country-code"},
+		{schema: schema, path: "/sample/unknown", wantErr: true},
+		{schema: schema, path: "/other:sample", wantErr: true},
+		{schema: schema, path: "/sample/*", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizePath(tt.schema, tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizePath(%s) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizePath(%s) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAllowedValues(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := schema.FindSchema("/sample/container-val/enum-val").AllowedValues(); strings.Join(v, ",") != "enum1,enum2,enum3" {
+		t.Errorf("unexpected allowed values of enum-val: %v", v)
+	}
+	if v := schema.FindSchema("/sample/str-val").AllowedValues(); len(v) != 0 {
+		t.Errorf("unexpected allowed values of str-val: %v", v)
+	}
+	schema, err = Load([]string{"testdata/modules/enum-module.yang"}, []string{"testdata/modules"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := schema.FindSchema("/parent/child/config/id").AllowedValues(); strings.Join(v, ",") != "FORTY_TWO,SO_LONG_AND_THANKS_FOR_ALL_THE_FISH" {
+		t.Errorf("unexpected allowed values of identityref: %v", v)
+	}
+}
+
+func TestIsConfigPath(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		config  bool
+		wantErr bool
+	}{
+		{path: "/sample/str-val", config: true},
+		{path: "/sample/single-key-list[list-key=AAA]/country-code", config: true},
+		{path: "/sample/single-key-list/uint32-range", config: false},
+		{path: "/sample/leaf-list-ro", config: false},
+		{path: "/sample/unknown", wantErr: true},
+	}
+	for _, tt := range tests {
+		config, err := schema.IsConfigPath(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("IsConfigPath(%s) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if config != tt.config {
+			t.Errorf("IsConfigPath(%s) = %v, want %v", tt.path, config, tt.config)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		partial string
+		want    []string
+	}{
+		{partial: "/sam", want: []string{"sample"}},
+		{partial: "/sample/sin", want: []string{"single-key-list", "single-key-list[list-key=<list-key>]"}},
+		{partial: "/sample/sample:sin", want: []string{"sample:single-key-list", "sample:single-key-list[list-key=<list-key>]"}},
+		{
+			partial: "/sample/container-val/",
+			want: []string{"a", "b", "enum-val", "leaf-list-val",
+				"test-default", "test-instance-identifier", "test-must"},
+		},
+		{partial: "/sample/container-val/test-d", want: []string{"test-default"}},
+		{partial: "/sample/single-key-list[list-key='a/b']/country", want: []string{"country-code"}},
+		{partial: "/sample/single-key-list[list-key=", want: nil},
+		{partial: "/sample/unknown/", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.partial, func(t *testing.T) {
+			if got := Complete(schema, tt.partial); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicatedTopNodes(t *testing.T) {
+	files := []string{"testdata/modules/default.yang", "testdata/modules/empty.yang"}
+	_, err := Load(files, nil, nil)
+	if err == nil {
+		t.Fatalf("Load() must fail for the duplicated top-level nodes")
+	}
+	expected := "duplicated schema test found in module default and empty"
+	if err.Error() != expected {
+		t.Errorf("unexpected error:\n got: %v\nwant: %s", err, expected)
+	}
+
+	schema, err := Load(files, nil, nil, YANGTreeOption{PrefixDuplicatedTopNodes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := schema.GetSchema("test"); s == nil || s.Module.Name != "default" {
+		t.Errorf("the first top-level node must be kept: %v", s)
+	}
+	renamed := schema.GetSchema("e_test")
+	if renamed == nil || renamed.Module.Name != "empty" {
+		t.Fatalf("the duplicated top-level node must be renamed with its module prefix")
+	}
+	if renamed.FindSchema("config/e") == nil {
+		t.Errorf("the descendants of the renamed node must be built")
+	}
+}
+
+func TestAugmentedQboundary(t *testing.T) {
+	schema, err := Load([]string{
+		"testdata/modules/openconfig-simple-target.yang",
+		"testdata/modules/openconfig-simple-augment.yang",
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path      string
+		module    string
+		qboundary bool
+	}{
+		{path: "/target", module: "openconfig-simple-target", qboundary: true},
+		{path: "/target/foo", module: "openconfig-simple-augment", qboundary: true},
+		{path: "/target/foo/config", module: "openconfig-simple-augment", qboundary: false},
+		{path: "/target/foo/state/a", module: "openconfig-simple-augment", qboundary: false},
+		{path: "/native/config/a", module: "openconfig-simple-target", qboundary: false},
+	}
+	for _, tt := range tests {
+		s := schema.FindSchema(tt.path)
+		if s == nil {
+			t.Fatalf("schema %s not found", tt.path)
+		}
+		if s.Module.Name != tt.module || s.Qboundary != tt.qboundary {
+			t.Errorf("%s: module = %s, qboundary = %v, want %s, %v",
+				tt.path, s.Module.Name, s.Qboundary, tt.module, tt.qboundary)
+		}
+	}
+	root, err := NewWithValueString(schema, `{"target":{"foo":{"config":{"a":"x"}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := MarshalJSON(root, RFC7951Format{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"openconfig-simple-target:target":{"openconfig-simple-augment:foo":{"config":{"a":"x"}}}}`
+	if string(j) != expected {
+		t.Errorf("unexpected RFC7951 json:\n got: %s\nwant: %s", j, expected)
+	}
+	x, err := MarshalXML(root.Get("target"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(x), `<foo xmlns="urn:a">`) {
+		t.Errorf("the augmented node must be placed in the augmenting module namespace: %s", x)
+	}
+}
@@ -1,5 +1,7 @@
 package yangtree
 
+import "github.com/openconfig/goyang/pkg/yang"
+
 // TrvsCallOption is an argument of Traverse() to decide where user-defined traverser() is called.
 //  - TrvsCalledAtEnter TrvsCallOption // call user-defined traverser() at the entrance of of child nodes.
 //  - TrvsCalledAtExit                     // call user-defined traverser() at the exit of of child nodes.
@@ -70,3 +72,47 @@ func traverse(node DataNode, arg *traverseArg) error {
 	}
 	return nil
 }
+
+// LeafOnly option is used to select leaf and leaf-list data nodes only.
+type LeafOnly struct{}
+
+func (f LeafOnly) IsOption()      {}
+func (f LeafOnly) String() string { return "leaf-only" }
+
+// AllPaths() returns the paths of all descendants of the node.
+// The options [LeafOnly, ConfigOnly, StateOnly] can be used to filter the paths.
+//  - LeafOnly: only the paths of leaf and leaf-list data nodes are returned.
+//  - ConfigOnly: only the paths of config data nodes are returned.
+//  - StateOnly: only the paths of state data nodes are returned.
+func AllPaths(node DataNode, option ...Option) []string {
+	var leafOnly bool
+	configOnly := yang.TSUnset
+	for i := range option {
+		switch option[i].(type) {
+		case LeafOnly:
+			leafOnly = true
+		case ConfigOnly:
+			configOnly = yang.TSTrue
+		case StateOnly:
+			configOnly = yang.TSFalse
+		}
+	}
+	var paths []string
+	traverser := func(n DataNode, at TrvsCallOption) error {
+		if n == node {
+			return nil
+		}
+		switch {
+		case configOnly == yang.TSTrue && n.IsStateNode():
+			return nil
+		case configOnly == yang.TSFalse && !n.IsStateNode():
+			return nil
+		}
+		paths = append(paths, n.Path())
+		return nil
+	}
+	if err := Traverse(node, traverser, TrvsCalledAtEnter, -1, leafOnly); err != nil {
+		return nil
+	}
+	return paths
+}
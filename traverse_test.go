@@ -90,4 +90,36 @@ func TestTraverse(t *testing.T) {
 	if count != 24 {
 		t.Errorf("invalid number traversing nodes, %d", count)
 	}
+
+	if paths := AllPaths(root1); len(paths) != 31 {
+		t.Errorf("invalid number of all paths, %d", len(paths))
+	}
+	paths := AllPaths(root1, LeafOnly{})
+	if len(paths) != 24 {
+		t.Errorf("invalid number of leaf paths, %d", len(paths))
+	}
+	for _, p := range []string{
+		"/sample/str-val",
+		"/sample/container-val/a",
+		"/sample/single-key-list[list-key=AAA]/country-code",
+		"/sample/single-key-list[list-key=AAA]/uint32-range",
+	} {
+		found := false
+		for i := range paths {
+			if paths[i] == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("path %s not found in %v", p, paths)
+		}
+	}
+	if paths := AllPaths(root1, LeafOnly{}, ConfigOnly{}); len(paths) != 23 {
+		t.Errorf("invalid number of config leaf paths, %d", len(paths))
+	}
+	paths = AllPaths(root1, StateOnly{})
+	if len(paths) != 1 || paths[0] != "/sample/single-key-list[list-key=AAA]/uint32-range" {
+		t.Errorf("invalid state paths, %v", paths)
+	}
 }
@@ -2,14 +2,16 @@ package yangtree
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/openconfig/goyang/pkg/yang"
 )
 
 func Validate(node DataNode) []error {
 	checkAll := true
+	cache := newLeafrefCache()
 	for n := node; n != nil; n = n.Parent() {
-		err := validateDataNode(node, node.Schema().Type, checkAll)
+		err := validateDataNode(node, node.Schema().Type, checkAll, cache)
 		if err != nil {
 			return err
 		}
@@ -18,7 +20,77 @@ func Validate(node DataNode) []error {
 	return nil
 }
 
-func validateDataNode(node DataNode, typ *yang.YangType, checkAll bool) []error {
+// leafrefCache is the resolver cache of the leafref target nodes used within a single validation.
+// The value strings of the target nodes are cached by the context node and the path
+// to avoid the repeated search of the same target nodes (e.g. many leafrefs into a large list).
+type leafrefCache struct {
+	values map[leafrefCacheKey]map[string]bool
+}
+
+// leafrefCacheKey is the key of the leafref target nodes.
+// The context is the ancestor of the leafref node reached by the leading parent steps
+// of the path or nil for the absolute path.
+type leafrefCacheKey struct {
+	context DataNode
+	path    string
+}
+
+func newLeafrefCache() *leafrefCache {
+	return &leafrefCache{values: map[leafrefCacheKey]map[string]bool{}}
+}
+
+// key() returns the cache key of the leafref path of the node.
+// It returns false if the target nodes depend on the node itself (e.g. current()).
+func (cache *leafrefCache) key(node DataNode, path string) (leafrefCacheKey, bool) {
+	if strings.Contains(path, "current()") {
+		return leafrefCacheKey{}, false
+	}
+	if strings.HasPrefix(path, "/") {
+		return leafrefCacheKey{path: path}, true
+	}
+	context := node
+	for strings.HasPrefix(path, "../") {
+		context = context.Parent()
+		if context == nil {
+			return leafrefCacheKey{}, false
+		}
+		path = strings.TrimPrefix(path, "../")
+	}
+	if context == node {
+		return leafrefCacheKey{}, false
+	}
+	return leafrefCacheKey{context: context, path: path}, true
+}
+
+// resolve() returns the value strings of the leafref target nodes of the node.
+// The target nodes are searched only once for the same cache key.
+func (cache *leafrefCache) resolve(node DataNode, path string) (map[string]bool, error) {
+	key, cacheable := leafrefCacheKey{}, false
+	if cache != nil {
+		key, cacheable = cache.key(node, path)
+		if cacheable {
+			if values, ok := cache.values[key]; ok {
+				return values, nil
+			}
+		}
+	}
+	ref, err := Find(node, path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]bool, len(ref))
+	for i := range ref {
+		values[ref[i].ValueString()] = true
+	}
+	if cacheable {
+		cache.values[key] = values
+	}
+	return values, nil
+}
+
+// validateDataNode() validates the data node. The leafref target nodes are resolved
+// using the cache if it is not nil.
+func validateDataNode(node DataNode, typ *yang.YangType, checkAll bool, cache *leafrefCache) []error {
 	var errors []error
 	// when, must statements must be test for the validation.
 	whenstr, ok := node.Schema().GetWhenXPath()
@@ -46,12 +118,16 @@ func validateDataNode(node DataNode, typ *yang.YangType, checkAll bool) []error
 			}
 		}
 	}
+	errors = append(errors, runValidators(node)...)
 	switch n := node.(type) {
 	case *DataBranch:
 		// check the validation of the children
 		if checkAll {
+			errors = append(errors, validateMandatory(n, n.schema)...)
+			errors = append(errors, validateKeys(n)...)
+			errors = append(errors, validateUnique(n)...)
 			for i := range n.children {
-				err := validateDataNode(n.children[i], n.children[i].Schema().Type, checkAll)
+				err := validateDataNode(n.children[i], n.children[i].Schema().Type, checkAll, cache)
 				errors = append(errors, err...)
 			}
 		}
@@ -76,15 +152,13 @@ func validateDataNode(node DataNode, typ *yang.YangType, checkAll bool) []error
 				return append(errors, fmt.Errorf("data instance not present to %s", node.Path()))
 			}
 		case yang.Yleafref:
-			ref, err := Find(node, typ.Path)
+			values, err := cache.resolve(node, typ.Path)
 			if err != nil {
 				return append(errors, err)
 			}
 			nodeValue := node.ValueString()
-			for i := range ref {
-				if ref[i].ValueString() == nodeValue {
-					return nil
-				}
+			if values[nodeValue] {
+				return errors
 			}
 			return append(errors, fmt.Errorf("invalid leafref %s", nodeValue))
 		default:
@@ -93,6 +167,254 @@ func validateDataNode(node DataNode, typ *yang.YangType, checkAll bool) []error
 	return errors
 }
 
+// AddValidator() registers a validator function for the data nodes of the schema path
+// found from the schema node (e.g. the root schema returned by Load()).
+// The validator functions are kept in the schema tree and invoked for the data nodes of the
+// schema while the data nodes are validated by Validate() and ValidateAll()
+// so that the custom rules (e.g. the relationship of the leaves) can be checked.
+// The validator functions must be registered before the data nodes are validated.
+//   AddValidator(schema, "/sample/container-val", func(node DataNode) error { ... })
+func AddValidator(schema *SchemaNode, schemaPath string, fn func(DataNode) error) error {
+	if schema == nil {
+		return Errorf(EAppTagInvalidArg, "no schema for the validator of %s", schemaPath)
+	}
+	if fn == nil {
+		return Errorf(EAppTagInvalidArg, "no validator function for %s", schemaPath)
+	}
+	target := schema.FindSchema(schemaPath)
+	if target == nil {
+		return Errorf(EAppTagInvalidArg, "schema %s not found", schemaPath)
+	}
+	target.validators = append(target.validators, fn)
+	return nil
+}
+
+// RemoveValidators() removes all validator functions registered for the schema path
+// found from the schema node.
+func RemoveValidators(schema *SchemaNode, schemaPath string) error {
+	if schema == nil {
+		return Errorf(EAppTagInvalidArg, "no schema for the validator of %s", schemaPath)
+	}
+	target := schema.FindSchema(schemaPath)
+	if target == nil {
+		return Errorf(EAppTagInvalidArg, "schema %s not found", schemaPath)
+	}
+	target.validators = nil
+	return nil
+}
+
+// runValidators() invokes the validator functions registered for the schema of the data node.
+func runValidators(node DataNode) []error {
+	var errors []error
+	for _, fn := range node.Schema().validators {
+		if err := fn(node); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	return errors
+}
+
+// ValidateAll() validates the node and all its descendants and returns all errors found
+// (type, range, length, pattern, mandatory, when, must and leafref) instead of stopping at the first one.
+func ValidateAll(node DataNode) []error {
+	return validateAll(node, newLeafrefCache())
+}
+
+func validateAll(node DataNode, cache *leafrefCache) []error {
+	if node == nil {
+		return nil
+	}
+	var errors []error
+	switch n := node.(type) {
+	case *DataBranch:
+		errors = append(errors, validateDataNode(n, n.schema.Type, false, cache)...)
+		errors = append(errors, validateMandatory(n, n.schema)...)
+		errors = append(errors, validateKeys(n)...)
+		errors = append(errors, validateUnique(n)...)
+		for i := range n.children {
+			errors = append(errors, validateAll(n.children[i], cache)...)
+		}
+	case *DataNodeGroup:
+		for i := range n.Nodes {
+			errors = append(errors, validateAll(n.Nodes[i], cache)...)
+		}
+	default:
+		schema := node.Schema()
+		values := node.Values()
+		for i := range values {
+			if _, err := ValueToValidTypeValue(schema, schema.Type, values[i]); err != nil {
+				errors = append(errors, Errorf(ETagInvalidValue, "invalid value in %s: %v", node.Path(), err))
+			}
+		}
+		errors = append(errors, validateDataNode(node, schema.Type, false, cache)...)
+	}
+	return errors
+}
+
+// validateMandatory() checks the mandatory nodes of the schema are present in the branch.
+// Non-presence containers are not required, but their mandatory descendants are checked
+// even if the containers are not present. The descendants of absent presence containers are not checked.
+// The mandatory nodes placed in a choice are only checked in the selected case and
+// the mandatory nodes having the when statements are only checked if the conditions are satisfied.
+func validateMandatory(branch *DataBranch, schema *SchemaNode) []error {
+	var errors []error
+	for _, cschema := range schema.Children {
+		if cschema.IsRPC() || cschema.IsState || cschema.IsCase() || !inSelectedCase(branch, cschema) {
+			continue
+		}
+		if cschema.IsChoice() {
+			if cschema.Mandatory == yang.TSTrue && (branch == nil || branch.selectedCase(cschema) == nil) &&
+				isWhenSatisfied(branch, cschema) {
+				errors = append(errors, Errorf(ETagDataMissing,
+					"mandatory choice %s not present in %s", cschema.Name, GeneratePath(schema, false, false)+"/"))
+			}
+			continue
+		}
+		var child DataNode
+		if branch != nil {
+			child = branch.Get(cschema.Name)
+		}
+		if child != nil || !isWhenSatisfied(branch, cschema) {
+			continue
+		}
+		switch {
+		case cschema.IsContainer() && !cschema.IsPresence():
+			errors = append(errors, validateMandatory(nil, cschema)...)
+		case cschema.IsDir():
+		case cschema.Mandatory == yang.TSTrue:
+			errors = append(errors, Errorf(ETagDataMissing,
+				"mandatory node %s not present", GeneratePath(cschema, false, false)))
+		}
+	}
+	return errors
+}
+
+// inSelectedCase() returns true if the schema node is not placed in any choice or
+// is placed in the selected cases of all choices containing the schema node.
+func inSelectedCase(branch *DataBranch, schema *SchemaNode) bool {
+	for s := schema; s.Choice != nil; s = s.Choice {
+		if branch == nil || branch.selectedCase(s.Choice) != s.Case {
+			return false
+		}
+	}
+	return true
+}
+
+// isWhenSatisfied() returns true if the when statements of the absent schema node are satisfied
+// in the branch. The when statement of the data node is evaluated on a temporal node that refers
+// to the branch as its parent but is not inserted to the branch. The when statements are regarded
+// as not satisfied if the branch is not present.
+func isWhenSatisfied(branch *DataBranch, schema *SchemaNode) bool {
+	if !schema.hasWhen() {
+		return true
+	}
+	if branch == nil {
+		return false
+	}
+	if schema.IsChoice() {
+		whens := schema.getCaseWhenXPath()
+		if when, ok := schema.GetWhenXPath(); ok {
+			whens = append(whens, when)
+		}
+		for _, when := range whens {
+			if ok, err := evaluatePathExpr(branch, when); err != nil || !ok {
+				return false
+			}
+		}
+		return true
+	}
+	c, err := New(schema)
+	if err != nil {
+		return false
+	}
+	id := schema.Name
+	setParent(c, branch, &id)
+	return isDefaultEnabled(branch, c)
+}
+
+// validateKeys() checks all key nodes of the list entry are present and not empty.
+func validateKeys(branch *DataBranch) []error {
+	if !branch.schema.IsListHasKey() {
+		return nil
+	}
+	var errors []error
+	for _, kname := range branch.schema.Keyname {
+		key := branch.Get(kname)
+		if key == nil {
+			errors = append(errors, Errorf(ETagMissingElement,
+				"key %s of the list entry %s not found", kname, branch.Path()))
+			continue
+		}
+		if typ := key.Schema().Type; typ != nil && typ.Kind == yang.Yempty {
+			continue
+		}
+		if key.ValueString() == "" {
+			errors = append(errors, Errorf(ETagInvalidValue,
+				"empty key %s of the list entry %s", kname, branch.Path()))
+		}
+	}
+	return errors
+}
+
+// validateUnique() checks the unique statements of the list nodes of the branch.
+// The list entries must not have the same combination of the values of the unique statement.
+// The entries that don't have all the nodes of the unique statement are not checked.
+func validateUnique(branch *DataBranch) []error {
+	var errors []error
+	var lists []*SchemaNode
+	entries := map[*SchemaNode][]DataNode{}
+	for _, child := range branch.children {
+		schema := child.Schema()
+		if !schema.IsList() {
+			continue
+		}
+		if _, ok := entries[schema]; !ok {
+			lists = append(lists, schema)
+		}
+		entries[schema] = append(entries[schema], child)
+	}
+	for _, schema := range lists {
+		for _, unique := range schema.GetUnique() {
+			found := map[string]string{}
+			for _, entry := range entries[schema] {
+				values, ok := uniqueValues(entry, unique)
+				if !ok {
+					continue
+				}
+				if id, exists := found[values]; exists {
+					errors = append(errors, Errorf(ETagOperationFailed,
+						"unique %q violated by %s and %s", strings.Join(unique, " "), id, entry.ID()))
+					continue
+				}
+				found[values] = entry.ID()
+			}
+		}
+	}
+	return errors
+}
+
+// uniqueValues() returns the combined values of the descendant nodes of the unique statement.
+// It returns false if any of the descendant nodes is not present.
+func uniqueValues(entry DataNode, unique []string) (string, bool) {
+	var b strings.Builder
+	for _, descendant := range unique {
+		elements := strings.Split(descendant, "/")
+		for i := range elements {
+			if j := strings.Index(elements[i], ":"); j >= 0 {
+				elements[i] = elements[i][j+1:]
+			}
+		}
+		nodes, err := Find(entry, strings.Join(elements, "/"))
+		if err != nil || len(nodes) == 0 {
+			return "", false
+		}
+		b.WriteString("[")
+		b.WriteString(escapeKeyValue(nodes[0].ValueString()))
+		b.WriteString("]")
+	}
+	return b.String(), true
+}
+
 // Refer to:
 // https://tools.ietf.org/html/rfc6020#section-9.4.
 // github.com/openconfig/ygot/ytypes/string_type.go
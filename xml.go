@@ -1,8 +1,11 @@
 package yangtree
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"sort"
 	"strconv"
 
 	"github.com/openconfig/goyang/pkg/yang"
@@ -10,6 +13,9 @@ import (
 
 // value2XMLString() marshals a value based on its schema, type and representing format.
 func value2XMLString(schema *SchemaNode, typ *yang.YangType, value interface{}) (string, error) {
+	if fn := outputTransform(schema); fn != nil {
+		return fn(ValueToValueString(value)), nil
+	}
 	switch typ.Kind {
 	// case yang.YinstanceIdentifier:
 	// [FIXME] The leftmost (top-level) data node name is always in the
@@ -83,9 +89,59 @@ func value2XMLString(schema *SchemaNode, typ *yang.YangType, value interface{})
 
 type xmlNode struct {
 	DataNode
-	ConfigOnly yang.TriState
-	printMeta  bool
-	metaNS     map[string]string
+	ConfigOnly  yang.TriState
+	printMeta   bool
+	metaNS      map[string]string
+	operation   map[DataNode]string // NETCONF edit-config operations
+	numericKeys bool                // encode the list entries in the numeric order of the keys
+}
+
+// metadataAttrs() returns the metadata of the xml node as XML attributes.
+// The namespaces of the metadata are declared only once in the scope of
+// the xml node and its descendants.
+func (xnode *xmlNode) metadataAttrs() []xml.Attr {
+	var attrs []xml.Attr
+	attrs, xnode.metaNS = metadataXMLAttrs(xnode.DataNode.Metadata(), xnode.metaNS)
+	return attrs
+}
+
+// metadataXMLAttrs() returns the metadata as XML attributes sorted by the metadata names
+// for the reproducible XML output. The namespaces of the metadata not declared in the parent
+// scope (declared: namespace to prefix) are declared in the attributes and then
+// the namespaces declared in the scope are returned.
+func metadataXMLAttrs(meta map[string]DataNode, declared map[string]string) ([]xml.Attr, map[string]string) {
+	if len(meta) == 0 {
+		return nil, declared
+	}
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]xml.Attr, 0, len(meta))
+	metaNS := declared
+	copied := false
+	for _, k := range keys {
+		m := meta[k]
+		ns, prefix := m.Schema().GetNamespaceAndPrefix()
+		if _prefix, ok := metaNS[ns]; ok {
+			prefix = _prefix
+		} else {
+			if !copied {
+				// copy the namespaces declared in the parent scope
+				// so as not to affect the siblings of the xml node.
+				metaNS = make(map[string]string, len(declared)+1)
+				for n, p := range declared {
+					metaNS[n] = p
+				}
+				copied = true
+			}
+			metaNS[ns] = prefix
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: ns})
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: prefix + ":" + m.Name()}, Value: m.ValueString()})
+	}
+	return attrs, metaNS
 }
 
 func (xnode *xmlNode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
@@ -119,22 +175,10 @@ func (xnode *xmlNode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 
 	// metadata
 	if xnode.printMeta {
-		if xnode.metaNS == nil {
-			xnode.metaNS = make(map[string]string)
-		}
-		meta := xnode.DataNode.Metadata()
-		for _, v := range meta {
-			mschema := v.Schema()
-			ns, prefix := mschema.GetNamespaceAndPrefix()
-			if _prefix, ok := xnode.metaNS[ns]; !ok {
-				nsattr := xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: ns}
-				start.Attr = append(start.Attr, nsattr)
-				xnode.metaNS[ns] = prefix
-				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: prefix + ":" + v.Name()}, Value: v.ValueString()})
-			} else {
-				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: _prefix + ":" + v.Name()}, Value: v.ValueString()})
-			}
-		}
+		start.Attr = append(start.Attr, xnode.metadataAttrs()...)
+	}
+	if op, ok := xnode.operation[xnode.DataNode]; ok {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "nc:operation"}, Value: op})
 	}
 
 	// if err := e.EncodeToken(xml.Comment(leaflist.ID())); err != nil {
@@ -145,7 +189,15 @@ func (xnode *xmlNode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		if err := e.EncodeToken(xml.Token(start)); err != nil {
 			return err
 		}
-		for _, child := range node.children {
+		children := node.children
+		if xnode.numericKeys {
+			children = sortByNumericKeys(children)
+		}
+		for _, child := range children {
+			if (xnode.ConfigOnly == yang.TSTrue && child.IsStateNode()) ||
+				(xnode.ConfigOnly == yang.TSFalse && !child.IsStateNode() && !child.HasStateNode()) {
+				continue
+			}
 			cxnode := *xnode
 			cxnode.DataNode = child
 			if err := e.EncodeElement(&cxnode, xml.StartElement{Name: xml.Name{Local: cxnode.Name()}}); err != nil {
@@ -154,8 +206,13 @@ func (xnode *xmlNode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		}
 		return e.EncodeToken(xml.Token(xml.EndElement{Name: xml.Name{Local: schema.Name}}))
 	case *DataLeafList:
+		transform := outputTransform(schema)
 		for _, v := range node.Values() {
-			if err := e.EncodeElement(ValueToValueString(v), start); err != nil {
+			vstr := ValueToValueString(v)
+			if transform != nil {
+				vstr = transform(vstr)
+			}
+			if err := e.EncodeElement(vstr, start); err != nil {
 				return err
 			}
 		}
@@ -172,8 +229,8 @@ func (xnode *xmlNode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return nil
 }
 
-// MarshalXML returns the XML bytes of a data node.
-func MarshalXML(node DataNode, option ...Option) ([]byte, error) {
+// newXMLNode() returns an xml node for marshalling the data node with the options.
+func newXMLNode(node DataNode, option ...Option) (*xmlNode, error) {
 	xnode := &xmlNode{DataNode: node}
 	for i := range option {
 		switch option[i].(type) {
@@ -187,31 +244,191 @@ func MarshalXML(node DataNode, option ...Option) ([]byte, error) {
 			return nil, fmt.Errorf("%v is not allowed for marshalling", option[i])
 		case Metadata:
 			xnode.printMeta = true
+		case NumericKeyAware:
+			xnode.numericKeys = true
 		}
 	}
+	return xnode, nil
+}
+
+// MarshalXML returns the XML bytes of a data node.
+func MarshalXML(node DataNode, option ...Option) ([]byte, error) {
+	xnode, err := newXMLNode(node, option...)
+	if err != nil {
+		return nil, err
+	}
 	return xml.Marshal(xnode)
 }
 
 // MarshalXMLIndent returns the XML bytes of a data node.
 func MarshalXMLIndent(node DataNode, prefix, indent string, option ...Option) ([]byte, error) {
-	xnode := &xmlNode{DataNode: node}
-	for i := range option {
-		switch option[i].(type) {
-		case HasState:
-			return nil, fmt.Errorf("%v is not allowed for marshalling", option[i])
-		case ConfigOnly:
-			xnode.ConfigOnly = yang.TSTrue
-		case StateOnly:
-			xnode.ConfigOnly = yang.TSFalse
-		case RFC7951Format:
-			return nil, fmt.Errorf("%v is not allowed for marshalling", option[i])
-		case Metadata:
-			xnode.printMeta = true
-		}
+	xnode, err := newXMLNode(node, option...)
+	if err != nil {
+		return nil, err
 	}
 	return xml.MarshalIndent(xnode, prefix, indent)
 }
 
+// EncodeXML() writes the XML encoding of a data node to the writer.
+// The XML document is streamed to the writer while the data node is
+// being encoded, so that a large data tree can be written without
+// building the whole XML bytes in memory. Each XML element begins on
+// a new line with indent if the indent is not empty.
+func EncodeXML(w io.Writer, node DataNode, indent string, option ...Option) error {
+	xnode, err := newXMLNode(node, option...)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", indent)
+	if err := enc.Encode(xnode); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// netconfNamespace is the XML namespace of the NETCONF base protocol.
+const netconfNamespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+// ToNETCONFEditConfig() returns a NETCONF <config> XML document of the edit-config
+// operation built from the diff entries. The created, replaced and deleted data nodes
+// are annotated with the nc:operation attributes (merge, replace and delete) and
+// only the ancestors and the key nodes of the deleted list entries are included
+// to locate them. The schema must be the root schema of the data nodes.
+func ToNETCONFEditConfig(entries []DiffEntry, schema *SchemaNode) ([]byte, error) {
+	if schema == nil {
+		return nil, Errorf(EAppTagDataNodeMissing, "no schema for edit-config")
+	}
+	root, err := NewWithValueString(schema)
+	if err != nil {
+		return nil, err
+	}
+	config, ok := root.(*DataBranch)
+	if !ok {
+		return nil, Errorf(ETagOperationNotSupported, "%s is not a branch schema", schema.Name)
+	}
+	changed := make(map[DataNode]bool, len(entries))
+	for i := range entries {
+		changed[entries[i].Node] = true
+	}
+	operation := map[DataNode]string{}
+	for i := range entries {
+		node := entries[i].Node
+		if !IsValid(node) {
+			continue
+		}
+		// the descendants of the changed node are included in the changed node.
+		included := false
+		var ancestors []DataNode
+		for p := node.Parent(); p != nil; p = p.Parent() {
+			if changed[p] {
+				included = true
+				break
+			}
+			ancestors = append(ancestors, p)
+		}
+		if included {
+			continue
+		}
+		if len(ancestors) == 0 || ancestors[len(ancestors)-1].Schema() != schema {
+			if node.Schema() == schema {
+				return nil, Errorf(ETagOperationNotSupported, "the root data node is not allowed for edit-config")
+			}
+			return nil, Errorf(ETagUnknownElement, "%s is not a data node of %s", node, schema.Name)
+		}
+		// build the ancestors of the changed node.
+		parent := config
+		for j := len(ancestors) - 2; j >= 0; j-- {
+			id := ancestors[j].ID()
+			if child := parent.Get(id); child != nil {
+				parent = child.(*DataBranch)
+				continue
+			}
+			child, err := newEditConfigNode(parent, ancestors[j])
+			if err != nil {
+				return nil, err
+			}
+			parent = child.(*DataBranch)
+		}
+		var n DataNode
+		switch entries[i].Operation {
+		case DiffDelete:
+			if n, err = newEditConfigNode(parent, node); err != nil {
+				return nil, err
+			}
+			operation[n] = "delete"
+		case DiffCreate, DiffReplace:
+			n = Clone(node)
+			if _, err := parent.insert(n, nil); err != nil {
+				return nil, err
+			}
+			operation[n] = "merge"
+			if entries[i].Operation == DiffReplace {
+				operation[n] = "replace"
+			}
+		default:
+			return nil, Errorf(ETagOperationNotSupported, "unknown diff operation %q", entries[i].Operation)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	start := xml.StartElement{
+		Name: xml.Name{Local: "config"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: netconfNamespace},
+			{Name: xml.Name{Local: "xmlns:nc"}, Value: netconfNamespace},
+		},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return nil, err
+	}
+	for _, child := range config.children {
+		xnode := &xmlNode{DataNode: child, ConfigOnly: yang.TSTrue, operation: operation}
+		if err := enc.EncodeElement(xnode, xml.StartElement{Name: xml.Name{Local: child.Name()}}); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.EncodeToken(start.End()); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newEditConfigNode() inserts a new data node having only the key nodes of the node
+// to the parent for the edit-config document.
+func newEditConfigNode(parent *DataBranch, node DataNode) (DataNode, error) {
+	if _, ok := node.(*DataBranch); !ok {
+		n := Clone(node)
+		if _, err := parent.insert(n, nil); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+	n, err := NewWithValueString(node.Schema())
+	if err != nil {
+		return nil, err
+	}
+	for _, kname := range node.Schema().Keyname {
+		if key := node.Get(kname); key != nil {
+			if _, err := n.Insert(Clone(key), nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := parent.insert(n, nil); err != nil {
+		return nil, err
+	}
+	// remove the default nodes created with the new node.
+	if err := n.(*DataBranch).Clear(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
 // UnmarshalXML updates the data node using an XML document.
 func UnmarshalXML(node DataNode, data []byte, option ...Option) error {
 	for i := range option {
@@ -224,3 +441,209 @@ func UnmarshalXML(node DataNode, data []byte, option ...Option) error {
 	}
 	return xml.Unmarshal(data, node)
 }
+
+// BatchOption is the configuration of the batch apply such as ApplyEditConfig().
+type BatchOption struct {
+	// DefaultOperation is the operation of the data nodes without an explicit operation
+	// like the NETCONF edit-config default-operation parameter. It must be one of
+	// EditMerge (default), EditReplace and EditNone. With EditNone, only the data nodes
+	// tagged with an explicit operation are touched.
+	DefaultOperation EditOp
+}
+
+func (o BatchOption) IsOption() {}
+
+// editConfigElement is an XML element of the NETCONF edit-config <config> document.
+type editConfigElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr           `xml:",any,attr"`
+	Text     string               `xml:",chardata"`
+	Children []*editConfigElement `xml:",any"`
+}
+
+// operation() returns the operation of the element annotated by the operation attribute.
+func (elem *editConfigElement) operation() (EditOp, bool, error) {
+	for i := range elem.Attrs {
+		if elem.Attrs[i].Name.Local != "operation" {
+			continue
+		}
+		switch elem.Attrs[i].Value {
+		case "merge":
+			return EditMerge, true, nil
+		case "create":
+			return EditCreate, true, nil
+		case "replace":
+			return EditReplace, true, nil
+		case "delete":
+			return EditDelete, true, nil
+		case "remove":
+			return EditRemove, true, nil
+		default:
+			return EditMerge, false, Errorf(ETagBadAttribute,
+				"invalid operation %q of %s", elem.Attrs[i].Value, elem.XMLName.Local)
+		}
+	}
+	return EditMerge, false, nil
+}
+
+// schema() returns the child schema node of the pschema matched by the local name
+// and the XML namespace of the element.
+func (elem *editConfigElement) schema(pschema *SchemaNode) (*SchemaNode, error) {
+	schema := pschema.GetSchema(elem.XMLName.Local)
+	if schema == nil {
+		return nil, Errorf(ETagUnknownElement, "schema %s not found from %s", elem.XMLName.Local, pschema.Name)
+	}
+	if schema.GetNamespace() == elem.XMLName.Space {
+		return schema, nil
+	}
+	for _, child := range pschema.Children {
+		if child.Name == elem.XMLName.Local && child.GetNamespace() == elem.XMLName.Space {
+			return child, nil
+		}
+	}
+	return nil, Errorf(ETagUnknownNamespace, "unknown namespace %q of %s", elem.XMLName.Space, elem.XMLName.Local)
+}
+
+// ApplyEditConfig() applies a NETCONF edit-config <config> XML document to the root data node.
+// The data nodes annotated with the operation attributes (merge, create, replace, delete
+// and remove) are updated using the operation and the data nodes without the attribute
+// inherit the operation of the parent element. The top-level elements inherit
+// the default operation configured by BatchOption (merge if not specified).
+func ApplyEditConfig(root DataNode, config []byte, option ...Option) error {
+	if !IsValid(root) {
+		return Errorf(EAppTagInvalidArg, "invalid root data node")
+	}
+	defaultOp := EditMerge
+	for i := range option {
+		switch o := option[i].(type) {
+		case BatchOption:
+			defaultOp = o.DefaultOperation
+		default:
+			return fmt.Errorf("%s option not supported", option[i])
+		}
+	}
+	switch defaultOp {
+	case EditMerge, EditReplace, EditNone:
+	default:
+		return Errorf(ETagInvalidValue, "invalid default-operation %s", defaultOp)
+	}
+	var doc editConfigElement
+	if err := xml.Unmarshal(config, &doc); err != nil {
+		return Errorf(ETagMarlformedMessage, "%v", err)
+	}
+	elems := []*editConfigElement{&doc}
+	if doc.XMLName.Local == "config" && doc.XMLName.Space == netconfNamespace {
+		elems = doc.Children
+	}
+	for i := range elems {
+		if err := applyEditConfigElement(root, "", root.Schema(), elems[i], defaultOp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEditConfigElement() applies the element of the edit-config document to the data node
+// in the path relative to the root data node. The op is the operation inherited from the parent.
+func applyEditConfigElement(root DataNode, prefix string, pschema *SchemaNode, elem *editConfigElement, op EditOp) error {
+	schema, err := elem.schema(pschema)
+	if err != nil {
+		return err
+	}
+	if explicit, ok, err := elem.operation(); err != nil {
+		return err
+	} else if ok {
+		op = explicit
+	}
+	path := schema.Name
+	if pschema.GetSchema(schema.Name) != schema {
+		// the schema node having the same name in another module
+		path = schema.Module.Name + ":" + schema.Name
+	}
+	var keys map[string]bool
+	switch {
+	case schema.IsList():
+		keys = make(map[string]bool, len(schema.Keyname))
+		for _, kname := range schema.Keyname {
+			var key *editConfigElement
+			for _, child := range elem.Children {
+				if child.XMLName.Local == kname && child.XMLName.Space == elem.XMLName.Space {
+					key = child
+					break
+				}
+			}
+			if key == nil {
+				return Errorf(ETagMissingElement, "key %s of %s not found", kname, schema.Name)
+			}
+			keys[kname] = true
+			path += "[" + kname + "=" + escapeKeyValue(key.Text) + "]"
+		}
+	case schema.IsLeafList() && !schema.IsSingleLeafList():
+		path += "[.=" + escapeKeyValue(elem.Text) + "]"
+	}
+	if prefix != "" {
+		path = prefix + "/" + path
+	}
+
+	if !schema.IsDir() {
+		var value []string
+		if schema.Type == nil || schema.Type.Kind != yang.Yempty {
+			value = []string{elem.Text}
+		}
+		switch op {
+		case EditNone:
+			value = nil
+		case EditDelete, EditRemove:
+			if !schema.IsSingleLeafList() {
+				value = nil
+			}
+		}
+		return SetValueString(root, path, &EditOption{EditOp: op}, value...)
+	}
+
+	switch op {
+	case EditNone:
+		// the non-presence container is not required to exist.
+		if !schema.IsContainer() || schema.IsPresence() {
+			if err := SetValueString(root, path, &EditOption{EditOp: op}); err != nil {
+				return err
+			}
+		}
+	case EditDelete, EditRemove:
+		return SetValueString(root, path, &EditOption{EditOp: op})
+	case EditReplace:
+		found, err := Find(root, path)
+		if err != nil {
+			return err
+		}
+		for i := range found {
+			if err := found[i].(*DataBranch).Clear(); err != nil {
+				return err
+			}
+		}
+		if len(found) == 0 {
+			if err := SetValueString(root, path, &EditOption{EditOp: EditMerge}); err != nil {
+				return err
+			}
+		}
+	case EditCreate:
+		if err := SetValueString(root, path, &EditOption{EditOp: op}); err != nil {
+			return err
+		}
+		// the descendants of the created node are merged into it.
+		op = EditMerge
+	default: // merge
+		if err := SetValueString(root, path, &EditOption{EditOp: op}); err != nil {
+			return err
+		}
+	}
+	for _, child := range elem.Children {
+		if keys[child.XMLName.Local] {
+			continue
+		}
+		if err := applyEditConfigElement(root, path, schema, child, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
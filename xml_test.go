@@ -1,9 +1,12 @@
 package yangtree
 
 import (
+	"bytes"
 	"encoding/xml"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -112,3 +115,335 @@ func TestXML2(t *testing.T) {
 		t.Errorf("different result: root2 %s\n", string(j2))
 	}
 }
+
+type xmlWriteCounter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *xmlWriteCounter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestEncodeXML(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample/sample.yang"}, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatalf("error in new yangtree: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		path := fmt.Sprintf("/sample/single-key-list[list-key=key%d]", i)
+		if err := SetValueString(root, path+"/country-code", nil, "KR"); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetValueString(root, path+"/uint32-range", nil, "100"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sample := root.Get("sample")
+	for _, opt := range [][]Option{nil, {ConfigOnly{}}, {StateOnly{}}} {
+		w := &xmlWriteCounter{}
+		if err := EncodeXML(w, sample, "  ", opt...); err != nil {
+			t.Fatalf("EncodeXML() error: %v", err)
+		}
+		if w.writes < 2 {
+			t.Errorf("EncodeXML() must stream the xml document: %d writes", w.writes)
+		}
+		b, err := MarshalXMLIndent(sample, "", "  ", opt...)
+		if err != nil {
+			t.Fatalf("MarshalXMLIndent() error: %v", err)
+		}
+		if w.String() != string(b) {
+			t.Errorf("different result between EncodeXML() and MarshalXMLIndent() with %v", opt)
+		}
+		switch {
+		case len(opt) == 0:
+			if !strings.Contains(w.String(), "<uint32-range>100</uint32-range>") ||
+				!strings.Contains(w.String(), "<country-code>KR</country-code>") {
+				t.Errorf("missing data nodes in EncodeXML()")
+			}
+		case opt[0] == ConfigOnly{}:
+			if strings.Contains(w.String(), "<uint32-range>") {
+				t.Errorf("state nodes must be filtered by ConfigOnly")
+			}
+		case opt[0] == StateOnly{}:
+			if strings.Contains(w.String(), "<country-code>") ||
+				!strings.Contains(w.String(), "<uint32-range>100</uint32-range>") {
+				t.Errorf("config nodes must be filtered by StateOnly")
+			}
+		}
+	}
+	if err := EncodeXML(&bytes.Buffer{}, sample, "", RFC7951Format{}); err == nil {
+		t.Errorf("RFC7951Format must not be allowed for xml encoding")
+	}
+}
+
+func TestEncodeXMLMetadata(t *testing.T) {
+	yangfiles := []string{
+		"testdata/sample/sample.yang",
+		"testdata/modules/example-last-modified.yang",
+	}
+	dir := []string{"../../openconfig/public/", "../../YangModels/yang"}
+	schema, err := Load(yangfiles, dir, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatalf("error in new yangtree: %v", err)
+	}
+	for _, path := range []string{"/sample/str-val", "/sample/container-val/a"} {
+		if err := SetValueString(root, path, nil, "abc"); err != nil {
+			t.Fatal(err)
+		}
+		node, err := Find(root, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		if err := node[0].SetMetadataString("last-modified", "2015-06-18T17:01:14+02:00"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := EncodeXML(&buf, root.Get("sample"), " ", Metadata{}); err != nil {
+		t.Fatalf("EncodeXML() error: %v", err)
+	}
+	// The metadata namespace must be declared for each sibling scope.
+	if n := strings.Count(buf.String(), `xmlns:elm="http://example.org/example-last-modified"`); n != 2 {
+		t.Errorf("unexpected metadata namespace declarations (%d):\n%s", n, buf.String())
+	}
+	if n := strings.Count(buf.String(), `elm:last-modified="2015-06-18T17:01:14+02:00"`); n != 2 {
+		t.Errorf("unexpected metadata attributes (%d):\n%s", n, buf.String())
+	}
+	if err := xml.Unmarshal(buf.Bytes(), new(interface{})); err != nil {
+		t.Errorf("invalid xml document: %v", err)
+	}
+}
+
+func TestXMLMetadataOrder(t *testing.T) {
+	yangfiles := []string{
+		"testdata/sample/sample.yang",
+		"testdata/modules/example-annotations.yang",
+	}
+	schema, err := Load(yangfiles, nil, nil)
+	if err != nil {
+		t.Fatalf("error in loading: %v", err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatalf("error in new yangtree: %v", err)
+	}
+	if err := SetValueString(root, "/sample/container-val/a", nil, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/sample/container-val", "/sample/container-val/a"} {
+		node, err := Find(root, path)
+		if err != nil || len(node) != 1 {
+			t.Fatalf("%s not found: %v", path, err)
+		}
+		for _, name := range []string{"tag", "owner", "origin", "comment"} {
+			if err := node[0].SetMetadataString(name, name+"-value"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expected := `ean:comment="comment-value" ean:origin="origin-value" ean:owner="owner-value" ean:tag="tag-value"`
+	marshallers := map[string]func() ([]byte, error){
+		"xml.Marshal": func() ([]byte, error) {
+			return xml.Marshal(root.Get("sample"))
+		},
+		"MarshalXML": func() ([]byte, error) {
+			return MarshalXML(root.Get("sample"), Metadata{})
+		},
+	}
+	for name, marshal := range marshallers {
+		first, err := marshal()
+		if err != nil {
+			t.Fatalf("%s error: %v", name, err)
+		}
+		if n := strings.Count(string(first), expected); n != 2 {
+			t.Errorf("%s returns unsorted metadata attributes (%d):\n%s", name, n, first)
+		}
+		for i := 0; i < 10; i++ {
+			b, err := marshal()
+			if err != nil {
+				t.Fatalf("%s error: %v", name, err)
+			}
+			if !bytes.Equal(first, b) {
+				t.Fatalf("%s returns unstable output:\n%s\n%s", name, first, b)
+			}
+		}
+	}
+}
+
+func TestToNETCONFEditConfig(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node1, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "abc",
+		"single-key-list": [{"list-key": "AAA", "country-code": "KR"}],
+		"container-val": {"a": "A"}
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node2, err := NewWithValueString(schema, `{"sample": {
+		"str-val": "xyz",
+		"single-key-list": [{"list-key": "BBB", "country-code": "US"}],
+		"container-val": {"a": "A"}
+	}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ToNETCONFEditConfig(DiffEntries(node1, node2), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := string(b)
+	if !strings.HasPrefix(config, `<config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">`) {
+		t.Errorf("unexpected edit-config root: %s", config)
+	}
+	for _, expected := range []string{
+		`<single-key-list nc:operation="delete"><list-key>AAA</list-key></single-key-list>`,
+		`<single-key-list nc:operation="merge">`,
+		`<country-code>US</country-code>`,
+		`<str-val nc:operation="replace">xyz</str-val>`,
+	} {
+		if !strings.Contains(config, expected) {
+			t.Errorf("%s not found in edit-config: %s", expected, config)
+		}
+	}
+	if n := strings.Count(config, "nc:operation="); n != 3 {
+		t.Errorf("unexpected number of operations (%d): %s", n, config)
+	}
+	if strings.Contains(config, "container-val") || strings.Contains(config, "KR") {
+		t.Errorf("unchanged data included in edit-config: %s", config)
+	}
+}
+
+func TestApplyEditConfig(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := `<config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">
+	<sample xmlns="urn:network">
+		<str-val>xyz</str-val>
+		<single-key-list>
+			<list-key>AAA</list-key>
+			<country-code nc:operation="merge">US</country-code>
+		</single-key-list>
+		<single-key-list nc:operation="create">
+			<list-key>BBB</list-key>
+			<country-code>JP</country-code>
+		</single-key-list>
+	</sample>
+</config>`
+	tests := []struct {
+		defaultOp EditOp
+		expected  map[string]string
+	}{
+		{
+			defaultOp: EditMerge,
+			expected: map[string]string{
+				"/sample/str-val": "xyz",
+				"/sample/single-key-list[list-key=AAA]/country-code": "US",
+				"/sample/single-key-list[list-key=AAA]/int8-range":   "1",
+				"/sample/single-key-list[list-key=BBB]/country-code": "JP",
+				"/sample/str-val-ext":                                "ext",
+			},
+		},
+		{
+			defaultOp: EditReplace,
+			expected: map[string]string{
+				"/sample/str-val": "xyz",
+				"/sample/single-key-list[list-key=AAA]/country-code": "US",
+				"/sample/single-key-list[list-key=AAA]/int8-range":   "<none>",
+				"/sample/single-key-list[list-key=BBB]/country-code": "JP",
+				"/sample/str-val-ext":                                "<none>",
+			},
+		},
+		{
+			defaultOp: EditNone,
+			expected: map[string]string{
+				"/sample/str-val": "abc",
+				"/sample/single-key-list[list-key=AAA]/country-code": "US",
+				"/sample/single-key-list[list-key=AAA]/int8-range":   "1",
+				"/sample/single-key-list[list-key=BBB]/country-code": "JP",
+				"/sample/str-val-ext":                                "ext",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.defaultOp.String(), func(t *testing.T) {
+			root, err := NewWithValueString(schema, `{"sample": {
+				"str-val": "abc",
+				"str-val-ext": "ext",
+				"single-key-list": [{"list-key": "AAA", "country-code": "KR", "int8-range": 1}]
+			}}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ApplyEditConfig(root, []byte(config), BatchOption{DefaultOperation: tt.defaultOp}); err != nil {
+				t.Fatalf("ApplyEditConfig() error = %v", err)
+			}
+			for path, value := range tt.expected {
+				found, err := Find(root, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := "<none>"
+				if len(found) > 0 {
+					got = found[0].ValueString()
+				}
+				if got != value {
+					t.Errorf("%s = %q, want %q", path, got, value)
+				}
+			}
+		})
+	}
+
+	root, err := NewWithValueString(schema, `{"sample": {"str-val": "abc"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := `<config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">
+	<sample xmlns="urn:network">
+		<single-key-list>
+			<list-key>CCC</list-key>
+			<country-code nc:operation="merge">US</country-code>
+		</single-key-list>
+	</sample>
+</config>`
+	if err := ApplyEditConfig(root, []byte(missing), BatchOption{DefaultOperation: EditNone}); err == nil {
+		t.Errorf("ApplyEditConfig() must return data-missing for the untagged list entry not found")
+	}
+	if err := ApplyEditConfig(root, []byte(config), BatchOption{DefaultOperation: EditDelete}); err == nil {
+		t.Errorf("ApplyEditConfig() must reject the delete default-operation")
+	}
+	unknownNS := `<config xmlns="urn:ietf:params:xml:ns:netconf:base:1.0">
+	<sample xmlns="urn:unknown"><str-val>xyz</str-val></sample>
+</config>`
+	if err := ApplyEditConfig(root, []byte(unknownNS)); err == nil {
+		t.Errorf("ApplyEditConfig() must reject the element of the unknown namespace")
+	}
+
+	if err := SetValueString(root, "/sample/str-val", &EditOption{EditOp: EditNone}, "xyz"); err != nil {
+		t.Errorf("SetValueString() with EditNone error = %v", err)
+	}
+	if found, _ := Find(root, "/sample/str-val"); len(found) != 1 || found[0].ValueString() != "abc" {
+		t.Errorf("SetValueString() with EditNone must not change the existent node: %v", found)
+	}
+	if err := SetValueString(root, "/sample/single-key-list[list-key=DDD]/country-code", &EditOption{EditOp: EditNone}, "KR"); err == nil {
+		t.Errorf("SetValueString() with EditNone must return data-missing for the node not found")
+	}
+	if found, _ := Find(root, "/sample/single-key-list[list-key=DDD]"); len(found) != 0 {
+		t.Errorf("SetValueString() with EditNone must not create the node: %v", found)
+	}
+}
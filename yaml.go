@@ -54,7 +54,7 @@ func unmarshalYAMLListNode(parent DataNode, cschema *SchemaNode, kname []string,
 		idBuilder.WriteString("[")
 		idBuilder.WriteString(kname[i])
 		idBuilder.WriteString("=")
-		idBuilder.WriteString(ValueToValueString(kval[i]))
+		idBuilder.WriteString(escapeKeyValue(ValueToValueString(kval[i])))
 		idBuilder.WriteString("]")
 	}
 	id := idBuilder.String()
@@ -113,11 +113,36 @@ func unmarshalYAMLListableNode(parent DataNode, cschema *SchemaNode, kname []str
 		}
 		return nil
 	}
+	// the new list entries are inserted at once to avoid sorting for each entry.
+	created := make([]DataNode, 0, len(sequnce))
+	createdByID := make(map[string]DataNode, len(sequnce))
+	insertCreated := func() error {
+		if branch, ok := parent.(*DataBranch); ok {
+			if err := branch.BulkInsert(created); err != nil {
+				return err
+			}
+		} else {
+			for i := range created {
+				if _, err := parent.Insert(created[i], nil); err != nil {
+					return err
+				}
+			}
+		}
+		return setCreatedDefaults(created)
+	}
+	// failed() inserts the list entries created before the error and returns the error
+	// combined with the insertion error.
+	failed := func(err error) error {
+		if ierr := insertCreated(); ierr != nil {
+			return MultipleError{err, ierr}
+		}
+		return err
+	}
 	for i := range sequnce {
 		switch sequnce[i].(type) {
 		case map[interface{}]interface{}, map[string]interface{}:
 		default:
-			return fmt.Errorf("unexpected value %T for %s", sequnce[i], cschema.Name)
+			return failed(fmt.Errorf("unexpected value %T for %s", sequnce[i], cschema.Name))
 		}
 		// check existent DataNode
 		var err error
@@ -131,14 +156,14 @@ func unmarshalYAMLListableNode(parent DataNode, cschema *SchemaNode, kname []str
 				if kvalue = getValueFromYAMLHash(sequnce[i], &qname); kvalue == nil {
 					qname, _ = kcschema.GetQName(false)
 					if kvalue = getValueFromYAMLHash(sequnce[i], &qname); kvalue == nil {
-						return fmt.Errorf("not found key data node %s", kname[j])
+						return failed(fmt.Errorf("not found key data node %s", kname[j]))
 					}
 				}
 			}
 			idBuilder.WriteString("[")
 			idBuilder.WriteString(kname[j])
 			idBuilder.WriteString("=")
-			idBuilder.WriteString(fmt.Sprint(kvalue))
+			idBuilder.WriteString(escapeKeyValue(fmt.Sprint(kvalue)))
 			idBuilder.WriteString("]")
 		}
 		id := idBuilder.String()
@@ -147,24 +172,26 @@ func unmarshalYAMLListableNode(parent DataNode, cschema *SchemaNode, kname []str
 			child = nil
 		} else if found = parent.Get(id); found != nil {
 			child = found
+		} else if found = createdByID[id]; found != nil {
+			child = found
 		}
 		if child == nil {
 			child, err = NewWithID(cschema, id)
 			if err != nil {
-				return err
-			}
-			if _, err = parent.Insert(child, nil); err != nil {
-				return err
+				return failed(err)
 			}
 		}
 		if err := unmarshalYAML(child, cschema, sequnce[i]); err != nil {
-			if found == nil {
-				parent.Delete(child)
+			return failed(err)
+		}
+		if found == nil {
+			created = append(created, child)
+			if !cschema.IsDuplicatableList() {
+				createdByID[id] = child
 			}
-			return err
 		}
 	}
-	return nil
+	return insertCreated()
 }
 
 func unmarshalYAMLUpdateMetadata(node DataNode, schema *SchemaNode, meta interface{}) error {
@@ -199,6 +226,9 @@ func unmarshalYAMLUpdateMetadata(node DataNode, schema *SchemaNode, meta interfa
 
 func unmarshalYAMLkeyval(parent DataNode, cschema *SchemaNode, haskey bool, keystr *string, v interface{}, meta interface{}) error {
 	if haskey {
+		if !cschema.IsList() || len(cschema.Keyname) == 0 {
+			return fmt.Errorf("key values are not allowed for %s", cschema.Name)
+		}
 		keyname := cschema.Keyname
 		keyval, err := extractKeyValues(keyname, keystr)
 		if err != nil {
@@ -391,6 +421,16 @@ func UnmarshalYAML(node DataNode, in []byte, option ...Option) error {
 	return unmarshalYAML(node, node.Schema(), ydata)
 }
 
+// UnmarshalYAMLGzip() decompresses the gzip-compressed YAML data and then updates
+// the data node like UnmarshalYAML().
+func UnmarshalYAMLGzip(node DataNode, gz []byte, option ...Option) error {
+	in, err := Unzip(gz)
+	if err != nil {
+		return Errorf(EAppTagYAMLParsing, "gzip decompression failed: %v", err)
+	}
+	return UnmarshalYAML(node, in, option...)
+}
+
 type yamlNode struct {
 	DataNode            // Target data node to encode the data node
 	RFC7951S            // Modified RFC7951 format for YAML
@@ -399,14 +439,21 @@ type yamlNode struct {
 	ConfigOnly     yang.TriState
 	IndentStr      string
 	PrefixStr      string
+	nsMode         NamespaceMode
+	annotate       bool // Append the type and config/state of leaf nodes as comments
+	withDefaults   bool // Print the absent leaf nodes having the default values
+	numericKeys    bool // Print the list entries in the numeric order of the keys
 }
 
+// getQname() returns the name of the node, qualified by its module name if required.
+// It only updates the RFC7951S of the ynode that is allocated or copied for each marshalling,
+// so the data tree is not modified and can be marshalled concurrently.
 func (ynode *yamlNode) getQname() string {
 	switch ynode.RFC7951S {
 	case RFC7951InProgress, RFC7951Enabled:
-		if qname, boundary := ynode.QName(true); boundary ||
-			ynode.RFC7951S == RFC7951Enabled {
+		if ynode.nsMode.qualify(ynode.Schema(), ynode.RFC7951S == RFC7951Enabled) {
 			ynode.RFC7951S = RFC7951InProgress
+			qname, _ := ynode.QName(true)
 			return qname
 		}
 		return ynode.Schema().Name
@@ -510,11 +557,18 @@ func (ynode *yamlNode) marshalYAMChildListableNodes(
 			}
 		}
 	}
-	if cynode.RFC7951S != RFC7951Disabled || schema.IsDuplicatableList() || schema.IsLeafList() {
+	// the ordered-by user list entries are represented to a sequence in the internal format
+	// to keep their order.
+	orderedByUser := cynode.InternalFormat && schema.IsList() && schema.IsOrderedByUser()
+	if cynode.RFC7951S != RFC7951Disabled || schema.IsDuplicatableList() || schema.IsLeafList() || orderedByUser {
 		if !skipRoot {
 			if indent >= 0 {
 				cynode.WriteIndent(buffer, indent, unindent)
-				buffer.WriteString(cynode.getQname())
+				if orderedByUser {
+					buffer.WriteString(schema.Name)
+				} else {
+					buffer.WriteString(cynode.getQname())
+				}
 				buffer.WriteString(":\n")
 			}
 			indentoffset++
@@ -621,7 +675,9 @@ func (ynode *yamlNode) marshalYAML(buffer *bytes.Buffer, indent int, unindent, p
 			buffer.WriteString(ynode.getQname())
 			if ynode.IsLeafNode() {
 				if ynode.HasMultipleValues() && ynode.Len() > 8 {
-					buffer.WriteString(":\n")
+					buffer.WriteString(":")
+					ynode.writeAnnotation(buffer)
+					buffer.WriteString("\n")
 				} else {
 					buffer.WriteString(": ")
 				}
@@ -635,7 +691,13 @@ func (ynode *yamlNode) marshalYAML(buffer *bytes.Buffer, indent int, unindent, p
 	cynode := *ynode
 	switch {
 	case ynode.IsBranchNode():
-		children := ynode.Children()
+		children, err := childrenWithDefaults(ynode.DataNode, ynode.withDefaults)
+		if err != nil {
+			return Error(EAppTagYAMLEmitting, err)
+		}
+		if ynode.numericKeys {
+			children = sortByNumericKeys(children)
+		}
 		for i := 0; i < len(children); {
 			if children[i].IsListableNode() { // for list and multiple leaf-list nodes
 				var err error
@@ -704,6 +766,7 @@ func (ynode *yamlNode) marshalYAML(buffer *bytes.Buffer, indent int, unindent, p
 				comma = true
 			}
 			buffer.WriteString("]")
+			ynode.writeAnnotation(buffer)
 		}
 	case ynode.IsLeafNode():
 		schema := ynode.Schema()
@@ -712,10 +775,29 @@ func (ynode *yamlNode) marshalYAML(buffer *bytes.Buffer, indent int, unindent, p
 			return Error(EAppTagYAMLEmitting, err)
 		}
 		buffer.Write(valbyte)
+		ynode.writeAnnotation(buffer)
 	}
 	return nil
 }
 
+// writeAnnotation() writes the type and the config/state of the leaf node as a YAML comment
+// if the Annotate option is set.
+func (ynode *yamlNode) writeAnnotation(buffer *bytes.Buffer) {
+	if !ynode.annotate {
+		return
+	}
+	schema := ynode.Schema()
+	buffer.WriteString(" # type=")
+	if schema.Type != nil {
+		buffer.WriteString(schema.Type.Name)
+	}
+	if schema.IsState {
+		buffer.WriteString(" (state)")
+	} else {
+		buffer.WriteString(" (config)")
+	}
+}
+
 func (yamlnode *yamlNode) WriteIndent(buffer *bytes.Buffer, indent int, unindent bool) bool {
 	if unindent {
 		return false
@@ -805,6 +887,12 @@ func (ynode *yamlNode) skip(node DataNode) bool {
 	return false
 }
 
+// isYAMLOrderedByUserEntry() returns true if the node is an entry of the ordered-by user list
+// that is represented to a sequence in the internal YAML format.
+func isYAMLOrderedByUserEntry(node DataNode) bool {
+	return node.IsList() && node.Schema().IsOrderedByUser()
+}
+
 // toMap() encodes the data node using golang yaml marshaler interface.
 func (ynode *yamlNode) toMap(skipRoot bool) (interface{}, error) {
 	top := make(map[interface{}]interface{})
@@ -820,7 +908,10 @@ func (ynode *yamlNode) toMap(skipRoot bool) (interface{}, error) {
 			switch at {
 			case TrvsCalledAtEnter:
 				key := n.ID()
-				if n.IsDuplicatableNode() {
+				if isYAMLOrderedByUserEntry(n) {
+					key = n.Name()
+				}
+				if n.IsDuplicatableNode() || isYAMLOrderedByUserEntry(n) {
 					dir, ok := parent[key]
 					if !ok {
 						dir = []interface{}{}
@@ -840,7 +931,7 @@ func (ynode *yamlNode) toMap(skipRoot bool) (interface{}, error) {
 				_, err := ynode.marshalYAMLValue(n, parent)
 				return err
 			case TrvsCalledAtExit:
-				if n.IsDuplicatableNode() {
+				if n.IsDuplicatableNode() || isYAMLOrderedByUserEntry(n) {
 					curkeys = curkeys[:len(curkeys)-2]
 				} else {
 					curkeys = curkeys[:len(curkeys)-1]
@@ -867,9 +958,10 @@ func (ynode *yamlNode) toMap(skipRoot bool) (interface{}, error) {
 			switch at {
 			case TrvsCalledAtEnter:
 				key := n.Name()
-				qname, boundary := n.QName(true)
-				if boundary || ynode.DataNode == n {
-					key = qname
+				top := ynode.DataNode == n ||
+					(ynode.DataNode.Schema().IsRoot && n.Parent() == ynode.DataNode)
+				if ynode.nsMode.qualify(n.Schema(), top) {
+					key, _ = n.QName(true)
 				}
 				if n.IsList() {
 					dir, ok := parent[key]
@@ -1003,14 +1095,20 @@ type InternalFormat struct{}
 
 func (o InternalFormat) IsOption() {}
 
+// Annotate is an option to append the type and whether the node is config or state
+// to each leaf line of the YAML document as a comment. e.g. `uint32-range: 100 # type=uint32 (state)`
+type Annotate struct{}
+
+func (o Annotate) IsOption() {}
+
 // MarshalYAML encodes the data node to a YAML document with a number of options.
-// The options available are [ConfigOnly, StateOnly, RFC7951Format, InternalFormat].
+// The options available are [ConfigOnly, StateOnly, RFC7951Format, NamespaceMode, InternalFormat, Annotate, WithDefaults, NumericKeyAware].
 func MarshalYAML(node DataNode, option ...Option) ([]byte, error) {
 	printNodeName := false
 	buffer := bytes.NewBufferString("")
 	ynode := &yamlNode{DataNode: node, IndentStr: " "}
 	for i := range option {
-		switch option[i].(type) {
+		switch o := option[i].(type) {
 		case HasState:
 			return nil, Errorf(EAppTagYAMLEmitting, "%v option can be used to find nodes", option[i])
 		case ConfigOnly:
@@ -1019,8 +1117,16 @@ func MarshalYAML(node DataNode, option ...Option) ([]byte, error) {
 			ynode.ConfigOnly = yang.TSFalse
 		case RFC7951Format:
 			ynode.RFC7951S = RFC7951Enabled
+		case NamespaceMode:
+			ynode.nsMode = o
 		case InternalFormat:
 			ynode.InternalFormat = true
+		case Annotate:
+			ynode.annotate = true
+		case WithDefaults:
+			ynode.withDefaults = true
+		case NumericKeyAware:
+			ynode.numericKeys = true
 		case RepresentItself:
 			printNodeName = true
 		case Metadata:
@@ -1044,13 +1150,13 @@ func MarshalYAML(node DataNode, option ...Option) ([]byte, error) {
 }
 
 // MarshalYAMLIndent encodes the data node to a YAML document with a number of options.
-// The options available are [ConfigOnly, StateOnly, RFC7951Format, InternalFormat].
+// The options available are [ConfigOnly, StateOnly, RFC7951Format, NamespaceMode, InternalFormat, Annotate, WithDefaults, NumericKeyAware].
 func MarshalYAMLIndent(node DataNode, prefix, indent string, option ...Option) ([]byte, error) {
 	printNodeName := false
 	buffer := bytes.NewBufferString("")
 	ynode := &yamlNode{DataNode: node, PrefixStr: prefix, IndentStr: indent}
 	for i := range option {
-		switch option[i].(type) {
+		switch o := option[i].(type) {
 		case HasState:
 			return nil, Errorf(EAppTagYAMLEmitting, "%v option can be used to find nodes", option[i])
 		case ConfigOnly:
@@ -1059,8 +1165,16 @@ func MarshalYAMLIndent(node DataNode, prefix, indent string, option ...Option) (
 			ynode.ConfigOnly = yang.TSFalse
 		case RFC7951Format:
 			ynode.RFC7951S = RFC7951Enabled
+		case NamespaceMode:
+			ynode.nsMode = o
 		case InternalFormat:
 			ynode.InternalFormat = true
+		case Annotate:
+			ynode.annotate = true
+		case WithDefaults:
+			ynode.withDefaults = true
+		case NumericKeyAware:
+			ynode.numericKeys = true
 		case RepresentItself:
 			printNodeName = true
 		case Metadata:
@@ -1082,3 +1196,65 @@ func MarshalYAMLIndent(node DataNode, prefix, indent string, option ...Option) (
 	}
 	return buffer.Bytes(), nil
 }
+
+// ToMap() converts the data node to a map[string]interface{} that has the same structure as
+// the JSON document of the data node. The values of the map are the native Go values.
+// The options available are [ConfigOnly, StateOnly, RFC7951Format, NamespaceMode].
+func ToMap(node DataNode, option ...Option) (map[string]interface{}, error) {
+	if !IsValid(node) {
+		return nil, fmt.Errorf("invalid data node")
+	}
+	ynode := &yamlNode{DataNode: node}
+	for i := range option {
+		switch o := option[i].(type) {
+		case HasState:
+			return nil, Errorf(EAppTagInvalidArg, "%v option can be used to find nodes", option[i])
+		case ConfigOnly:
+			ynode.ConfigOnly = yang.TSTrue
+		case StateOnly:
+			ynode.ConfigOnly = yang.TSFalse
+		case RFC7951Format:
+			ynode.RFC7951S = RFC7951Enabled
+		case NamespaceMode:
+			ynode.nsMode = o
+		}
+	}
+	if !node.IsBranchNode() {
+		m := map[interface{}]interface{}{}
+		if _, err := ynode.marshalYAMLValue(node, m); err != nil {
+			return nil, err
+		}
+		return toStringKeyMap(m), nil
+	}
+	v, err := ynode.toMap(false)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, Errorf(EAppTagInvalidArg, "unable to convert %s to a map", node)
+	}
+	return toStringKeyMap(m), nil
+}
+
+// toStringKeyMap() converts the keys of the map and all the nested maps to strings.
+func toStringKeyMap(m map[interface{}]interface{}) map[string]interface{} {
+	sm := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		sm[ValueToValueString(k)] = toStringKeyValue(v)
+	}
+	return sm
+}
+
+func toStringKeyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		return toStringKeyMap(value)
+	case []interface{}:
+		for i := range value {
+			value[i] = toStringKeyValue(value[i])
+		}
+		return value
+	}
+	return v
+}
@@ -1,9 +1,12 @@
 package yangtree
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -202,3 +205,243 @@ func TestRepresentItself(t *testing.T) {
 		t.Errorf("unexpected marshalling result: %v %v\n", string(j), `{"enum-val":"enum1"}`)
 	}
 }
+
+func TestToMap(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jbyte := `{
+		"sample": {
+			"container-val": {"a": "A", "enum-val": "enum2", "leaf-list-val": ["first", "second"]},
+			"multiple-key-list": {"first": {"1": {"integer": 1, "ok": true, "str": "first"}}},
+			"non-key-list": [{"strval": "XYZ", "uintval": 10}],
+			"single-key-list": {"AAA": {"country-code": "KR", "list-key": "AAA", "uint32-range": 100}},
+			"str-val": "abc"
+		}
+	}`
+	root, err := NewWithValueString(RootSchema, jbyte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ToMap(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sample, ok := m["sample"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("unexpected map: %v", m)
+	}
+	skl := sample["single-key-list"].(map[string]interface{})["AAA"].(map[string]interface{})
+	if v, ok := skl["uint32-range"].(uint32); !ok || v != 100 {
+		t.Errorf("number must be a number in the map: %T %v", skl["uint32-range"], skl["uint32-range"])
+	}
+	if _, err := ToMap(root, ConfigOnly{}); err != nil {
+		t.Error(err)
+	}
+	if rm, err := ToMap(root, RFC7951Format{}); err != nil {
+		t.Error(err)
+	} else if _, ok := rm["sample:sample"]; !ok {
+		t.Errorf("unexpected map in RFC7951 format: %v", rm)
+	}
+
+	// round-trip
+	root2, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(root2, "/sample", nil, m["sample"]); err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(root, root2) {
+		j1, _ := MarshalJSON(root)
+		j2, _ := MarshalJSON(root2)
+		t.Errorf("round-trip data is not equal:\n%s\n%s", j1, j2)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jbyte := `{
+		"sample": {
+			"container-val": {"a": "A", "leaf-list-val": ["first", "second"]},
+			"single-key-list": {"AAA": {"country-code": "KR", "list-key": "AAA", "uint32-range": 100}}
+		}
+	}`
+	root, err := NewWithValueString(RootSchema, jbyte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalYAML(root, Annotate{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comment := range []string{
+		"uint32-range: 100 # type=uint32 (state)",
+		"country-code: KR # type=string (config)",
+		"a: A # type=string (config)",
+		"- first # type=string (config)",
+	} {
+		if !strings.Contains(string(b), comment) {
+			t.Errorf("annotated yaml must contain %q:\n%s", comment, b)
+		}
+	}
+	if b, err := MarshalYAML(root); err != nil {
+		t.Fatal(err)
+	} else if strings.Contains(string(b), "# type=") {
+		t.Errorf("yaml must not be annotated without Annotate option:\n%s", b)
+	}
+
+	// the annotated yaml can be loaded.
+	root2, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalYAML(root2, b); err != nil {
+		t.Fatal(err)
+	}
+	if !Equal(root, root2) {
+		t.Errorf("annotated yaml is not loaded correctly:\n%s", b)
+	}
+}
+
+func TestYAMLCompoundKeys(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := func(filename string) DataNode {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := New(RootSchema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := UnmarshalYAML(root, b); err != nil {
+			t.Fatalf("unmarshalling %s error: %v", filename, err)
+		}
+		return root
+	}
+	expected := load("testdata/yaml/sample2.yaml")
+	root := load("testdata/yaml/sample-compound-keys.yaml")
+	if !Equal(expected, root) {
+		j1, _ := MarshalJSON(expected)
+		j2, _ := MarshalJSON(root)
+		t.Errorf("compound-key yaml is not equal to the array format:\n%s\n%s", j1, j2)
+	}
+	if v, _ := FindValueString(root, "/sample/multiple-key-list[str=first][integer=1]/ok"); len(v) != 1 || v[0] != "true" {
+		t.Errorf("ok leaf of the compound-key list entry not updated")
+	}
+
+	// keys must be only used for keyed lists.
+	node, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalYAML(node, []byte("sample:\n  container-val[a=A]:\n")); err == nil {
+		t.Errorf("key values for a container must be rejected")
+	}
+}
+
+func TestUnmarshalYAMLGzip(t *testing.T) {
+	RootSchema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile("testdata/yaml/sample1.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	if _, err := gzw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalYAML(expected, b); err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(RootSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalYAMLGzip(root, buf.Bytes()); err != nil {
+		t.Fatalf("UnmarshalYAMLGzip() error: %v", err)
+	}
+	if !Equal(expected, root) {
+		t.Errorf("gzip-compressed yaml data is not loaded equally")
+	}
+}
+
+func TestYAMLInternalFormatOrderedByUser(t *testing.T) {
+	schema, err := Load([]string{"testdata/sample"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := []string{"Z", "A", "M"}
+	for _, name := range order {
+		err := SetValueString(root, "/sample/ordered-by-user-list[name="+name+"]/value", &EditOption{InsertOption: InsertToLast{}}, "v-"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(node DataNode) []string {
+		var names []string
+		for _, c := range node.Get("sample").Children() {
+			if c.Name() == "ordered-by-user-list" {
+				names = append(names, c.GetValueString("name"))
+			}
+		}
+		return names
+	}
+	b, err := MarshalYAML(root, InternalFormat{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed, err := New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalYAML(reversed, b); err != nil {
+		t.Fatalf("unmarshalling error: %v\n%s", err, b)
+	}
+	if got := names(reversed); strings.Join(got, ",") != strings.Join(order, ",") {
+		t.Errorf("the user order is not kept through the internal yaml format: %v\n%s", got, b)
+	}
+	if v, ok := GetValueStringAt(reversed, "/sample/ordered-by-user-list[name=A]/value"); !ok || v != "v-A" {
+		t.Errorf("unexpected value of the list entry: %s", v)
+	}
+
+	// golang yaml marshaler interface
+	ynode := &yamlNode{DataNode: root, InternalFormat: true}
+	b, err = yaml.Marshal(ynode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reversed, err = New(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalYAML(reversed, b); err != nil {
+		t.Fatalf("unmarshalling error: %v\n%s", err, b)
+	}
+	if got := names(reversed); strings.Join(got, ",") != strings.Join(order, ",") {
+		t.Errorf("the user order is not kept through the yaml marshaler: %v\n%s", got, b)
+	}
+}
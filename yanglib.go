@@ -170,6 +170,37 @@ func getConformanceType(m *yang.Module, excluded []string) (conformancetype stri
 	return
 }
 
+// LoadedModules() returns the modules loaded to the schema tree except the submodules.
+// The modules are sorted by their names and revisions.
+func (schema *SchemaNode) LoadedModules() []*yang.Module {
+	schema = schema.GetRootSchema()
+	if schema.Modules == nil {
+		return nil
+	}
+	return loadedModules(schema.Modules.Modules)
+}
+
+// loadedModules() returns the modules of the module map except the submodules.
+// The module map can have the same module with and without the revision (NAME@REVISION).
+func loadedModules(modulemap map[string]*yang.Module) []*yang.Module {
+	mods := make([]*yang.Module, 0, len(modulemap))
+	added := make(map[*yang.Module]bool, len(modulemap))
+	for _, m := range modulemap {
+		if m.BelongsTo != nil || added[m] {
+			continue
+		}
+		added[m] = true
+		mods = append(mods, m)
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		if mods[i].Name == mods[j].Name {
+			return mods[i].Current() < mods[j].Current()
+		}
+		return mods[i].Name < mods[j].Name
+	})
+	return mods
+}
+
 // Module set ID
 var moduleSetNum int
 
@@ -197,22 +228,7 @@ func loadYanglibrary(rootschema *SchemaNode, excluded []string) error {
 		if err != nil {
 			return fmt.Errorf(`yanglib: %s not found`, "yang-library")
 		}
-		var mods []*yang.Module
-		for _, m := range modulemap {
-			mods = append(mods, m)
-		}
-		sort.Slice(mods, func(i, j int) bool {
-			if mods[i].Name < mods[j].Name {
-				return true
-			} else if mods[i].Name == mods[j].Name {
-				return mods[i].Current() < mods[j].Current()
-			}
-			return true
-		})
-		for _, m := range modulemap {
-			if m.BelongsTo != nil {
-				continue
-			}
+		for _, m := range loadedModules(modulemap) {
 			name, revision, namespace := m.Name, m.Current(), ""
 			if m.Namespace != nil {
 				namespace = m.Namespace.Name